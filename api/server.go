@@ -134,6 +134,7 @@ func (s *Server) setupRoutes() {
 			protected.POST("/traders/:id/stop", s.handleStopTrader)
 			protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
 			protected.POST("/traders/:id/sync-balance", s.handleSyncBalance)
+			protected.POST("/traders/:id/copy-loss-streak/reset", s.handleResetCopyLossStreak)
 
 			// AI模型配置
 			protected.GET("/models", s.handleGetModelConfigs)
@@ -373,6 +374,34 @@ type CopyTradingConfigPayload struct {
 	MaxAmount       float64 `json:"max_amount"`
 	SyncLeverage    bool    `json:"sync_leverage"`
 	SyncMarginMode  bool    `json:"sync_margin_mode"`
+	// 按币种覆盖跟单比例（百分比）
+	SymbolRatios map[string]float64 `json:"symbol_ratios,omitempty"`
+	// 仓位计算方式（ratio/equity）
+	SizeMode string `json:"size_mode,omitempty"`
+	// 跟随者杠杆上限（0 表示不限制）
+	MaxLeverage int `json:"max_leverage,omitempty"`
+	// 反向跟单（持有与领航员相反的仓位）
+	Reverse bool `json:"reverse,omitempty"`
+	// 交易所单笔订单名义价值上限（按币种）及超限处理方式（split/clamp）
+	MaxOrderNotional map[string]float64 `json:"max_order_notional,omitempty"`
+	OrderLimitMode   string             `json:"order_limit_mode,omitempty"`
+	// 单币种同向持仓数量上限（币）
+	MaxSymbolBaseSize map[string]float64 `json:"max_symbol_base_size,omitempty"`
+	// 跟随模式（trade/net）及 net 模式调仓阈值（百分比）
+	FollowMode            string  `json:"follow_mode,omitempty"`
+	RebalanceThresholdPct float64 `json:"rebalance_threshold_pct,omitempty"`
+	// 动作映射（如 reduce_long→close_long）
+	ActionRemap map[string]string `json:"action_remap,omitempty"`
+	// 按币种强制保证金模式（cross/isolated）
+	MarginModeOverrides map[string]string `json:"margin_mode_overrides,omitempty"`
+	// 跟单总敞口上限（相对净值的倍数）
+	MaxTotalLeverage float64 `json:"max_total_leverage,omitempty"`
+	// 领航员连续亏损熔断次数
+	MaxConsecutiveLosses int `json:"max_consecutive_losses,omitempty"`
+	// 领航员净值平滑系数（0~1）
+	EquitySmoothing float64 `json:"equity_smoothing,omitempty"`
+	// 领航员强平价保护止损缓冲（百分比）
+	LiqStopBufferPct float64 `json:"liq_stop_buffer_pct,omitempty"`
 }
 
 type CreateTraderRequest struct {
@@ -434,6 +463,21 @@ func marshalCopyTradingConfigPayload(payload *CopyTradingConfigPayload) string {
 		cfg.FollowReduce = payload.FollowReduce
 		cfg.SyncLeverage = payload.SyncLeverage
 		cfg.SyncMarginMode = payload.SyncMarginMode
+		cfg.SymbolRatios = payload.SymbolRatios
+		cfg.SizeMode = payload.SizeMode
+		cfg.MaxLeverage = payload.MaxLeverage
+		cfg.Reverse = payload.Reverse
+		cfg.MaxOrderNotional = payload.MaxOrderNotional
+		cfg.OrderLimitMode = payload.OrderLimitMode
+		cfg.MaxSymbolBaseSize = payload.MaxSymbolBaseSize
+		cfg.FollowMode = payload.FollowMode
+		cfg.RebalanceThresholdPct = payload.RebalanceThresholdPct
+		cfg.ActionRemap = payload.ActionRemap
+		cfg.MarginModeOverrides = payload.MarginModeOverrides
+		cfg.MaxTotalLeverage = payload.MaxTotalLeverage
+		cfg.MaxConsecutiveLosses = payload.MaxConsecutiveLosses
+		cfg.EquitySmoothing = payload.EquitySmoothing
+		cfg.LiqStopBufferPct = payload.LiqStopBufferPct
 	}
 
 	data, _ := json.Marshal(cfg)
@@ -751,6 +795,7 @@ type UpdateTraderRequest struct {
 	TradingSymbols      string                    `json:"trading_symbols"`
 	CustomPrompt        string                    `json:"custom_prompt"`
 	OverrideBasePrompt  bool                      `json:"override_base_prompt"`
+	SystemPromptTemplate string                   `json:"system_prompt_template"` // 系统提示词模板名称（为空时保持原值）
 	IsCrossMargin       *bool                     `json:"is_cross_margin"`
 	SignalSourceType    string                    `json:"signal_source_type"`
 	SignalSourceValue   string                    `json:"signal_source_value"`
@@ -835,6 +880,11 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		copyTradingConfigJSON = marshalCopyTradingConfigPayload(req.CopyTradingConfig)
 	}
 
+	systemPromptTemplate := existingTrader.SystemPromptTemplate
+	if req.SystemPromptTemplate != "" {
+		systemPromptTemplate = req.SystemPromptTemplate
+	}
+
 	// 更新交易员配置
 	trader := &config.TraderRecord{
 		ID:                   traderID,
@@ -848,7 +898,7 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		TradingSymbols:       req.TradingSymbols,
 		CustomPrompt:         req.CustomPrompt,
 		OverrideBasePrompt:   req.OverrideBasePrompt,
-		SystemPromptTemplate: existingTrader.SystemPromptTemplate, // 保持原值
+		SystemPromptTemplate: systemPromptTemplate,
 		IsCrossMargin:        isCrossMargin,
 		ScanIntervalMinutes:  scanIntervalMinutes,
 		SignalSourceType:     signalSourceType,
@@ -864,6 +914,18 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		return
 	}
 
+	// 仅跟单参数变化时直接热更新内存中的交易员，避免重启和重新快照领航员仓位
+	if onlyCopyTradingConfigChanged(existingTrader, trader) && s.hotUpdateCopyTradingConfig(traderID, copyTradingConfigJSON) {
+		log.Printf("✓ 热更新交易员跟单配置: %s", traderID)
+		c.JSON(http.StatusOK, gin.H{
+			"trader_id":   traderID,
+			"trader_name": req.Name,
+			"ai_model":    req.AIModelID,
+			"message":     "交易员更新成功",
+		})
+		return
+	}
+
 	// 如果交易员已在内存中，停止并移除旧实例，以便使用新配置重建
 	wasRunning := false
 	if loadedTrader, err := s.traderManager.GetTrader(traderID); err == nil {
@@ -907,6 +969,39 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 	})
 }
 
+// onlyCopyTradingConfigChanged 判断更新是否只涉及跟单参数（交易所、模型、凭证等变化仍需重建）
+func onlyCopyTradingConfigChanged(existing, updated *config.TraderRecord) bool {
+	if updated.SignalSourceType == "ai" {
+		return false
+	}
+	return existing.Name == updated.Name &&
+		existing.AIModelID == updated.AIModelID &&
+		existing.ExchangeID == updated.ExchangeID &&
+		existing.ScanIntervalMinutes == updated.ScanIntervalMinutes &&
+		existing.BTCETHLeverage == updated.BTCETHLeverage &&
+		existing.AltcoinLeverage == updated.AltcoinLeverage &&
+		existing.TradingSymbols == updated.TradingSymbols &&
+		existing.CustomPrompt == updated.CustomPrompt &&
+		existing.OverrideBasePrompt == updated.OverrideBasePrompt &&
+		existing.SignalSourceType == updated.SignalSourceType &&
+		existing.SignalSourceValue == updated.SignalSourceValue &&
+		existing.SystemPromptTemplate == updated.SystemPromptTemplate &&
+		existing.IsCrossMargin == updated.IsCrossMargin
+}
+
+// hotUpdateCopyTradingConfig 将新的跟单配置应用到内存中的交易员，失败时返回false以走完整重建流程
+func (s *Server) hotUpdateCopyTradingConfig(traderID, copyTradingConfigJSON string) bool {
+	loadedTrader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		return false
+	}
+	if err := loadedTrader.UpdateCopyTradingConfig(trader.ParseCopyTradingConfig(copyTradingConfigJSON)); err != nil {
+		log.Printf("⚠️ 热更新跟单配置失败，改为重建交易员: %v", err)
+		return false
+	}
+	return true
+}
+
 // handleDeleteTrader 删除交易员
 func (s *Server) handleDeleteTrader(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -1013,6 +1108,33 @@ func (s *Server) handleStopTrader(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已停止"})
 }
 
+// handleResetCopyLossStreak 手动解除跟单交易员的领航员连续亏损熔断
+func (s *Server) handleResetCopyLossStreak(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	// 校验交易员是否属于当前用户
+	_, _, _, err := s.database.GetTraderConfig(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	if sourceType, _ := trader.GetStatus()["signal_source_type"].(string); sourceType == "ai" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该交易员不是复制交易模式"})
+		return
+	}
+
+	trader.ResetCopyLossStreak()
+	c.JSON(http.StatusOK, gin.H{"message": "已解除连续亏损熔断"})
+}
+
 // handleUpdateTraderPrompt 更新交易员自定义Prompt
 func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 	traderID := c.Param("id")
@@ -1,10 +1,17 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"nofx/config"
+	"nofx/manager"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
 )
 
 // TestUpdateTraderRequest_SystemPromptTemplate 测试更新交易员时 SystemPromptTemplate 字段是否存在
@@ -303,3 +310,151 @@ func TestPublicTraderListResponse_SystemPromptTemplate(t *testing.T) {
 		t.Errorf("Expected system_prompt_template='default', got %v", response["system_prompt_template"])
 	}
 }
+
+// newCopyTraderTestServer 构造带临时数据库的 Server，并加载一个 OKX 钱包跟单交易员
+func newCopyTraderTestServer(t *testing.T) (s *Server, userID, traderID string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := config.NewDatabase(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	userID = "copy-user"
+	if err := db.CreateUser(&config.User{ID: userID, Email: userID + "@test.com", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	if err := db.CreateAIModel(userID, userID+"_deepseek", "DeepSeek", "deepseek", true, "sk-test", ""); err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+	for _, exchangeID := range []string{"binance", "bybit"} {
+		if err := db.CreateExchange(userID, exchangeID, exchangeID, "cex", true, "api-key", "secret-key", false, "", "", "", ""); err != nil {
+			t.Fatalf("创建交易所失败: %v", err)
+		}
+	}
+
+	traderID = userID + "_copy"
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID:                  traderID,
+		UserID:              userID,
+		Name:                "Copy Trader",
+		AIModelID:           userID + "_deepseek",
+		ExchangeID:          "binance",
+		InitialBalance:      1000,
+		ScanIntervalMinutes: 3,
+		BTCETHLeverage:      5,
+		AltcoinLeverage:     5,
+		IsCrossMargin:       true,
+		SignalSourceType:    "okx_wallet",
+		SignalSourceValue:   "leader-1",
+		CopyTradingConfig:   marshalCopyTradingConfigPayload(nil),
+	}); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+
+	s = &Server{traderManager: manager.NewTraderManager(), database: db}
+	if err := s.traderManager.LoadTraderByID(db, userID, traderID); err != nil {
+		t.Fatalf("加载交易员失败: %v", err)
+	}
+	return s, userID, traderID
+}
+
+// TestHandleUpdateTrader_CopyConfigHotSwap 测试仅修改跟单参数时热更新，不重建交易员
+func TestHandleUpdateTrader_CopyConfigHotSwap(t *testing.T) {
+	s, userID, traderID := newCopyTraderTestServer(t)
+	db := s.database
+	original, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		t.Fatalf("获取交易员失败: %v", err)
+	}
+
+	update := func(exchangeID string, followRatio float64) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{
+			"name":                  "Copy Trader",
+			"ai_model_id":           userID + "_deepseek",
+			"exchange_id":           exchangeID,
+			"initial_balance":       1000,
+			"scan_interval_minutes": 3,
+			"btc_eth_leverage":      5,
+			"altcoin_leverage":      5,
+			"is_cross_margin":       true,
+			"signal_source_type":    "okx_wallet",
+			"signal_source_value":   "leader-1",
+			"copy_trading_config":   map[string]interface{}{"follow_open": true, "follow_ratio": followRatio},
+		})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/api/traders/"+traderID, bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: traderID}}
+		c.Set("user_id", userID)
+		s.handleUpdateTrader(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("更新交易员失败: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	// 仅修改跟单比例：内存中的交易员保持同一实例，数据库保存新配置
+	update("binance", 50)
+	hotSwapped, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		t.Fatalf("获取交易员失败: %v", err)
+	}
+	if hotSwapped != original {
+		t.Fatal("仅修改跟单参数不应重建交易员")
+	}
+	traders, err := db.GetTraders(userID)
+	if err != nil || len(traders) != 1 {
+		t.Fatalf("读取交易员失败: %v", err)
+	}
+	if cfg := trader.ParseCopyTradingConfig(traders[0].CopyTradingConfig); cfg.FollowRatio != 50 {
+		t.Errorf("跟单比例未保存: got %v", cfg.FollowRatio)
+	}
+
+	// 修改交易所：仍走完整重建
+	update("bybit", 50)
+	rebuilt, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		t.Fatalf("获取交易员失败: %v", err)
+	}
+	if rebuilt == original {
+		t.Error("修改交易所应重建交易员")
+	}
+	if rebuilt.GetExchange() != "bybit" {
+		t.Errorf("交易所未更新: got %s", rebuilt.GetExchange())
+	}
+}
+
+// TestHandleResetCopyLossStreak 测试手动解除连续亏损熔断接口
+func TestHandleResetCopyLossStreak(t *testing.T) {
+	s, userID, traderID := newCopyTraderTestServer(t)
+
+	reset := func(userID, traderID string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/traders/"+traderID+"/copy-loss-streak/reset", nil)
+		c.Params = gin.Params{{Key: "id", Value: traderID}}
+		c.Set("user_id", userID)
+		s.handleResetCopyLossStreak(c)
+		return w.Code
+	}
+
+	if code := reset(userID, traderID); code != http.StatusOK {
+		t.Fatalf("解除熔断应成功，got %d", code)
+	}
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		t.Fatalf("获取交易员失败: %v", err)
+	}
+	if tripped, ok := at.GetStatus()["copy_loss_breaker_tripped"].(bool); !ok || tripped {
+		t.Errorf("状态应显示熔断未触发: %v", at.GetStatus()["copy_loss_breaker_tripped"])
+	}
+
+	// 其他用户无权操作
+	if code := reset("other-user", traderID); code != http.StatusNotFound {
+		t.Errorf("非本人交易员应返回404，got %d", code)
+	}
+}
@@ -0,0 +1,516 @@
+package copytrading
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	binanceBaseURL       = "https://www.binance.com"
+	binanceCopyTradePath = "/bapi/futures/v1/friendly/future/copy-trade"
+)
+
+// binanceProvider follows a Binance Futures lead trader through the public
+// copy-trading portfolio pages. Identifier is the lead portfolio id.
+type binanceProvider struct {
+	mu sync.RWMutex // guards lastFillTime and tracker
+
+	portfolioID  string
+	client       *http.Client
+	baseURL      string
+	lastFillTime int64
+	tracker      *positionTracker
+	store        StateStore
+	maxBody      int64
+	stablecoin   stablecoinValuer
+	shadow       *shadowMonitor // only touched by the poll loop
+	emitted      *emittedKeys   // only touched by the poll loop
+	poll         *adaptivePoll
+	backoff      *errorBackoff // only touched by the poll loop
+	errs         errorReporter
+	pause        *PauseController
+	stream       string // follow-mode variant, see streamVariant
+	onDuplicate  DuplicatePolicy
+	watch        *watchHandle // set while Run is active
+	clock        Clock
+	logger       Logger
+	metrics      Metrics
+	heartbeat    time.Duration
+	bus          *SignalBus
+
+	stop           *stopSignal // closed by Stop
+	closeAllOnStop bool
+	bufferSize     int
+	overflow       OverflowPolicy
+}
+
+func newBinanceProvider(cfg Config) Provider {
+	return &binanceProvider{
+		portfolioID: strings.TrimSpace(cfg.Identifier),
+		client:      cfg.HTTPClient,
+		baseURL:     baseURLOf(cfg, binanceBaseURL),
+		tracker:     newVenueTracker(cfg, "binance"),
+		store:       cfg.StateStore,
+		maxBody:     cfg.MaxResponseBytes,
+		stablecoin:  newStablecoinValuer(cfg),
+		shadow:      newShadowMonitor(cfg),
+		emitted:     newEmittedKeys(cfg),
+		poll:        newAdaptivePoll(cfg),
+		backoff:     newErrorBackoff(cfg),
+		errs:        newErrorReporter(cfg, "Binance"),
+		pause:       pauseOf(cfg),
+		stream:      streamVariant(cfg),
+		onDuplicate: cfg.OnDuplicate,
+		clock:       clockOf(cfg.Clock),
+		logger:      loggerOf(cfg.Logger),
+		metrics:     metricsOf(cfg.Metrics),
+		heartbeat:   cfg.Heartbeat,
+		bus:         cfg.Bus,
+
+		stop:           newStopSignal(),
+		closeAllOnStop: cfg.CloseAllOnStop,
+		bufferSize:     cfg.BufferSize,
+		overflow:       cfg.Overflow,
+	}
+}
+
+func (p *binanceProvider) Run(ctx context.Context, out chan<- Signal) error {
+	if p.portfolioID == "" {
+		return fmt.Errorf("binance provider requires portfolioId")
+	}
+
+	watch, err := defaultWatchRegistry.register(watchKey("binance", p.portfolioID, p.stream), p.onDuplicate)
+	if err != nil {
+		return err
+	}
+	p.watch = watch
+	defer watch.release()
+
+	out, stopHeartbeat := withHeartbeat(ctx, out, p.heartbeat, p.clock)
+	defer stopHeartbeat()
+	out, stopBuffer := withBuffer(ctx, out, p.bufferSize, p.overflow, p.metrics, p.logger)
+	defer stopBuffer()
+
+	p.loadState()
+	defer p.saveState()
+
+	for {
+		err := p.fetchAndEmit(ctx, out)
+		if err != nil {
+			p.errs.report(p.stateKey(), err)
+		} else {
+			// persist every poll so a crash loses at most one interval
+			p.saveState()
+		}
+		p.backoff.observe(err)
+
+		timer := time.NewTimer(p.backoff.wait(p.poll.interval()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-p.stop.done():
+			timer.Stop()
+			p.closeAll(ctx, out)
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Stop asks Run to return after the poll in progress (see Stopper).
+func (p *binanceProvider) Stop() {
+	p.stop.stop()
+}
+
+// closeAll emits a close for every mirrored position when Config.CloseAllOnStop is
+// set.
+func (p *binanceProvider) closeAll(ctx context.Context, out chan<- Signal) {
+	if !p.closeAllOnStop {
+		return
+	}
+	p.mu.Lock()
+	signals := p.tracker.closeAll()
+	p.mu.Unlock()
+	p.emit(ctx, signals, out)
+}
+
+func (p *binanceProvider) stateKey() string {
+	return stateKey("binance", p.portfolioID)
+}
+
+// emit sends signals that pass deduplication to out and the bus. It gives up once
+// ctx is done, so a consumer that stopped reading cannot hang the provider.
+func (p *binanceProvider) emit(ctx context.Context, signals []Signal, out chan<- Signal) {
+	for _, sig := range signals {
+		if !p.emitted.admit(sig.DedupKey) || !p.watch.admit(sig, p.clock.Now()) {
+			continue
+		}
+		p.logger.Debugf("📤 Binance %s %s notional=%.2f", sig.Symbol, sig.Action, sig.NotionalUSD)
+		p.metrics.IncSignal(string(sig.Action), sig.Symbol)
+		if out != nil {
+			select {
+			case out <- sig:
+			case <-ctx.Done():
+				return
+			}
+		}
+		p.bus.Publish(p.stateKey(), sig)
+		p.shadow.record(sig)
+	}
+}
+
+// Cursor returns the time (epoch ms) of the last processed trade.
+func (p *binanceProvider) Cursor() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastFillTime
+}
+
+// Initialized reports whether the leader snapshot has been seeded.
+func (p *binanceProvider) Initialized() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.tracker.initialized
+}
+
+// Ready is closed after the first successful fetch of the leader's book.
+func (p *binanceProvider) Ready() <-chan struct{} {
+	return p.tracker.ready
+}
+
+// HealthCheck fetches the leader's portfolio detail once, so an unknown portfolioId or an unreachable
+// endpoint is reported before Run starts.
+func (p *binanceProvider) HealthCheck(ctx context.Context) error {
+	if p.portfolioID == "" {
+		return fmt.Errorf("binance provider requires portfolioId")
+	}
+	if _, err := p.fetchEquity(ctx); err != nil {
+		return fmt.Errorf("binance leader %s: %w", p.portfolioID, err)
+	}
+	return nil
+}
+
+// positions returns a copy of the mirrored leader book.
+func (p *binanceProvider) positions() map[string]PositionMeta {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return copyPositions(p.tracker.lastPositions)
+}
+
+// Snapshot returns the leader's mirrored book with last known prices (see
+// SnapshotReporter).
+func (p *binanceProvider) Snapshot() map[string]PositionMeta {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.tracker.snapshot()
+}
+
+func (p *binanceProvider) loadState() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, ok := loadState(p.store, p.logger, p.stateKey()); ok {
+		p.lastFillTime = s.LastFillTime
+		p.tracker.restoreState(s)
+	}
+}
+
+func (p *binanceProvider) saveState() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.store == nil || !p.tracker.initialized {
+		return
+	}
+	s := ProviderState{LastFillTime: p.lastFillTime, SavedAt: p.clock.Now()}
+	p.tracker.exportState(&s)
+	saveState(p.store, p.logger, p.stateKey(), s)
+}
+
+func (p *binanceProvider) fetchAndEmit(ctx context.Context, out chan<- Signal) (err error) {
+	defer observeFetch(p.metrics, "binance/poll", time.Now(), &err)
+	// fills only refine prices; the position snapshot is authoritative, so a fills
+	// outage must not hold back the diff
+	trades, err := p.fetchTrades(ctx)
+	if err != nil {
+		p.logger.Warnf("⚠️  Binance fills unavailable, diffing positions without them: %v", err)
+		trades = nil
+	}
+
+	accountValue, err := p.fetchEquity(ctx)
+	if err != nil {
+		return err
+	}
+	if accountValue <= 0 {
+		return fmt.Errorf("%w: binance margin balance %g", ErrNoEquity, accountValue)
+	}
+	positions, err := p.fetchPositions(ctx)
+	if err != nil {
+		return err
+	}
+	return p.apply(ctx, trades, positions, accountValue, out)
+}
+
+// apply folds new fills into prices, diffs the position book against the mirrored
+// one and emits the resulting signals.
+func (p *binanceProvider) apply(ctx context.Context, trades []binanceTrade, positions map[string]PositionMeta, accountValue float64, out chan<- Signal) error {
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time < trades[j].Time })
+
+	p.mu.Lock()
+	maxFill := p.lastFillTime
+	for _, trade := range trades {
+		if trade.Time <= p.lastFillTime {
+			continue
+		}
+		symbol := formatBinanceSymbol(trade.Symbol)
+		if symbol == "" {
+			continue
+		}
+
+		price, size := p.number("price", trade.Price), math.Abs(p.number("qty", trade.Qty))
+		p.tracker.recordFill(symbol, price, size, time.UnixMilli(trade.Time))
+		side := strings.ToUpper(trade.Side)
+		if side == "BUY" || side == "SELL" {
+			p.tracker.recordFillSide(symbol, side == "BUY")
+		}
+		// Binance books realized PnL only on fills that reduce a position
+		if pnl := p.number("realizedProfit", trade.RealizedProfit); pnl != 0 {
+			p.tracker.recordCloseFill(symbol, price, size, pnl)
+		}
+		if trade.Time > maxFill {
+			maxFill = trade.Time
+		}
+	}
+	newFills := maxFill > p.lastFillTime
+	if newFills {
+		p.lastFillTime = maxFill
+	}
+
+	signals := p.tracker.update(positions, accountValue)
+	p.mu.Unlock()
+	p.poll.observe(newFills || len(signals) > 0)
+	signals = suppressWhilePaused(p.pause, p.logger, "Binance", signals)
+
+	p.emit(ctx, signals, out)
+	p.shadow.compare(positions)
+	return nil
+}
+
+func (p *binanceProvider) fetchTrades(ctx context.Context) (_ []binanceTrade, err error) {
+	defer observeFetch(p.metrics, "binance/trades", time.Now(), &err)
+	body, err := json.Marshal(map[string]interface{}{
+		"portfolioId": p.portfolioID,
+		"pageNumber":  1,
+		"pageSize":    50,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+binanceCopyTradePath+"/lead-portfolio/trade-history", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var result binanceResponse[binanceTradePage]
+	if err := p.do(req, "trades", &result); err != nil {
+		return nil, err
+	}
+	return result.Data.List, nil
+}
+
+func (p *binanceProvider) fetchEquity(ctx context.Context) (_ float64, err error) {
+	defer observeFetch(p.metrics, "binance/equity", time.Now(), &err)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.endpoint("/lead-portfolio/detail"), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var result binanceResponse[binancePortfolioDetail]
+	if err := p.do(req, "portfolio", &result); err != nil {
+		return 0, err
+	}
+	if result.Data.MarginBalance == nil {
+		return 0, fmt.Errorf("%w: binance portfolio %s", ErrLeaderNotFound, p.portfolioID)
+	}
+	return p.stablecoin.toUSD(p.number("marginBalance", *result.Data.MarginBalance)), nil
+}
+
+// fetchPositions returns the leader's book as signed sizes in coins. Hedge-mode
+// legs of one symbol net into a single position, like the other venues.
+func (p *binanceProvider) fetchPositions(ctx context.Context) (_ map[string]PositionMeta, err error) {
+	defer observeFetch(p.metrics, "binance/positions", time.Now(), &err)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.endpoint("/lead-data/positions"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result binanceResponse[[]binancePosition]
+	if err := p.do(req, "position", &result); err != nil {
+		return nil, err
+	}
+
+	positions := make(map[string]PositionMeta)
+	for _, pos := range result.Data {
+		symbol := formatBinanceSymbol(pos.Symbol)
+		size := p.number("positionAmount", pos.PositionAmount)
+		if symbol == "" || size == 0 {
+			continue
+		}
+		switch strings.ToUpper(pos.PositionSide) {
+		case "LONG":
+			size = math.Abs(size)
+		case "SHORT":
+			size = -math.Abs(size)
+		}
+
+		meta, ok := positions[symbol]
+		if ok {
+			meta.Size += size
+			positions[symbol] = meta
+			continue
+		}
+		leverage := pos.Leverage
+		if leverage <= 0 {
+			leverage = 1
+		}
+		marginMode := "cross"
+		if pos.Isolated {
+			marginMode = "isolated"
+		}
+		positions[symbol] = PositionMeta{
+			Size:       size,
+			Leverage:   leverage,
+			MarginMode: marginMode,
+			EntryPrice: p.number("entryPrice", pos.EntryPrice),
+			LiqPrice:   p.number("liquidationPrice", pos.LiquidationPrice),
+		}
+	}
+	for symbol, meta := range positions {
+		if meta.Size == 0 {
+			// fully hedged legs hold no net exposure
+			delete(positions, symbol)
+		}
+	}
+	return positions, nil
+}
+
+func (p *binanceProvider) endpoint(path string) string {
+	params := url.Values{}
+	params.Set("portfolioId", p.portfolioID)
+	return p.baseURL + binanceCopyTradePath + path + "?" + params.Encode()
+}
+
+// do sends req and decodes the bapi envelope, which reports failures with HTTP 200
+// and success=false.
+func (p *binanceProvider) do(req *http.Request, what string, v binanceEnvelope) error {
+	acceptGzip(req)
+
+	resp, err := doRequest(p.client, p.clock, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("binance %s error: %s", what, resp.Status)
+	}
+	if err := decodeJSON(resp, p.maxBody, v); err != nil {
+		return err
+	}
+	return v.err(what)
+}
+
+type binanceEnvelope interface {
+	err(what string) error
+}
+
+type binanceResponse[T any] struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+	Data    T      `json:"data"`
+}
+
+func (r *binanceResponse[T]) err(what string) error {
+	if r.Success || r.Code == "000000" {
+		return nil
+	}
+	return fmt.Errorf("binance %s error: %s %s", what, r.Code, r.Message)
+}
+
+type binanceTradePage struct {
+	Total int            `json:"total"`
+	List  []binanceTrade `json:"list"`
+}
+
+type binanceTrade struct {
+	Time           int64        `json:"time"`
+	Symbol         string       `json:"symbol"`
+	Side           string       `json:"side"`
+	PositionSide   string       `json:"positionSide"`
+	Price          binanceFloat `json:"price"`
+	Qty            binanceFloat `json:"qty"`
+	RealizedProfit binanceFloat `json:"realizedProfit"`
+}
+
+type binancePortfolioDetail struct {
+	MarginBalance *binanceFloat `json:"marginBalance"`
+}
+
+type binancePosition struct {
+	Symbol           string       `json:"symbol"`
+	PositionSide     string       `json:"positionSide"` // BOTH (one-way), LONG or SHORT
+	PositionAmount   binanceFloat `json:"positionAmount"`
+	EntryPrice       binanceFloat `json:"entryPrice"`
+	LiquidationPrice binanceFloat `json:"liquidationPrice"`
+	Leverage         int          `json:"leverage"`
+	Isolated         bool         `json:"isolated"`
+}
+
+// binanceFloat is a number Binance may send quoted or bare; empty and malformed
+// values decode as 0. A malformed value keeps its raw text in malformed so the
+// provider can report it through its Logger (see number).
+type binanceFloat struct {
+	value     float64
+	malformed string
+}
+
+func (f *binanceFloat) UnmarshalJSON(data []byte) error {
+	*f = binanceFloat{}
+	raw := strings.Trim(strings.TrimSpace(string(data)), `"`)
+	if raw == "" || raw == "null" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		f.malformed = raw
+		return nil
+	}
+	f.value = value
+	return nil
+}
+
+// number returns f's value, logging a malformed raw value for field.
+func (p *binanceProvider) number(field string, f binanceFloat) float64 {
+	if f.malformed != "" {
+		p.logger.Warnf("⚠️  Binance unexpected number format field=%s value=%q", field, f.malformed)
+	}
+	return f.value
+}
+
+// formatBinanceSymbol normalizes a Binance Futures symbol to the BTCUSDT form used
+// across providers.
+func formatBinanceSymbol(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	return strings.NewReplacer("-", "", "_", "", "/", "").Replace(symbol)
+}
@@ -0,0 +1,117 @@
+package copytrading
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// binanceFake serves canned bapi responses keyed by the last path segment.
+type binanceFake struct {
+	mu        sync.Mutex
+	responses map[string]string
+}
+
+func newBinanceFake() *binanceFake {
+	return &binanceFake{responses: map[string]string{
+		"trade-history": `{"code":"000000","success":true,"data":{"total":0,"list":[]}}`,
+		"detail":        `{"code":"000000","success":true,"data":{"marginBalance":"1000"}}`,
+		"positions":     `{"code":"000000","success":true,"data":[]}`,
+	}}
+}
+
+func (f *binanceFake) set(endpoint, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[endpoint] = body
+}
+
+func (f *binanceFake) client() *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		endpoint := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		body, ok := f.responses[endpoint]
+		if !ok {
+			return jsonResponse(http.StatusNotFound, `{}`), nil
+		}
+		return jsonResponse(http.StatusOK, body), nil
+	})}
+}
+
+func newTestBinanceProvider(fake *binanceFake, cfg Config) *binanceProvider {
+	cfg.Identifier = "123456"
+	cfg.HTTPClient = fake.client()
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Hour
+	}
+	if cfg.SharedCache == nil {
+		cfg.SharedCache = NewSharedCache(0, nil)
+	}
+	return newBinanceProvider(cfg).(*binanceProvider)
+}
+
+func binancePositions(entries ...string) string {
+	return `{"code":"000000","success":true,"data":[` + strings.Join(entries, ",") + `]}`
+}
+
+func binanceTrades(entries ...string) string {
+	return `{"code":"000000","success":true,"data":{"list":[` + strings.Join(entries, ",") + `]}}`
+}
+
+func TestBinanceSeedsSilentlyThenEmitsPositionChanges(t *testing.T) {
+	fake := newBinanceFake()
+	fake.set("trade-history", binanceTrades(`{"time":1700000000000,"symbol":"BTCUSDT","side":"BUY","price":"100","qty":"1"}`))
+	fake.set("positions", binancePositions(`{"symbol":"BTCUSDT","positionSide":"BOTH","positionAmount":"1","entryPrice":"100","leverage":5,"isolated":false}`))
+
+	p := newTestBinanceProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 || !p.Initialized() {
+		t.Fatalf("the initial book must seed without signals, got %d", len(out))
+	}
+
+	// the leader opens an ETH short in hedge mode and closes BTC
+	fake.set("trade-history", binanceTrades(
+		`{"time":1700000060000,"symbol":"ETHUSDT","side":"SELL","positionSide":"SHORT","price":10,"qty":5}`,
+		`{"time":1700000120000,"symbol":"BTCUSDT","side":"SELL","price":"110","qty":"1","realizedProfit":"10"}`,
+	))
+	fake.set("positions", binancePositions(
+		`{"symbol":"ETHUSDT","positionSide":"SHORT","positionAmount":"-5","entryPrice":"10","leverage":3,"isolated":true}`,
+		`{"symbol":"ETHUSDT","positionSide":"LONG","positionAmount":"0","leverage":3,"isolated":true}`,
+	))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Signal{}
+	for len(out) > 0 {
+		sig := <-out
+		got[sig.Symbol] = sig
+	}
+	if sig := got["ETHUSDT"]; sig.Action != ActionOpenShort || sig.Price != 10 || sig.LeaderLeverage != 3 || sig.MarginMode != "isolated" {
+		t.Fatalf("expected an isolated ETH short at 10, got %+v", sig)
+	}
+	if sig := got["BTCUSDT"]; sig.Action != ActionCloseLong || sig.Price != 110 {
+		t.Fatalf("expected the BTC long closed at 110, got %+v", sig)
+	}
+	if p.Cursor() != 1700000120000 {
+		t.Fatalf("expected the cursor at the last fill, got %d", p.Cursor())
+	}
+}
+
+func TestBinanceRejectsFailedEnvelope(t *testing.T) {
+	fake := newBinanceFake()
+	fake.set("detail", `{"code":"100001","message":"portfolio not found","success":false,"data":null}`)
+	p := newTestBinanceProvider(fake, Config{})
+	if err := p.fetchAndEmit(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "portfolio not found") {
+		t.Fatalf("expected the bapi error surfaced, got %v", err)
+	}
+	if p.Initialized() {
+		t.Fatal("a failed fetch must not seed the book")
+	}
+}
@@ -0,0 +1,113 @@
+package copytrading
+
+import (
+	"context"
+	"sync"
+)
+
+// withBuffer interposes a queue of up to size signals on out, so a slow consumer does
+// not stall polling. When the queue is full, policy decides: OverflowBlock (default)
+// back-pressures the provider as an unbuffered out would, OverflowDropNewest drops the
+// incoming signal and OverflowDropOldest the oldest queued one, each reported through
+// metrics.IncDropped.
+//
+// High urgency signals (closes, liquidations, flip legs and reversals) are never
+// dropped: dropping one would leave the follower holding a position the leader has
+// left. The oldest droppable signal makes room for them instead, and when nothing
+// queued is droppable the queue grows past size.
+//
+// The returned stop function must be called when Run returns; it waits until the
+// queued signals have been forwarded or ctx is done. With size <= 0 or a nil out, out
+// is returned unchanged.
+func withBuffer(ctx context.Context, out chan<- Signal, size int, policy OverflowPolicy, metrics Metrics, logger Logger) (chan<- Signal, func()) {
+	if size <= 0 || out == nil {
+		return out, func() {}
+	}
+	b := &signalBuffer{size: size, policy: policy, metrics: metrics, logger: logger}
+	in := make(chan Signal)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		closed := false
+		for !closed || len(b.queue) > 0 {
+			recv := in
+			if closed || (b.blocks() && len(b.queue) >= b.size) {
+				recv = nil
+			}
+			var send chan<- Signal
+			var next Signal
+			if len(b.queue) > 0 {
+				send, next = out, b.queue[0]
+			}
+			select {
+			case sig, ok := <-recv:
+				if !ok {
+					closed = true
+					continue
+				}
+				b.push(sig)
+			case send <- next:
+				b.queue = b.queue[1:]
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return in, func() {
+		once.Do(func() {
+			close(in)
+			wg.Wait()
+		})
+	}
+}
+
+type signalBuffer struct {
+	queue   []Signal
+	size    int
+	policy  OverflowPolicy
+	metrics Metrics
+	logger  Logger
+}
+
+// blocks reports whether a full queue back-pressures the provider rather than drop.
+func (b *signalBuffer) blocks() bool {
+	return b.policy != OverflowDropNewest && b.policy != OverflowDropOldest
+}
+
+// push queues sig, dropping a signal first when the queue is full.
+func (b *signalBuffer) push(sig Signal) {
+	if len(b.queue) >= b.size {
+		if b.policy == OverflowDropNewest && droppable(sig) {
+			b.drop(sig)
+			return
+		}
+		victim := -1
+		for i, queued := range b.queue {
+			if droppable(queued) {
+				victim = i
+				break
+			}
+		}
+		if victim < 0 && droppable(sig) {
+			b.drop(sig)
+			return
+		}
+		if victim >= 0 {
+			b.drop(b.queue[victim])
+			b.queue = append(b.queue[:victim], b.queue[victim+1:]...)
+		}
+	}
+	b.queue = append(b.queue, sig)
+}
+
+func (b *signalBuffer) drop(sig Signal) {
+	b.logger.Warnf("⚠️  signal buffer full, dropped %s %s", sig.Symbol, sig.Action)
+	b.metrics.IncDropped(string(sig.Action), sig.Symbol)
+}
+
+// droppable reports whether an overflowing buffer may drop sig.
+func droppable(sig Signal) bool {
+	return sig.Urgency != UrgencyHigh && urgencyFor(sig.Action) != UrgencyHigh
+}
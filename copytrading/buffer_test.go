@@ -0,0 +1,78 @@
+package copytrading
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferDropsAllButCloses(t *testing.T) {
+	add := func(symbol string) Signal { return Signal{Symbol: symbol, Action: ActionAddLong, Urgency: UrgencyLow} }
+	closeLong := func(symbol string) Signal {
+		return Signal{Symbol: symbol, Action: ActionCloseLong, Urgency: UrgencyHigh}
+	}
+	run := func(policy OverflowPolicy, size int, signals ...Signal) (delivered, dropped []string) {
+		metrics := newRecordingMetrics()
+		out := make(chan Signal)
+		in, stop := withBuffer(context.Background(), out, size, policy, metrics, loggerOf(nil))
+		// nobody reads out until every signal is handed over
+		for _, sig := range signals {
+			in <- sig
+		}
+		stopped := make(chan struct{})
+		go func() {
+			stop()
+			close(stopped)
+		}()
+		for {
+			select {
+			case sig := <-out:
+				delivered = append(delivered, sig.Symbol)
+			case <-stopped:
+				return delivered, metrics.dropped
+			}
+		}
+	}
+
+	delivered, dropped := run(OverflowDropOldest, 2, add("A"), add("B"), add("C"), closeLong("X"), closeLong("Y"), add("D"))
+	if strings.Join(delivered, ",") != "X,Y" || len(dropped) != 4 {
+		t.Fatalf("expected the closes kept and every add dropped, got %v (dropped %v)", delivered, dropped)
+	}
+
+	delivered, dropped = run(OverflowDropNewest, 2, add("A"), add("B"), add("C"), closeLong("X"))
+	if strings.Join(delivered, ",") != "B,X" || strings.Join(dropped, ",") != "add_long C,add_long A" {
+		t.Fatalf("expected the new add dropped and the oldest add evicted for the close, got %v (dropped %v)", delivered, dropped)
+	}
+
+	// every queued signal is a close: the queue grows rather than drop one
+	delivered, _ = run(OverflowDropOldest, 1, closeLong("X"), closeLong("Y"))
+	if strings.Join(delivered, ",") != "X,Y" {
+		t.Fatalf("expected both closes delivered, got %v", delivered)
+	}
+}
+
+func TestBufferBlocksWhenFullByDefault(t *testing.T) {
+	out := make(chan Signal)
+	in, stop := withBuffer(context.Background(), out, 1, "", newRecordingMetrics(), loggerOf(nil))
+	defer stop()
+
+	in <- Signal{Symbol: "A"}
+	select {
+	case in <- Signal{Symbol: "B"}:
+		t.Fatal("expected a full buffer to back-pressure the provider")
+	case <-time.After(20 * time.Millisecond):
+	}
+	sent := make(chan struct{})
+	go func() {
+		in <- Signal{Symbol: "B"}
+		close(sent)
+	}()
+	if sig := <-out; sig.Symbol != "A" {
+		t.Fatalf("expected A first, got %+v", sig)
+	}
+	<-sent
+	if sig := <-out; sig.Symbol != "B" {
+		t.Fatalf("expected B once room was made, got %+v", sig)
+	}
+}
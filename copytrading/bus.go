@@ -0,0 +1,215 @@
+package copytrading
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OverflowPolicy decides what happens when a subscriber's buffer is full.
+type OverflowPolicy string
+
+const (
+	OverflowBlock      OverflowPolicy = "block"       // default: back-pressure the publisher
+	OverflowDropNewest OverflowPolicy = "drop_newest" // the full subscriber misses the new signal
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // the full subscriber loses its oldest buffered signal
+)
+
+// SignalFilter selects the signals a bus subscriber receives. Empty fields match
+// everything; within a field any listed value matches.
+type SignalFilter struct {
+	Actions []SignalAction
+	Symbols []string
+	// Leaders are provider keys in "venue:identifier" form, e.g. "okx:abc".
+	Leaders []string
+}
+
+func (f SignalFilter) match(leader string, sig Signal) bool {
+	if len(f.Actions) > 0 {
+		found := false
+		for _, action := range f.Actions {
+			if action == sig.Action {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return matchAny(f.Symbols, sig.Symbol) && matchAny(f.Leaders, leader)
+}
+
+func matchAny(values []string, v string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// SignalBus is an in-process publish/subscribe hub for signals. Providers configured
+// with Config.Bus publish every emitted signal to it, and each subscriber receives
+// the matching ones. Delivery is deterministic: publishes are serialized, and every
+// subscriber sees its signals in publish order.
+type SignalBus struct {
+	pubMu sync.Mutex // serializes Publish and channel teardown
+
+	mu         sync.Mutex
+	subs       map[<-chan Signal]*busSub
+	nextID     int
+	bufferSize int
+	policy     OverflowPolicy
+	closed     bool
+}
+
+type busSub struct {
+	id     int
+	filter SignalFilter
+	ch     chan Signal
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewSignalBus creates a bus whose subscriber channels hold bufferSize signals and
+// overflow per policy (default OverflowBlock).
+func NewSignalBus(bufferSize int, policy OverflowPolicy) *SignalBus {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	if policy == "" {
+		policy = OverflowBlock
+	}
+	return &SignalBus{subs: make(map[<-chan Signal]*busSub), bufferSize: bufferSize, policy: policy}
+}
+
+// Subscribe registers a subscriber for the signals matching filter. The channel is
+// closed by Unsubscribe or Close.
+func (b *SignalBus) Subscribe(filter SignalFilter) <-chan Signal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &busSub{id: b.nextID, filter: filter, ch: make(chan Signal, b.bufferSize), done: make(chan struct{})}
+	b.nextID++
+	if b.closed {
+		close(sub.ch)
+		return sub.ch
+	}
+	b.subs[sub.ch] = sub
+	return sub.ch
+}
+
+// Unsubscribe removes the subscriber and closes its channel. A publisher blocked on
+// it is released. Unknown or already removed channels are ignored.
+func (b *SignalBus) Unsubscribe(ch <-chan Signal) {
+	b.mu.Lock()
+	sub, ok := b.subs[ch]
+	b.mu.Unlock()
+	if !ok {
+		return
+	}
+	sub.once.Do(func() { close(sub.done) })
+
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(sub.ch)
+	}
+}
+
+// Len reports the number of active subscribers.
+func (b *SignalBus) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Publish delivers sig, published by leader ("venue:identifier"), to every matching
+// subscriber in subscription order. Safe to call on a nil bus.
+func (b *SignalBus) Publish(leader string, sig Signal) {
+	if b == nil {
+		return
+	}
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+
+	b.mu.Lock()
+	subs := make([]*busSub, 0, len(b.subs))
+	for _, sub := range b.subs {
+		if sub.filter.match(leader, sig) {
+			subs = append(subs, sub)
+		}
+	}
+	b.mu.Unlock()
+	sort.Slice(subs, func(i, j int) bool { return subs[i].id < subs[j].id })
+
+	for _, sub := range subs {
+		b.deliver(sub, sig)
+	}
+}
+
+func (b *SignalBus) deliver(sub *busSub, sig Signal) {
+	switch b.policy {
+	case OverflowDropNewest:
+		select {
+		case sub.ch <- sig:
+		default:
+		}
+	case OverflowDropOldest:
+		if cap(sub.ch) == 0 {
+			// nothing buffered to evict
+			select {
+			case sub.ch <- sig:
+			default:
+			}
+			return
+		}
+		for {
+			select {
+			case sub.ch <- sig:
+				return
+			default:
+			}
+			select {
+			case <-sub.ch:
+			default:
+			}
+		}
+	default:
+		select {
+		case sub.ch <- sig:
+		case <-sub.done:
+		}
+	}
+}
+
+// Close closes every subscriber channel; later subscribers get a closed channel and
+// later publishes are dropped.
+func (b *SignalBus) Close() {
+	b.mu.Lock()
+	subs := make([]*busSub, 0, len(b.subs))
+	for _, sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.closed = true
+	b.mu.Unlock()
+	for _, sub := range subs {
+		sub.once.Do(func() { close(sub.done) })
+	}
+
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch, sub := range b.subs {
+		close(sub.ch)
+		delete(b.subs, ch)
+	}
+}
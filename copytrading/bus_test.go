@@ -0,0 +1,104 @@
+package copytrading
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func drain(ch <-chan Signal) []Signal {
+	var got []Signal
+	for {
+		select {
+		case sig, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, sig)
+		default:
+			return got
+		}
+	}
+}
+
+func TestSignalBusFilteredSubscribers(t *testing.T) {
+	bus := NewSignalBus(8, "")
+	all := bus.Subscribe(SignalFilter{})
+	closes := bus.Subscribe(SignalFilter{Actions: []SignalAction{ActionCloseLong, ActionCloseShort}})
+	eth := bus.Subscribe(SignalFilter{Symbols: []string{"ETHUSDT"}})
+	leader := bus.Subscribe(SignalFilter{Leaders: []string{"okx:b"}, Symbols: []string{"BTCUSDT"}})
+
+	bus.Publish("okx:a", Signal{Symbol: "BTCUSDT", Action: ActionOpenLong})
+	bus.Publish("okx:a", Signal{Symbol: "ETHUSDT", Action: ActionCloseShort})
+	bus.Publish("okx:b", Signal{Symbol: "BTCUSDT", Action: ActionCloseLong})
+
+	expect := func(name string, ch <-chan Signal, want ...SignalAction) {
+		t.Helper()
+		got := drain(ch)
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %v, got %+v", name, want, got)
+		}
+		for i := range want {
+			if got[i].Action != want[i] {
+				t.Fatalf("%s: expected %v in order, got %+v", name, want, got)
+			}
+		}
+	}
+	expect("all", all, ActionOpenLong, ActionCloseShort, ActionCloseLong)
+	expect("closes", closes, ActionCloseShort, ActionCloseLong)
+	expect("eth", eth, ActionCloseShort)
+	expect("leader", leader, ActionCloseLong)
+}
+
+func TestSignalBusOverflowAndUnsubscribe(t *testing.T) {
+	lossy := NewSignalBus(2, OverflowDropOldest)
+	ch := lossy.Subscribe(SignalFilter{})
+	for _, sym := range []string{"A", "B", "C"} {
+		lossy.Publish("okx:a", Signal{Symbol: sym})
+	}
+	if got := drain(ch); len(got) != 2 || got[0].Symbol != "B" || got[1].Symbol != "C" {
+		t.Fatalf("drop_oldest must keep the newest signals, got %+v", got)
+	}
+
+	// a blocked publisher is released when the slow subscriber leaves
+	bus := NewSignalBus(0, OverflowBlock)
+	slow := bus.Subscribe(SignalFilter{})
+	published := make(chan struct{})
+	go func() {
+		bus.Publish("okx:a", Signal{Symbol: "BTCUSDT"})
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	bus.Unsubscribe(slow)
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe must release a blocked publisher")
+	}
+	if _, ok := <-slow; ok {
+		t.Fatal("expected the unsubscribed channel to be closed")
+	}
+	if bus.Len() != 0 {
+		t.Fatalf("expected no subscribers, got %d", bus.Len())
+	}
+	bus.Unsubscribe(slow) // idempotent
+}
+
+func TestProviderPublishesToBus(t *testing.T) {
+	bus := NewSignalBus(8, "")
+	opens := bus.Subscribe(SignalFilter{Actions: []SignalAction{ActionOpenLong}, Leaders: []string{"okx:leader"}})
+
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	p := newTestOKXProvider(fake, Config{Bus: bus})
+	if err := p.fetchAndEmit(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}`))
+	if err := p.fetchAndEmit(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := drain(opens); len(got) != 1 || got[0].Symbol != "BTCUSDT" {
+		t.Fatalf("expected the open on the bus, got %+v", got)
+	}
+}
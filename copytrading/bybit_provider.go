@@ -0,0 +1,450 @@
+package copytrading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	bybitBaseURL       = "https://api2.bybit.com"
+	bybitCopyTradePath = "/fapi/beehive/public/v1/common"
+)
+
+// bybitProvider follows a Bybit copy-trading leader through the public leader
+// pages. Identifier is the leaderMark from the leader's profile URL.
+//
+// Bybit publishes no per-fill feed for leaders, so fill prices are implied from the
+// change in the position's average entry price; reductions fall back to the last
+// known or market price.
+type bybitProvider struct {
+	mu sync.RWMutex // guards tracker
+
+	leaderMark  string
+	client      *http.Client
+	baseURL     string
+	tracker     *positionTracker
+	store       StateStore
+	maxBody     int64
+	stablecoin  stablecoinValuer
+	shadow      *shadowMonitor // only touched by the poll loop
+	emitted     *emittedKeys   // only touched by the poll loop
+	poll        *adaptivePoll
+	backoff     *errorBackoff // only touched by the poll loop
+	errs        errorReporter
+	pause       *PauseController
+	stream      string // follow-mode variant, see streamVariant
+	onDuplicate DuplicatePolicy
+	watch       *watchHandle // set while Run is active
+	clock       Clock
+	logger      Logger
+	metrics     Metrics
+	heartbeat   time.Duration
+	bus         *SignalBus
+
+	stop           *stopSignal // closed by Stop
+	closeAllOnStop bool
+	bufferSize     int
+	overflow       OverflowPolicy
+}
+
+func newBybitProvider(cfg Config) Provider {
+	return &bybitProvider{
+		leaderMark:  strings.TrimSpace(cfg.Identifier),
+		client:      cfg.HTTPClient,
+		baseURL:     baseURLOf(cfg, bybitBaseURL),
+		tracker:     newVenueTracker(cfg, "bybit"),
+		store:       cfg.StateStore,
+		maxBody:     cfg.MaxResponseBytes,
+		stablecoin:  newStablecoinValuer(cfg),
+		shadow:      newShadowMonitor(cfg),
+		emitted:     newEmittedKeys(cfg),
+		poll:        newAdaptivePoll(cfg),
+		backoff:     newErrorBackoff(cfg),
+		errs:        newErrorReporter(cfg, "Bybit"),
+		pause:       pauseOf(cfg),
+		stream:      streamVariant(cfg),
+		onDuplicate: cfg.OnDuplicate,
+		clock:       clockOf(cfg.Clock),
+		logger:      loggerOf(cfg.Logger),
+		metrics:     metricsOf(cfg.Metrics),
+		heartbeat:   cfg.Heartbeat,
+		bus:         cfg.Bus,
+
+		stop:           newStopSignal(),
+		closeAllOnStop: cfg.CloseAllOnStop,
+		bufferSize:     cfg.BufferSize,
+		overflow:       cfg.Overflow,
+	}
+}
+
+func (p *bybitProvider) Run(ctx context.Context, out chan<- Signal) error {
+	if p.leaderMark == "" {
+		return fmt.Errorf("bybit provider requires leaderMark")
+	}
+
+	watch, err := defaultWatchRegistry.register(watchKey("bybit", p.leaderMark, p.stream), p.onDuplicate)
+	if err != nil {
+		return err
+	}
+	p.watch = watch
+	defer watch.release()
+
+	out, stopHeartbeat := withHeartbeat(ctx, out, p.heartbeat, p.clock)
+	defer stopHeartbeat()
+	out, stopBuffer := withBuffer(ctx, out, p.bufferSize, p.overflow, p.metrics, p.logger)
+	defer stopBuffer()
+
+	p.loadState()
+	defer p.saveState()
+
+	for {
+		err := p.fetchAndEmit(ctx, out)
+		if err != nil {
+			p.errs.report(p.stateKey(), err)
+		} else {
+			// persist every poll so a crash loses at most one interval
+			p.saveState()
+		}
+		p.backoff.observe(err)
+
+		timer := time.NewTimer(p.backoff.wait(p.poll.interval()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-p.stop.done():
+			timer.Stop()
+			p.closeAll(ctx, out)
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Stop asks Run to return after the poll in progress (see Stopper).
+func (p *bybitProvider) Stop() {
+	p.stop.stop()
+}
+
+// closeAll emits a close for every mirrored position when Config.CloseAllOnStop is
+// set.
+func (p *bybitProvider) closeAll(ctx context.Context, out chan<- Signal) {
+	if !p.closeAllOnStop {
+		return
+	}
+	p.mu.Lock()
+	signals := p.tracker.closeAll()
+	p.mu.Unlock()
+	p.emit(ctx, signals, out)
+}
+
+func (p *bybitProvider) stateKey() string {
+	return stateKey("bybit", p.leaderMark)
+}
+
+// emit sends signals that pass deduplication to out and the bus. It gives up once
+// ctx is done, so a consumer that stopped reading cannot hang the provider.
+func (p *bybitProvider) emit(ctx context.Context, signals []Signal, out chan<- Signal) {
+	for _, sig := range signals {
+		if !p.emitted.admit(sig.DedupKey) || !p.watch.admit(sig, p.clock.Now()) {
+			continue
+		}
+		p.logger.Debugf("📤 Bybit %s %s notional=%.2f", sig.Symbol, sig.Action, sig.NotionalUSD)
+		p.metrics.IncSignal(string(sig.Action), sig.Symbol)
+		if out != nil {
+			select {
+			case out <- sig:
+			case <-ctx.Done():
+				return
+			}
+		}
+		p.bus.Publish(p.stateKey(), sig)
+		p.shadow.record(sig)
+	}
+}
+
+// Cursor is always 0: Bybit exposes no fill feed to resume from.
+func (p *bybitProvider) Cursor() int64 {
+	return 0
+}
+
+// Initialized reports whether the leader snapshot has been seeded.
+func (p *bybitProvider) Initialized() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.tracker.initialized
+}
+
+// Ready is closed after the first successful fetch of the leader's book.
+func (p *bybitProvider) Ready() <-chan struct{} {
+	return p.tracker.ready
+}
+
+// HealthCheck fetches the leader's leader equity once, so an unknown leaderMark or an unreachable
+// endpoint is reported before Run starts.
+func (p *bybitProvider) HealthCheck(ctx context.Context) error {
+	if p.leaderMark == "" {
+		return fmt.Errorf("bybit provider requires leaderMark")
+	}
+	if _, err := p.fetchEquity(ctx); err != nil {
+		return fmt.Errorf("bybit leader %s: %w", p.leaderMark, err)
+	}
+	return nil
+}
+
+// positions returns a copy of the mirrored leader book.
+func (p *bybitProvider) positions() map[string]PositionMeta {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return copyPositions(p.tracker.lastPositions)
+}
+
+// Snapshot returns the leader's mirrored book with last known prices (see
+// SnapshotReporter).
+func (p *bybitProvider) Snapshot() map[string]PositionMeta {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.tracker.snapshot()
+}
+
+func (p *bybitProvider) loadState() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, ok := loadState(p.store, p.logger, p.stateKey()); ok {
+		p.tracker.restoreState(s)
+	}
+}
+
+func (p *bybitProvider) saveState() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.store == nil || !p.tracker.initialized {
+		return
+	}
+	s := ProviderState{SavedAt: p.clock.Now()}
+	p.tracker.exportState(&s)
+	saveState(p.store, p.logger, p.stateKey(), s)
+}
+
+func (p *bybitProvider) fetchAndEmit(ctx context.Context, out chan<- Signal) (err error) {
+	defer observeFetch(p.metrics, "bybit/poll", time.Now(), &err)
+	accountValue, err := p.fetchEquity(ctx)
+	if err != nil {
+		return err
+	}
+	if accountValue <= 0 {
+		return fmt.Errorf("%w: bybit equity %g", ErrNoEquity, accountValue)
+	}
+	positions, err := p.fetchPositions(ctx)
+	if err != nil {
+		return err
+	}
+
+	snapshot := make(map[string]PositionMeta, len(positions))
+	for sym, meta := range positions {
+		snapshot[sym] = PositionMeta{
+			Size:       meta.Size,
+			Leverage:   meta.Leverage,
+			MarginMode: meta.MarginMode,
+			EntryPrice: meta.EntryPrice,
+			LiqPrice:   meta.LiqPrice,
+		}
+	}
+
+	p.mu.Lock()
+	now := p.clock.Now()
+	for sym, meta := range snapshot {
+		prev, _ := p.tracker.position(sym)
+		if price, ok := bybitImpliedFillPrice(prev, meta); ok {
+			p.tracker.recordFill(sym, price, math.Abs(meta.Size-prev.Size), now)
+		}
+	}
+	signals := p.tracker.update(snapshot, accountValue)
+	p.mu.Unlock()
+	p.poll.observe(len(signals) > 0)
+	signals = suppressWhilePaused(p.pause, p.logger, "Bybit", signals)
+
+	p.emit(ctx, signals, out)
+	p.shadow.compare(snapshot)
+	return nil
+}
+
+// bybitImpliedFillPrice recovers the price the leader traded at from the change in
+// average entry price. Only opens, adds and flips move the entry price, so
+// reductions report ok=false.
+func bybitImpliedFillPrice(prev, curr PositionMeta) (float64, bool) {
+	if curr.Size == 0 || curr.EntryPrice <= 0 || curr.Size == prev.Size {
+		return 0, false
+	}
+	if prev.Size == 0 || (prev.Size > 0) != (curr.Size > 0) {
+		// a fresh position (or the new leg of a flip) was entered at its entry price
+		return curr.EntryPrice, true
+	}
+	if math.Abs(curr.Size) < math.Abs(prev.Size) || prev.EntryPrice <= 0 {
+		return 0, false
+	}
+	price := (curr.EntryPrice*curr.Size - prev.EntryPrice*prev.Size) / (curr.Size - prev.Size)
+	if price <= 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, false
+	}
+	return price, true
+}
+
+func (p *bybitProvider) fetchEquity(ctx context.Context) (_ float64, err error) {
+	defer observeFetch(p.metrics, "bybit/equity", time.Now(), &err)
+	var result bybitResponse[bybitLeaderIncome]
+	if err := p.get(ctx, "/leader-income", "equity", &result); err != nil {
+		return 0, err
+	}
+	equity, ok := parseBybitE8(p.logger, result.Result.EquityE8)
+	if !ok {
+		return 0, fmt.Errorf("%w: bybit leaderMark %s", ErrLeaderNotFound, p.leaderMark)
+	}
+	return p.stablecoin.toUSD(equity), nil
+}
+
+// fetchPositions returns the leader's book as signed sizes in coins. Bybit reports
+// size and side separately; shorts are signed negative, and hedge-mode legs of one
+// symbol net into a single position.
+func (p *bybitProvider) fetchPositions(ctx context.Context) (_ map[string]bybitPositionMeta, err error) {
+	defer observeFetch(p.metrics, "bybit/positions", time.Now(), &err)
+	var result bybitResponse[bybitPositionList]
+	if err := p.get(ctx, "/position/list", "position", &result); err != nil {
+		return nil, err
+	}
+
+	positions := make(map[string]bybitPositionMeta)
+	for _, pos := range result.Result.Data {
+		symbol := formatBinanceSymbol(pos.Symbol)
+		size, ok := parseBybitE8(p.logger, pos.SizeX)
+		if symbol == "" || !ok || size == 0 {
+			continue
+		}
+		size = math.Abs(size)
+		if strings.EqualFold(pos.Side, "Sell") {
+			size = -size
+		}
+
+		if meta, ok := positions[symbol]; ok {
+			meta.Size += size
+			positions[symbol] = meta
+			continue
+		}
+		leverage := 1
+		if lev, err := strconv.ParseFloat(strings.TrimSpace(pos.LeverageE2), 64); err == nil && lev >= 100 {
+			leverage = int(lev / 100)
+		}
+		marginMode := "cross"
+		if pos.IsIsolated {
+			marginMode = "isolated"
+		}
+		entry, _ := strconv.ParseFloat(strings.TrimSpace(pos.EntryPrice), 64)
+		liq, _ := strconv.ParseFloat(strings.TrimSpace(pos.LiqPrice), 64)
+		positions[symbol] = bybitPositionMeta{
+			Size:       size,
+			Leverage:   leverage,
+			MarginMode: marginMode,
+			EntryPrice: entry,
+			LiqPrice:   liq,
+		}
+	}
+	for symbol, meta := range positions {
+		if meta.Size == 0 {
+			// fully hedged legs hold no net exposure
+			delete(positions, symbol)
+		}
+	}
+	return positions, nil
+}
+
+// get fetches a leader endpoint and decodes its envelope, which reports failures
+// with HTTP 200 and a non-zero retCode.
+func (p *bybitProvider) get(ctx context.Context, path, what string, v bybitEnvelope) error {
+	params := url.Values{}
+	params.Set("leaderMark", p.leaderMark)
+	params.Set("timeStamp", fmt.Sprintf("%d", p.clock.Now().UnixMilli()))
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+bybitCopyTradePath+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	acceptGzip(req)
+
+	resp, err := doRequest(p.client, p.clock, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("bybit %s error: %s", what, resp.Status)
+	}
+	if err := decodeJSON(resp, p.maxBody, v); err != nil {
+		return err
+	}
+	return v.err(what)
+}
+
+type bybitEnvelope interface {
+	err(what string) error
+}
+
+type bybitResponse[T any] struct {
+	RetCode int    `json:"retCode"`
+	RetMsg  string `json:"retMsg"`
+	Result  T      `json:"result"`
+}
+
+func (r *bybitResponse[T]) err(what string) error {
+	if r.RetCode == 0 {
+		return nil
+	}
+	return fmt.Errorf("bybit %s error: %d %s", what, r.RetCode, r.RetMsg)
+}
+
+type bybitLeaderIncome struct {
+	EquityE8 string `json:"equityE8"`
+}
+
+type bybitPositionList struct {
+	Data []bybitPosition `json:"data"`
+}
+
+type bybitPosition struct {
+	Symbol     string `json:"symbol"`
+	Side       string `json:"side"`       // Buy or Sell
+	SizeX      string `json:"sizeX"`      // size in coins, scaled by 1e8
+	LeverageE2 string `json:"leverageE2"` // leverage, scaled by 100
+	EntryPrice string `json:"entryPrice"`
+	LiqPrice   string `json:"liqPrice"`
+	IsIsolated bool   `json:"isIsolated"`
+}
+
+type bybitPositionMeta struct {
+	Size       float64
+	Leverage   int
+	MarginMode string
+	EntryPrice float64
+	LiqPrice   float64
+}
+
+// parseBybitE8 decodes one of Bybit's 1e8-scaled integer strings, logging malformed
+// values to logger.
+func parseBybitE8(logger Logger, raw string) (float64, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		logger.Warnf("⚠️  Bybit unexpected number format value=%q", raw)
+		return 0, false
+	}
+	return value / 1e8, true
+}
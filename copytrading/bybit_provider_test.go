@@ -0,0 +1,288 @@
+package copytrading
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bybitFake serves canned leader responses keyed by the last path segment.
+type bybitFake struct {
+	mu        sync.Mutex
+	responses map[string]string
+}
+
+func newBybitFake() *bybitFake {
+	return &bybitFake{responses: map[string]string{
+		"leader-income": `{"retCode":0,"result":{"equityE8":"100000000000"}}`,
+		"list":          `{"retCode":0,"result":{"data":[]}}`,
+	}}
+}
+
+func (f *bybitFake) set(endpoint, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[endpoint] = body
+}
+
+func (f *bybitFake) client() *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		endpoint := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		body, ok := f.responses[endpoint]
+		if !ok {
+			return jsonResponse(http.StatusNotFound, `{}`), nil
+		}
+		return jsonResponse(http.StatusOK, body), nil
+	})}
+}
+
+func newTestBybitProvider(fake *bybitFake, cfg Config) *bybitProvider {
+	cfg.Identifier = "leader"
+	cfg.HTTPClient = fake.client()
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Hour
+	}
+	if cfg.SharedCache == nil {
+		cfg.SharedCache = NewSharedCache(0, nil)
+	}
+	return newBybitProvider(cfg).(*bybitProvider)
+}
+
+func bybitPositions(entries ...string) string {
+	return `{"retCode":0,"result":{"data":[` + strings.Join(entries, ",") + `]}}`
+}
+
+func TestBybitSignsShortsAndImpliesFillPrices(t *testing.T) {
+	fake := newBybitFake()
+	fake.set("list", bybitPositions(`{"symbol":"BTCUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"100"}`))
+
+	p := newTestBybitProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 || !p.Initialized() {
+		t.Fatalf("the initial book must seed without signals, got %d", len(out))
+	}
+
+	// BTC doubles at 110 (average entry 105); a hedge-mode ETH short opens at 10
+	fake.set("list", bybitPositions(
+		`{"symbol":"BTCUSDT","side":"Buy","sizeX":"200000000","leverageE2":"500","entryPrice":"105"}`,
+		`{"symbol":"ETHUSDT","side":"Sell","sizeX":"500000000","leverageE2":"300","entryPrice":"10","isIsolated":true}`,
+		`{"symbol":"ETHUSDT","side":"Buy","sizeX":"0","leverageE2":"300","isIsolated":true}`,
+	))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Signal{}
+	for len(out) > 0 {
+		sig := <-out
+		got[sig.Symbol] = sig
+	}
+	if sig := got["BTCUSDT"]; sig.Action != ActionAddLong || sig.Price != 110 || sig.LeaderLeverage != 5 {
+		t.Fatalf("expected a BTC add at the implied 110, got %+v", sig)
+	}
+	if sig := got["ETHUSDT"]; sig.Action != ActionOpenShort || sig.Price != 10 || sig.MarginMode != "isolated" {
+		t.Fatalf("expected an isolated ETH short at 10, got %+v", sig)
+	}
+	if p.positions()["ETHUSDT"].Size != -5 {
+		t.Fatalf("expected the short signed negative, got %+v", p.positions()["ETHUSDT"])
+	}
+
+	fake.set("list", bybitPositions(`{"symbol":"ETHUSDT","side":"Sell","sizeX":"500000000","leverageE2":"300","entryPrice":"10","isIsolated":true}`))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if sig := <-out; sig.Symbol != "BTCUSDT" || sig.Action != ActionCloseLong {
+		t.Fatalf("expected the BTC long closed, got %+v", sig)
+	}
+}
+
+func TestBybitRejectsFailedEnvelope(t *testing.T) {
+	fake := newBybitFake()
+	fake.set("leader-income", `{"retCode":10001,"retMsg":"leader not found"}`)
+	p := newTestBybitProvider(fake, Config{})
+	if err := p.fetchAndEmit(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "leader not found") {
+		t.Fatalf("expected the envelope error surfaced, got %v", err)
+	}
+}
+
+func TestSignalsEmittedInSymbolOrder(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		fake := newBybitFake()
+		fake.set("list", bybitPositions(`{"symbol":"XRPUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"1"}`))
+		p := newTestBybitProvider(fake, Config{})
+		out := make(chan Signal, 8)
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+
+		// XRP closes while three symbols open in the same poll
+		fake.set("list", bybitPositions(
+			`{"symbol":"SOLUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"100"}`,
+			`{"symbol":"BTCUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"100"}`,
+			`{"symbol":"ETHUSDT","side":"Sell","sizeX":"100000000","leverageE2":"500","entryPrice":"10"}`,
+		))
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+		var order []string
+		for len(out) > 0 {
+			order = append(order, (<-out).Symbol)
+		}
+		if strings.Join(order, ",") != "BTCUSDT,ETHUSDT,SOLUSDT,XRPUSDT" {
+			t.Fatalf("expected signals in symbol order, got %v", order)
+		}
+	}
+}
+
+func TestStopFinishesThePollInProgress(t *testing.T) {
+	fake := newBybitFake()
+	fake.set("list", bybitPositions(`{"symbol":"BTCUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"100"}`))
+	entered, release := make(chan struct{}), make(chan struct{})
+	var polls int
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(r.URL.Path, "/list") {
+			if polls++; polls == 2 {
+				close(entered)
+				<-release
+			}
+		}
+		return fake.client().Transport.RoundTrip(r)
+	})}
+	p := newTestBybitProvider(fake, Config{PollInterval: time.Millisecond})
+	p.client = client
+
+	out := make(chan Signal, 8)
+	done := make(chan error, 1)
+	go func() { done <- p.Run(context.Background(), out) }()
+
+	// stop while the second poll is in flight; it still emits the BTC add
+	<-entered
+	fake.set("list", bybitPositions(`{"symbol":"BTCUSDT","side":"Buy","sizeX":"200000000","leverageE2":"500","entryPrice":"105"}`))
+	p.Stop()
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+	if len(out) != 1 {
+		t.Fatalf("expected only the in-flight poll's signal, got %d", len(out))
+	}
+	if sig := <-out; sig.Action != ActionAddLong {
+		t.Fatalf("expected the in-flight poll's add, got %+v", sig)
+	}
+}
+
+func TestStopClosesTheBookWhenConfigured(t *testing.T) {
+	fake := newBybitFake()
+	fake.set("list", bybitPositions(
+		`{"symbol":"BTCUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"100"}`,
+		`{"symbol":"ETHUSDT","side":"Sell","sizeX":"300000000","leverageE2":"300","entryPrice":"10","isIsolated":true}`,
+	))
+	p := newTestBybitProvider(fake, Config{CloseAllOnStop: true})
+
+	out := make(chan Signal, 8)
+	done := make(chan error, 1)
+	go func() { done <- p.Run(context.Background(), out) }()
+	<-p.Ready()
+	p.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out) != 2 {
+		t.Fatalf("expected a close per position, got %d signals", len(out))
+	}
+	btc, eth := <-out, <-out
+	if btc.Symbol != "BTCUSDT" || btc.Action != ActionCloseLong || btc.LeaderPosBefore != 1 || btc.LeaderLeverage != 5 || btc.Confidence != 1 {
+		t.Fatalf("expected the BTC long closed in full, got %+v", btc)
+	}
+	if eth.Symbol != "ETHUSDT" || eth.Action != ActionCloseShort || eth.DeltaSize != 3 || eth.MarginMode != "isolated" {
+		t.Fatalf("expected the isolated ETH short closed in full, got %+v", eth)
+	}
+	if len(p.positions()) != 0 {
+		t.Fatalf("expected the mirrored book flat after the closes, got %+v", p.positions())
+	}
+}
+
+func TestSnapshotReportsTheMirroredBook(t *testing.T) {
+	fake := newBybitFake()
+	fake.set("list", bybitPositions(`{"symbol":"BTCUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"100"}`))
+	p := newTestBybitProvider(fake, Config{})
+	if err := p.fetchAndEmit(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	fake.set("list", bybitPositions(`{"symbol":"BTCUSDT","side":"Buy","sizeX":"200000000","leverageE2":"500","entryPrice":"105"}`))
+
+	// readers race the poll loop; run with -race to check the locking
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					p.Snapshot()
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := p.fetchAndEmit(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	book := p.Snapshot()
+	if btc := book["BTCUSDT"]; btc.Size != 2 || btc.Leverage != 5 || btc.Price != 110 {
+		t.Fatalf("expected BTC long 2 at the implied 110, got %+v", book)
+	}
+	book["BTCUSDT"] = PositionMeta{}
+	if p.Snapshot()["BTCUSDT"].Size != 2 {
+		t.Fatal("expected Snapshot to return a copy")
+	}
+}
+
+func TestRunReturnsWhenStoppedWhileOutIsFull(t *testing.T) {
+	fake := newBybitFake()
+	fake.set("list", bybitPositions(`{"symbol":"BTCUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"100"}`))
+	p := newTestBybitProvider(fake, Config{PollInterval: time.Millisecond})
+
+	// nobody reads out, which is already full
+	out := make(chan Signal, 1)
+	out <- Signal{}
+	stopCh := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- RunUntil(p, stopCh, out) }()
+	<-p.Ready()
+
+	fake.set("list", bybitPositions(`{"symbol":"BTCUSDT","side":"Buy","sizeX":"200000000","leverageE2":"500","entryPrice":"105"}`))
+	for p.Snapshot()["BTCUSDT"].Size != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(stopCh)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run stayed blocked on the full out channel after stop")
+	}
+}
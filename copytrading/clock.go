@@ -0,0 +1,21 @@
+package copytrading
+
+import "time"
+
+// Clock abstracts wall-clock time so windows, cooldowns and timestamps can be driven
+// deterministically in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// clockOf returns the configured clock, defaulting to the real one.
+func clockOf(c Clock) Clock {
+	if c == nil {
+		return realClock{}
+	}
+	return c
+}
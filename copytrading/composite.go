@@ -0,0 +1,335 @@
+package copytrading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CompositeConfig configures a Composite aggregator.
+type CompositeConfig struct {
+	// Agreement is the share of ready children that must hold the same direction on a
+	// symbol for the composite to follow it. The default 0.5 requires a strict
+	// majority; 1 requires unanimity.
+	Agreement float64
+	// ReadyQuorum is how many children must announce "provider ready" before consensus
+	// signals are emitted (default: all children). Until then child signals only
+	// update the aggregated book.
+	ReadyQuorum int
+	// Clock stamps consensus signals (default: the real clock).
+	Clock Clock
+	// Logger receives the composite's diagnostics (default: the standard log package).
+	Logger Logger
+}
+
+// Composite follows the consensus direction of several leaders. It emits an open when
+// enough leaders agree on a symbol's direction and a close when that agreement is
+// lost.
+type Composite struct {
+	children    []Provider
+	agreement   float64
+	readyQuorum int
+	clock       Clock
+	logger      Logger
+	ready       chan struct{}
+}
+
+// bookReporter is implemented by providers that can share their mirrored book, which
+// the composite reads when a child becomes ready.
+type bookReporter interface {
+	positions() map[string]PositionMeta
+}
+
+// NewComposite builds a consensus aggregator over children.
+func NewComposite(children []Provider, cfg CompositeConfig) *Composite {
+	if cfg.Agreement <= 0 {
+		cfg.Agreement = 0.5
+	}
+	if cfg.ReadyQuorum <= 0 || cfg.ReadyQuorum > len(children) {
+		cfg.ReadyQuorum = len(children)
+	}
+	return &Composite{
+		children:    children,
+		agreement:   cfg.Agreement,
+		readyQuorum: cfg.ReadyQuorum,
+		clock:       clockOf(cfg.Clock),
+		logger:      loggerOf(cfg.Logger),
+		ready:       make(chan struct{}),
+	}
+}
+
+// Ready is closed once ReadyQuorum children are ready and consensus is being emitted.
+func (c *Composite) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// HealthCheck checks every child that supports it and joins their errors.
+func (c *Composite) HealthCheck(ctx context.Context) error {
+	var errs []error
+	for i, child := range c.children {
+		if checker, ok := child.(HealthChecker); ok {
+			if err := checker.HealthCheck(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("child %d: %w", i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type childSignal struct {
+	child int
+	sig   Signal
+}
+
+func (c *Composite) Run(ctx context.Context, out chan<- Signal) error {
+	if len(c.children) == 0 {
+		return errors.New("composite provider requires at least one child")
+	}
+
+	signals := make(chan childSignal, 128)
+	readyCh := make(chan int, len(c.children))
+	doneCh := make(chan int, len(c.children))
+
+	for i, child := range c.children {
+		childOut := make(chan Signal, 64)
+		go func(i int, child Provider) {
+			if err := child.Run(ctx, childOut); err != nil {
+				c.logger.Warnf("⚠️  Composite child %d stopped: %v", i, err)
+			}
+			close(childOut)
+		}(i, child)
+		go func(i int) {
+			defer func() { doneCh <- i }()
+			for sig := range childOut {
+				select {
+				case signals <- childSignal{child: i, sig: sig}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(i)
+		go func(i int, child Provider) {
+			notifier, ok := child.(ReadyNotifier)
+			if !ok {
+				readyCh <- i
+				return
+			}
+			select {
+			case <-notifier.Ready():
+				readyCh <- i
+			case <-ctx.Done():
+			}
+		}(i, child)
+	}
+
+	book := newConsensusBook(len(c.children), c.agreement, c.clock)
+	open := false
+	done := 0
+	for {
+		var emit []Signal
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-doneCh:
+			if done++; done == len(c.children) {
+				return nil
+			}
+			continue
+		case i := <-readyCh:
+			book.markReady(i, c.children[i])
+			switch {
+			case !open && book.readyCount() >= c.readyQuorum:
+				// consensus starts from the current book, like a provider's first snapshot
+				book.seed()
+				open = true
+				close(c.ready)
+			case open:
+				emit = book.evaluateAll()
+			}
+		case ev := <-signals:
+			book.apply(ev.child, ev.sig, c.children[ev.child])
+			if open {
+				emit = book.evaluate(ev.sig.Symbol)
+			}
+		}
+
+		for _, sig := range emit {
+			select {
+			case out <- sig:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// consensusBook tracks each child's direction per symbol and the direction the
+// composite currently follows.
+type consensusBook struct {
+	agreement float64
+	clock     Clock
+	ready     []bool
+	sides     []map[string]int // per child: +1 long, -1 short
+	following map[string]int
+	last      map[string]Signal // latest child signal per symbol, for price and leverage
+}
+
+func newConsensusBook(children int, agreement float64, clock Clock) *consensusBook {
+	b := &consensusBook{
+		agreement: agreement,
+		clock:     clock,
+		ready:     make([]bool, children),
+		sides:     make([]map[string]int, children),
+		following: make(map[string]int),
+		last:      make(map[string]Signal),
+	}
+	for i := range b.sides {
+		b.sides[i] = make(map[string]int)
+	}
+	return b
+}
+
+func (b *consensusBook) readyCount() int {
+	n := 0
+	for _, r := range b.ready {
+		if r {
+			n++
+		}
+	}
+	return n
+}
+
+// markReady loads the child's book the first time it reports ready.
+func (b *consensusBook) markReady(child int, p Provider) {
+	if b.ready[child] {
+		return
+	}
+	b.ready[child] = true
+	reporter, ok := p.(bookReporter)
+	if !ok {
+		return
+	}
+	for sym, meta := range reporter.positions() {
+		if side := sign(meta.Size); side != 0 {
+			b.sides[child][sym] = side
+		}
+	}
+}
+
+// apply records a child signal. A signal implies its child is ready.
+func (b *consensusBook) apply(child int, sig Signal, p Provider) {
+	b.markReady(child, p)
+	if side := sign(sig.LeaderPosAfter); side != 0 {
+		b.sides[child][sig.Symbol] = side
+	} else {
+		delete(b.sides[child], sig.Symbol)
+	}
+	b.last[sig.Symbol] = sig
+}
+
+// consensus returns the direction enough ready children agree on, or 0.
+func (b *consensusBook) consensus(symbol string) int {
+	var longs, shorts, n int
+	for i, ready := range b.ready {
+		if !ready {
+			continue
+		}
+		n++
+		switch b.sides[i][symbol] {
+		case 1:
+			longs++
+		case -1:
+			shorts++
+		}
+	}
+	switch {
+	case b.agrees(longs, n):
+		return 1
+	case b.agrees(shorts, n):
+		return -1
+	}
+	return 0
+}
+
+func (b *consensusBook) agrees(count, n int) bool {
+	if n == 0 || count == 0 {
+		return false
+	}
+	if b.agreement >= 1 {
+		return count == n
+	}
+	return float64(count) > b.agreement*float64(n)
+}
+
+func (b *consensusBook) symbols() map[string]struct{} {
+	syms := make(map[string]struct{})
+	for _, sides := range b.sides {
+		for sym := range sides {
+			syms[sym] = struct{}{}
+		}
+	}
+	for sym := range b.following {
+		syms[sym] = struct{}{}
+	}
+	return syms
+}
+
+// seed adopts the current consensus without emitting signals.
+func (b *consensusBook) seed() {
+	for sym := range b.symbols() {
+		if side := b.consensus(sym); side != 0 {
+			b.following[sym] = side
+		}
+	}
+}
+
+func (b *consensusBook) evaluateAll() []Signal {
+	var signals []Signal
+	for sym := range b.symbols() {
+		signals = append(signals, b.evaluate(sym)...)
+	}
+	return signals
+}
+
+// evaluate emits the transition from the followed direction to the current consensus.
+// A symbol without a known price is left untouched and retried on the next change.
+func (b *consensusBook) evaluate(symbol string) []Signal {
+	prev, next := b.following[symbol], b.consensus(symbol)
+	if prev == next {
+		return nil
+	}
+
+	ref := b.last[symbol]
+	price := ref.Price
+	if price <= 0 {
+		p, err := marketPrice(symbol)
+		if err != nil || p <= 0 {
+			return nil
+		}
+		price = p
+	}
+
+	if next == 0 {
+		delete(b.following, symbol)
+	} else {
+		b.following[symbol] = next
+	}
+	meta := PositionMeta{Size: float64(next), Leverage: ref.LeaderLeverage, MarginMode: ref.MarginMode}
+	signals := transitionSignals(symbol, float64(prev), meta, price, ref.LeaderEquity, b.clock.Now())
+	for i := range signals {
+		// the consensus has no size of its own; carry the triggering leader's notional
+		signals[i].NotionalUSD = ref.NotionalUSD
+		signals[i].MarginUSD = ref.MarginUSD
+	}
+	return signals
+}
+
+func sign(v float64) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
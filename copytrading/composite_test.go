@@ -0,0 +1,125 @@
+package copytrading
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedChild is a provider whose readiness and signals are driven by the test.
+type scriptedChild struct {
+	mu      sync.Mutex
+	book    map[string]PositionMeta
+	ready   chan struct{}
+	signals chan Signal
+}
+
+func newScriptedChild(book map[string]float64) *scriptedChild {
+	return &scriptedChild{book: bookOf(book), ready: make(chan struct{}), signals: make(chan Signal)}
+}
+
+func bookOf(sizes map[string]float64) map[string]PositionMeta {
+	out := make(map[string]PositionMeta, len(sizes))
+	for sym, size := range sizes {
+		out[sym] = PositionMeta{Size: size, Leverage: 5}
+	}
+	return out
+}
+
+func (c *scriptedChild) Run(ctx context.Context, out chan<- Signal) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig := <-c.signals:
+			out <- sig
+		}
+	}
+}
+
+func (c *scriptedChild) Ready() <-chan struct{} { return c.ready }
+
+func (c *scriptedChild) positions() map[string]PositionMeta {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copyPositions(c.book)
+}
+
+func openLong(symbol string) Signal {
+	return Signal{Symbol: symbol, Action: ActionOpenLong, Price: 10, NotionalUSD: 100, LeaderPosAfter: 1, DeltaSize: 1}
+}
+
+func expectSignal(t *testing.T, out <-chan Signal) Signal {
+	t.Helper()
+	select {
+	case sig := <-out:
+		return sig
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for consensus signal")
+	}
+	return Signal{}
+}
+
+func TestCompositeWaitsForSlowChildBeforeConsensus(t *testing.T) {
+	fastA, fastB, slow := newScriptedChild(nil), newScriptedChild(nil), newScriptedChild(nil)
+	composite := NewComposite([]Provider{fastA, fastB, slow}, CompositeConfig{})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	out := make(chan Signal, 8)
+	go RunUntil(composite, stop, out)
+
+	close(fastA.ready)
+	close(fastB.ready)
+	// A alone is not a majority of two ready children, and the gate is still closed
+	fastA.signals <- openLong("ETHUSDT")
+
+	select {
+	case <-composite.Ready():
+		t.Fatal("composite must not be ready before the slow child")
+	case sig := <-out:
+		t.Fatalf("consensus emitted before all children were ready: %+v", sig)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(slow.ready)
+	<-composite.Ready()
+
+	// A's ETH long is 1 of 3: no consensus until B joins it
+	fastB.signals <- openLong("ETHUSDT")
+	sig := expectSignal(t, out)
+	if sig.Symbol != "ETHUSDT" || sig.Action != ActionOpenLong || sig.NotionalUSD != 100 {
+		t.Fatalf("unexpected consensus signal: %+v", sig)
+	}
+	select {
+	case extra := <-out:
+		t.Fatalf("unexpected extra signal: %+v", extra)
+	default:
+	}
+}
+
+func TestCompositeReadyQuorumAllowsPartialStart(t *testing.T) {
+	fast, slow := newScriptedChild(nil), newScriptedChild(map[string]float64{"ETHUSDT": -1})
+	composite := NewComposite([]Provider{fast, slow}, CompositeConfig{ReadyQuorum: 1})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	out := make(chan Signal, 8)
+	go RunUntil(composite, stop, out)
+
+	close(fast.ready)
+	<-composite.Ready()
+
+	// the lone ready child is a lopsided "consensus" of one
+	fast.signals <- openLong("ETHUSDT")
+	if sig := expectSignal(t, out); sig.Action != ActionOpenLong {
+		t.Fatalf("expected open from the ready child, got %+v", sig)
+	}
+
+	// once the short-holding child reports, the long loses its majority
+	close(slow.ready)
+	if sig := expectSignal(t, out); sig.Action != ActionCloseLong {
+		t.Fatalf("expected close after the slow child disagreed, got %+v", sig)
+	}
+}
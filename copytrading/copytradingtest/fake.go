@@ -0,0 +1,70 @@
+// Package copytradingtest provides a scripted copytrading.Provider for testing code
+// that consumes signals, without reaching an exchange.
+package copytradingtest
+
+import (
+	"context"
+	"time"
+
+	"nofx/copytrading"
+)
+
+// Step is one scripted signal, sent Delay after the previous one.
+type Step struct {
+	Delay  time.Duration
+	Signal copytrading.Signal
+}
+
+// FakeProvider replays its script onto out, then blocks until stopped like a real
+// provider with nothing left to report. Pass it as Config.Fake with Type "fake", or
+// run it directly.
+type FakeProvider struct {
+	Steps []Step
+
+	replayed chan struct{}
+}
+
+var _ copytrading.Provider = (*FakeProvider)(nil)
+
+// NewFakeProvider scripts signals to be sent back to back.
+func NewFakeProvider(signals ...copytrading.Signal) *FakeProvider {
+	steps := make([]Step, len(signals))
+	for i, sig := range signals {
+		steps[i] = Step{Signal: sig}
+	}
+	return NewScriptedProvider(steps...)
+}
+
+// NewScriptedProvider scripts signals with a delay before each.
+func NewScriptedProvider(steps ...Step) *FakeProvider {
+	return &FakeProvider{Steps: steps, replayed: make(chan struct{})}
+}
+
+// Replayed is closed once every step has been sent.
+func (f *FakeProvider) Replayed() <-chan struct{} {
+	return f.replayed
+}
+
+// Run sends the script in order and returns nil once ctx is done. A FakeProvider
+// runs once.
+func (f *FakeProvider) Run(ctx context.Context, out chan<- copytrading.Signal) error {
+	for _, step := range f.Steps {
+		if step.Delay > 0 {
+			timer := time.NewTimer(step.Delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case out <- step.Signal:
+		}
+	}
+	close(f.replayed)
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,48 @@
+package copytradingtest
+
+import (
+	"testing"
+	"time"
+
+	"nofx/copytrading"
+)
+
+func TestFakeProviderReplaysThenBlocks(t *testing.T) {
+	fake := NewScriptedProvider(
+		Step{Signal: copytrading.Signal{Symbol: "BTCUSDT", Action: copytrading.ActionOpenLong}},
+		Step{Delay: 20 * time.Millisecond, Signal: copytrading.Signal{Symbol: "BTCUSDT", Action: copytrading.ActionCloseLong}},
+	)
+	p, err := copytrading.NewProvider(copytrading.Config{Type: "fake", Fake: fake})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopCh := make(chan struct{})
+	out := make(chan copytrading.Signal)
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() { done <- copytrading.RunUntil(p, stopCh, out) }()
+	for _, want := range []copytrading.SignalAction{copytrading.ActionOpenLong, copytrading.ActionCloseLong} {
+		if sig := <-out; sig.Action != want {
+			t.Fatalf("expected %s, got %+v", want, sig)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected the scripted delay honored, replayed in %v", elapsed)
+	}
+	<-fake.Replayed()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected Run to block until stopped, returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(stopCh)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := copytrading.NewProvider(copytrading.Config{Type: "fake"}); err == nil {
+		t.Fatal("expected type fake without a script rejected")
+	}
+}
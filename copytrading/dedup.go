@@ -0,0 +1,64 @@
+package copytrading
+
+import (
+	"container/list"
+	"fmt"
+	"time"
+)
+
+// defaultDedupWindowSize is how many emitted signal keys a provider remembers when
+// Config.DedupWindowSize is unset.
+const defaultDedupWindowSize = 256
+
+// emittedKeys is a bounded LRU of recently emitted Signal.DedupKeys. It drops a
+// signal whose key was already emitted, e.g. when a flaky upstream serves an old
+// book between two fresh ones and the same change is diffed twice. A nil set admits
+// everything.
+type emittedKeys struct {
+	size  int
+	order *list.List // most recently emitted first
+	index map[string]*list.Element
+}
+
+// newEmittedKeys remembers DedupWindowSize keys (default 256); a negative size
+// disables deduplication.
+func newEmittedKeys(cfg Config) *emittedKeys {
+	size := cfg.DedupWindowSize
+	if size < 0 {
+		return nil
+	}
+	if size == 0 {
+		size = defaultDedupWindowSize
+	}
+	return &emittedKeys{size: size, order: list.New(), index: make(map[string]*list.Element)}
+}
+
+// admit reports whether a signal with key should be emitted and remembers the key.
+// Signals without a key are always admitted.
+func (e *emittedKeys) admit(key string) bool {
+	if e == nil || key == "" {
+		return true
+	}
+	if el, seen := e.index[key]; seen {
+		e.order.MoveToFront(el)
+		return false
+	}
+	e.index[key] = e.order.PushFront(key)
+	if e.order.Len() > e.size {
+		oldest := e.order.Back()
+		e.order.Remove(oldest)
+		delete(e.index, oldest.Value.(string))
+	}
+	return true
+}
+
+// signalKey identifies a leader change independently of when it was observed: the
+// fill behind it when there is one, otherwise the position move itself with the
+// entry prices around it, so a later open or close of the same size still differs.
+func signalKey(sig Signal, prev, curr map[string]PositionMeta, filledAt time.Time, filled bool) string {
+	if filled {
+		return fmt.Sprintf("%s|%s|%g|%d", sig.Symbol, sig.Action, sig.DeltaSize, filledAt.UnixMilli())
+	}
+	return fmt.Sprintf("%s|%s|%g|%g|%g|%g", sig.Symbol, sig.Action,
+		sig.LeaderPosBefore, sig.LeaderPosAfter, prev[sig.Symbol].EntryPrice, curr[sig.Symbol].EntryPrice)
+}
@@ -0,0 +1,68 @@
+package copytrading
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRepeatedUpstreamResponseEmitsOnce(t *testing.T) {
+	fake := newHyperliquidFake()
+	p := newTestHyperliquidProvider(fake, Config{})
+	p.tracker.marketPrice = func(string) (float64, error) { return 100, nil }
+	out := make(chan Signal, 8)
+	poll := func(book string) {
+		t.Helper()
+		fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[`+book+`]}`)
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	long := `{"position":{"coin":"BTC","szi":"1","entryPx":"100","leverage":{"type":"cross","value":5}}}`
+
+	// a lagging replica serves the old book between two fresh responses
+	poll("")
+	poll(long)
+	poll("")
+	poll(long)
+
+	opens := 0
+	for len(out) > 0 {
+		if sig := <-out; sig.Action == ActionOpenLong {
+			opens++
+		}
+	}
+	if opens != 1 {
+		t.Fatalf("expected the repeated open emitted once, got %d", opens)
+	}
+
+	// a genuine reopen at a new entry price is a new change
+	poll("")
+	poll(strings.Replace(long, `"100"`, `"105"`, 1))
+	if sig := lastSignal(out); sig.Action != ActionOpenLong {
+		t.Fatalf("expected the reopen emitted, got %+v", sig)
+	}
+}
+
+func TestEmittedKeysEvictsOldest(t *testing.T) {
+	keys := newEmittedKeys(Config{DedupWindowSize: 2})
+	if !keys.admit("a") || !keys.admit("b") || keys.admit("a") {
+		t.Fatal("expected a repeat inside the window dropped")
+	}
+	// a was refreshed, so c evicts b
+	if !keys.admit("c") || keys.admit("a") || !keys.admit("b") {
+		t.Fatal("expected the least recently emitted key evicted")
+	}
+	if disabled := newEmittedKeys(Config{DedupWindowSize: -1}); !disabled.admit("a") || !disabled.admit("a") {
+		t.Fatal("a negative window must disable deduplication")
+	}
+}
+
+// lastSignal drains out and returns the last signal in it.
+func lastSignal(out chan Signal) Signal {
+	var sig Signal
+	for len(out) > 0 {
+		sig = <-out
+	}
+	return sig
+}
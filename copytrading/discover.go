@@ -0,0 +1,190 @@
+package copytrading
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LeaderSummary is a leaderboard entry that can be followed via its Config.
+type LeaderSummary struct {
+	Venue      string // provider type accepted by NewProvider
+	Identifier string // wallet address (Hyperliquid) or unique code (OKX)
+	Name       string
+	ROI        float64 // fraction over the leaderboard period, e.g. 0.25 = +25%
+	PnLUSD     float64
+	Followers  int
+	EquityUSD  float64
+}
+
+// Config returns a provider config following this leader.
+func (s LeaderSummary) Config() Config {
+	return Config{Type: s.Venue, Identifier: s.Identifier}
+}
+
+// DiscoverOptions tunes DiscoverLeaders.
+type DiscoverOptions struct {
+	// Limit caps the number of leaders returned (default 20).
+	Limit int
+	// Period selects the leaderboard window on Hyperliquid: "day", "week", "month"
+	// (default) or "allTime".
+	Period           string
+	HTTPClient       *http.Client
+	MaxResponseBytes int64
+}
+
+const defaultDiscoverLimit = 20
+
+// DiscoverLeaders queries a venue's public leaderboard and returns candidates sorted
+// by ROI, best first. Supported venues: "hyperliquid" and "okx".
+func DiscoverLeaders(venue string, opts DiscoverOptions) ([]LeaderSummary, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultDiscoverLimit
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	var (
+		leaders []LeaderSummary
+		err     error
+	)
+	switch strings.ToLower(venue) {
+	case "hyperliquid", "hyperliquid_wallet":
+		leaders, err = discoverHyperliquid(opts)
+	case "okx", "okx_wallet":
+		leaders, err = discoverOKX(opts)
+	default:
+		return nil, fmt.Errorf("leader discovery not supported for %q", venue)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(leaders, func(i, j int) bool { return leaders[i].ROI > leaders[j].ROI })
+	if len(leaders) > opts.Limit {
+		leaders = leaders[:opts.Limit]
+	}
+	return leaders, nil
+}
+
+func getJSON(opts DiscoverOptions, endpoint string, v interface{}) error {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	acceptGzip(req)
+
+	resp, err := doRequest(opts.HTTPClient, realClock{}, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("leaderboard error: %s", resp.Status)
+	}
+	return decodeJSON(resp, opts.MaxResponseBytes, v)
+}
+
+type hyperliquidLeaderboard struct {
+	Rows []struct {
+		EthAddress   string  `json:"ethAddress"`
+		AccountValue string  `json:"accountValue"`
+		DisplayName  *string `json:"displayName"`
+		// [["day", {...}], ["week", {...}], ...]
+		WindowPerformances [][2]json.RawMessage `json:"windowPerformances"`
+	} `json:"leaderboardRows"`
+}
+
+type hyperliquidWindowPerformance struct {
+	PnL string `json:"pnl"`
+	ROI string `json:"roi"`
+}
+
+func discoverHyperliquid(opts DiscoverOptions) ([]LeaderSummary, error) {
+	period := opts.Period
+	if period == "" {
+		period = "month"
+	}
+
+	var board hyperliquidLeaderboard
+	if err := getJSON(opts, "https://stats-data.hyperliquid.xyz/Mainnet/leaderboard", &board); err != nil {
+		return nil, err
+	}
+
+	leaders := make([]LeaderSummary, 0, len(board.Rows))
+	for _, row := range board.Rows {
+		summary := LeaderSummary{Venue: "hyperliquid", Identifier: strings.ToLower(row.EthAddress)}
+		if row.DisplayName != nil {
+			summary.Name = *row.DisplayName
+		}
+		summary.EquityUSD, _ = strconv.ParseFloat(row.AccountValue, 64)
+		for _, window := range row.WindowPerformances {
+			var name string
+			if json.Unmarshal(window[0], &name) != nil || name != period {
+				continue
+			}
+			var perf hyperliquidWindowPerformance
+			if json.Unmarshal(window[1], &perf) == nil {
+				summary.ROI, _ = strconv.ParseFloat(perf.ROI, 64)
+				summary.PnLUSD, _ = strconv.ParseFloat(perf.PnL, 64)
+			}
+		}
+		if summary.Identifier != "" {
+			leaders = append(leaders, summary)
+		}
+	}
+	return leaders, nil
+}
+
+type okxLeadTraders struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data []struct {
+		Ranks []struct {
+			UniqueCode    string `json:"uniqueCode"`
+			NickName      string `json:"nickName"`
+			PnlRatio      string `json:"pnlRatio"`
+			PnL           string `json:"pnl"`
+			CopyTraderNum string `json:"copyTraderNum"`
+			AUM           string `json:"aum"`
+		} `json:"ranks"`
+	} `json:"data"`
+}
+
+func discoverOKX(opts DiscoverOptions) ([]LeaderSummary, error) {
+	params := url.Values{}
+	params.Set("instType", "SWAP")
+	params.Set("sortType", "overview")
+	params.Set("limit", strconv.Itoa(opts.Limit))
+
+	var result okxLeadTraders
+	if err := getJSON(opts, "https://www.okx.com/api/v5/copytrading/public-lead-traders?"+params.Encode(), &result); err != nil {
+		return nil, err
+	}
+	if result.Code != "" && result.Code != "0" {
+		return nil, fmt.Errorf("okx leaderboard error: %s %s", result.Code, result.Msg)
+	}
+
+	var leaders []LeaderSummary
+	for _, page := range result.Data {
+		for _, rank := range page.Ranks {
+			if rank.UniqueCode == "" {
+				continue
+			}
+			summary := LeaderSummary{Venue: "okx", Identifier: rank.UniqueCode, Name: rank.NickName}
+			summary.ROI, _ = strconv.ParseFloat(rank.PnlRatio, 64)
+			summary.PnLUSD, _ = strconv.ParseFloat(rank.PnL, 64)
+			summary.EquityUSD, _ = strconv.ParseFloat(rank.AUM, 64)
+			summary.Followers, _ = strconv.Atoi(rank.CopyTraderNum)
+			leaders = append(leaders, summary)
+		}
+	}
+	return leaders, nil
+}
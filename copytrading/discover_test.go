@@ -0,0 +1,67 @@
+package copytrading
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func leaderboardClient(t *testing.T, host, body string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if !strings.Contains(r.URL.Host, host) {
+			t.Fatalf("unexpected leaderboard host %s", r.URL.Host)
+		}
+		return jsonResponse(http.StatusOK, body), nil
+	})}
+}
+
+func TestDiscoverHyperliquidLeaders(t *testing.T) {
+	client := leaderboardClient(t, "hyperliquid.xyz", `{"leaderboardRows":[
+		{"ethAddress":"0xAAAA000000000000000000000000000000000001","accountValue":"50000","displayName":null,
+		 "windowPerformances":[["day",{"pnl":"10","roi":"0.9"}],["month",{"pnl":"5000","roi":"0.10"}]]},
+		{"ethAddress":"0xbbbb000000000000000000000000000000000002","accountValue":"20000","displayName":"whale",
+		 "windowPerformances":[["month",{"pnl":"8000","roi":"0.40"}]]}
+	]}`)
+
+	leaders, err := DiscoverLeaders("hyperliquid", DiscoverOptions{HTTPClient: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaders) != 2 {
+		t.Fatalf("expected two leaders, got %+v", leaders)
+	}
+	best := leaders[0]
+	if best.Identifier != "0xbbbb000000000000000000000000000000000002" || best.Name != "whale" ||
+		best.ROI != 0.40 || best.PnLUSD != 8000 || best.EquityUSD != 20000 {
+		t.Fatalf("unexpected top leader: %+v", best)
+	}
+	if leaders[1].Identifier != "0xaaaa000000000000000000000000000000000001" || leaders[1].ROI != 0.10 {
+		t.Fatalf("expected the monthly window to be used: %+v", leaders[1])
+	}
+	if cfg := best.Config(); cfg.Type != "hyperliquid" || cfg.Identifier != best.Identifier {
+		t.Fatalf("unexpected provider config: %+v", cfg)
+	}
+}
+
+func TestDiscoverOKXLeaders(t *testing.T) {
+	client := leaderboardClient(t, "okx.com", `{"code":"0","msg":"","data":[{"ranks":[
+		{"uniqueCode":"A1B2","nickName":"alpha","pnlRatio":"1.25","pnl":"12000","copyTraderNum":"150","aum":"300000"},
+		{"uniqueCode":"C3D4","nickName":"beta","pnlRatio":"2.5","pnl":"900","copyTraderNum":"7","aum":"1000"}
+	]}]}`)
+
+	leaders, err := DiscoverLeaders("okx", DiscoverOptions{HTTPClient: client, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaders) != 1 {
+		t.Fatalf("expected limit to apply, got %+v", leaders)
+	}
+	want := LeaderSummary{Venue: "okx", Identifier: "C3D4", Name: "beta", ROI: 2.5, PnLUSD: 900, Followers: 7, EquityUSD: 1000}
+	if leaders[0] != want {
+		t.Fatalf("got %+v, want %+v", leaders[0], want)
+	}
+
+	if _, err := DiscoverLeaders("kraken", DiscoverOptions{HTTPClient: client}); err == nil {
+		t.Fatal("expected unsupported venue error")
+	}
+}
@@ -0,0 +1,90 @@
+package copytrading
+
+import "sync"
+
+// FanOut duplicates one provider's signal stream to many subscribers so followers of
+// the same leader don't each poll the exchange. Every subscriber receives every
+// signal in order; a slow subscriber applies back-pressure to the stream rather than
+// losing signals.
+type FanOut struct {
+	mu         sync.Mutex
+	subs       map[int]*fanOutSub
+	nextID     int
+	bufferSize int
+	closed     bool
+}
+
+type fanOutSub struct {
+	ch   chan Signal
+	done chan struct{}
+}
+
+// NewFanOut creates a fan-out whose subscriber channels hold bufferSize signals.
+func NewFanOut(bufferSize int) *FanOut {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	return &FanOut{subs: make(map[int]*fanOutSub), bufferSize: bufferSize}
+}
+
+// Subscribe registers a new subscriber. The returned function unsubscribes; it is safe
+// to call more than once. The channel is closed when the source stream ends.
+func (f *FanOut) Subscribe() (<-chan Signal, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sub := &fanOutSub{ch: make(chan Signal, f.bufferSize), done: make(chan struct{})}
+	if f.closed {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+
+	id := f.nextID
+	f.nextID++
+	f.subs[id] = sub
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs, id)
+			f.mu.Unlock()
+			close(sub.done)
+		})
+	}
+}
+
+// Len reports the number of active subscribers.
+func (f *FanOut) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
+
+// Run forwards every signal from in to all subscribers until in is closed, then
+// closes the remaining subscriber channels.
+func (f *FanOut) Run(in <-chan Signal) {
+	for sig := range in {
+		f.mu.Lock()
+		subs := make([]*fanOutSub, 0, len(f.subs))
+		for _, sub := range f.subs {
+			subs = append(subs, sub)
+		}
+		f.mu.Unlock()
+
+		for _, sub := range subs {
+			select {
+			case sub.ch <- sig:
+			case <-sub.done:
+			}
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for id, sub := range f.subs {
+		close(sub.ch)
+		delete(f.subs, id)
+	}
+}
@@ -0,0 +1,261 @@
+package copytrading
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFixturesExhausted is returned for a request once every recorded response for
+// it has been replayed.
+var ErrFixturesExhausted = errors.New("copytrading: fixtures exhausted")
+
+// fixtureKey names the upstream call behind req: the last path segment, plus the
+// instType query parameter and the JSON body's "type" field when present, e.g.
+// "asset", "trade-records-SWAP" or "info-clearinghouseState".
+func fixtureKey(req *http.Request) string {
+	key := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	if instType := req.URL.Query().Get("instType"); instType != "" {
+		key += "-" + instType
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			var payload struct {
+				Type string `json:"type"`
+			}
+			if json.NewDecoder(body).Decode(&payload) == nil && payload.Type != "" {
+				key += "-" + payload.Type
+			}
+			body.Close()
+		}
+	}
+	return key
+}
+
+// fixtureName is "<unix millis>-<sequence>-<key>.json", so names sort in the
+// order the responses were received.
+func fixtureName(at time.Time, seq int, key string) string {
+	return fmt.Sprintf("%013d-%06d-%s.json", at.UnixMilli(), seq, key)
+}
+
+// recordingClient returns a copy of client that writes every 2xx response body,
+// decompressed, to cfg.RecordDir.
+func recordingClient(client *http.Client, cfg Config) *http.Client {
+	recording := *client
+	recording.Transport = &recordingTransport{
+		base:   client.Transport,
+		dir:    cfg.RecordDir,
+		limit:  cfg.MaxResponseBytes,
+		clock:  clockOf(cfg.Clock),
+		logger: loggerOf(cfg.Logger),
+	}
+	return &recording
+}
+
+type recordingTransport struct {
+	base   http.RoundTripper
+	dir    string
+	limit  int64
+	clock  Clock
+	logger Logger
+
+	mu  sync.Mutex
+	seq int
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil || resp.StatusCode/100 != 2 {
+		return resp, err
+	}
+	data, err := readBody(resp, t.limit)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.ContentLength = int64(len(data))
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	t.mu.Lock()
+	t.seq++
+	name := fixtureName(t.clock.Now(), t.seq, fixtureKey(req))
+	t.mu.Unlock()
+	if err := os.MkdirAll(t.dir, 0o755); err == nil {
+		err = os.WriteFile(filepath.Join(t.dir, name), data, 0o644)
+	}
+	if err != nil {
+		t.logger.Warnf("⚠️  copytrading fixture %s not recorded: %v", name, err)
+	}
+	return resp, nil
+}
+
+// fixture is one recorded response.
+type fixture struct {
+	at   time.Time
+	body []byte
+}
+
+// fixtureTransport serves recorded responses in order, one queue per fixtureKey.
+// It is also the replay's clock, reading the time the last served response was
+// recorded.
+type fixtureTransport struct {
+	mu     sync.Mutex
+	queues map[string][]fixture
+	now    time.Time
+}
+
+func loadFixtures(dir string) (*fixtureTransport, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	t := &fixtureTransport{queues: make(map[string][]fixture)}
+	for _, name := range names {
+		parts := strings.SplitN(strings.TrimSuffix(name, ".json"), "-", 3)
+		millis, err := strconv.ParseInt(parts[0], 10, 64)
+		if len(parts) != 3 || err != nil {
+			return nil, fmt.Errorf("invalid fixture name %q", name)
+		}
+		body, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		at := time.UnixMilli(millis)
+		if t.now.IsZero() {
+			t.now = at
+		}
+		t.queues[parts[2]] = append(t.queues[parts[2]], fixture{at: at, body: body})
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no fixtures in %s", dir)
+	}
+	return t, nil
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := fixtureKey(req)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	queue := t.queues[key]
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrFixturesExhausted, key)
+	}
+	t.queues[key] = queue[1:]
+	t.now = queue[0].at
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(queue[0].body)),
+		ContentLength: int64(len(queue[0].body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *fixtureTransport) Now() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.now
+}
+
+// remaining counts the responses not yet replayed.
+func (t *fixtureTransport) remaining() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, queue := range t.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// FixtureProvider replays responses recorded with Config.RecordDir through the
+// venue's own parsing and diffing, one poll at a time, then blocks until stopped
+// like a live provider with nothing new to report. Unless Config.Clock is set,
+// time reads as when each replayed response was recorded. Market price fallbacks
+// are not recorded and still go to the network.
+type FixtureProvider struct {
+	poller interface {
+		fetchAndEmit(context.Context, chan<- Signal) error
+	}
+	fixtures *fixtureTransport
+	errs     errorReporter
+	key      string
+}
+
+// NewFixtureProvider replays the fixtures in dir through a provider of cfg.Type,
+// configured as cfg otherwise is.
+func NewFixtureProvider(cfg Config, dir string) (*FixtureProvider, error) {
+	fixtures, err := loadFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg.HTTPClient = &http.Client{Transport: fixtures}
+	cfg.RecordDir = ""
+	cfg.RateLimiter = nil
+	cfg.RequestsPerSecond = -1
+	if cfg.Clock == nil {
+		cfg.Clock = fixtures
+	}
+	p, err := NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	poller, ok := p.(interface {
+		fetchAndEmit(context.Context, chan<- Signal) error
+	})
+	if !ok {
+		return nil, fmt.Errorf("fixtures cannot be replayed through a %q provider", cfg.Type)
+	}
+	return &FixtureProvider{
+		poller:   poller,
+		fixtures: fixtures,
+		errs:     newErrorReporter(cfg, "Fixture"),
+		key:      stateKey(cfg.Type, cfg.Identifier),
+	}, nil
+}
+
+// Run replays every recorded poll onto out, reporting failed ones like a live
+// provider, and returns nil once ctx is done.
+func (f *FixtureProvider) Run(ctx context.Context, out chan<- Signal) error {
+	for left := f.fixtures.remaining(); left > 0 && ctx.Err() == nil; {
+		err := f.poller.fetchAndEmit(ctx, out)
+		if errors.Is(err, ErrFixturesExhausted) {
+			break
+		}
+		if err != nil {
+			f.errs.report(f.key, err)
+		}
+		// a poll that consumed nothing can never make progress
+		if now := f.fixtures.remaining(); now < left {
+			left = now
+		} else {
+			break
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,62 @@
+package copytrading
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFixtureProviderReplaysRecordedPolls(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	p, err := NewProvider(Config{Type: "okx", Identifier: "leader", HTTPClient: fake.client(), RequestsPerSecond: -1,
+		SharedCache: NewSharedCache(0, nil), Clock: clock, RecordDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := p.(*okxProvider)
+	out := make(chan Signal, 8)
+	if err := live.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	clock.t = clock.t.Add(time.Minute)
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","side":"buy","avgPx":"101","sz":"1","fillTime":"1700000060000","ordId":"2"}]}`)
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}`))
+	if err := live.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("expected the recorded poll to open BTC, got %d signals", len(out))
+	}
+	recorded := <-out
+	if entries, _ := os.ReadDir(dir); len(entries) < 6 {
+		t.Fatalf("expected every response of both polls recorded, got %d files", len(entries))
+	}
+
+	replay, err := NewFixtureProvider(Config{Type: "okx", Identifier: "leader", SharedCache: NewSharedCache(0, nil)}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	replayed := make(chan Signal, 8)
+	go func() { done <- replay.Run(ctx, replayed) }()
+	select {
+	case sig := <-replayed:
+		if sig.Symbol != recorded.Symbol || sig.Action != recorded.Action || !sig.DetectedAt.Equal(recorded.DetectedAt) {
+			t.Fatalf("expected the recorded signal reproduced, got %+v want %+v", sig, recorded)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the second recorded poll to emit")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 0 || replay.fixtures.remaining() != 0 {
+		t.Fatalf("expected exactly the recorded polls replayed, %d signals and %d fixtures left", len(replayed), replay.fixtures.remaining())
+	}
+}
@@ -0,0 +1,53 @@
+package copytrading
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// withHeartbeat interposes on out so that an ActionHeartbeat signal is sent whenever
+// nothing was sent for every. The returned stop function must be called when Run
+// returns; it waits until the signals already handed over have been forwarded. With
+// every <= 0 or a nil out, out is returned unchanged.
+func withHeartbeat(ctx context.Context, out chan<- Signal, every time.Duration, clock Clock) (chan<- Signal, func()) {
+	if every <= 0 || out == nil {
+		return out, func() {}
+	}
+	in := make(chan Signal)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(every / 4)
+		defer ticker.Stop()
+		last := clock.Now()
+		send := func(sig Signal) {
+			select {
+			case out <- sig:
+				last = clock.Now()
+			case <-ctx.Done():
+			}
+		}
+		for {
+			select {
+			case sig, ok := <-in:
+				if !ok {
+					return
+				}
+				send(sig)
+			case <-ticker.C:
+				if now := clock.Now(); now.Sub(last) >= every {
+					send(Signal{Action: ActionHeartbeat, Timestamp: now, DetectedAt: now})
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return in, func() {
+		once.Do(func() {
+			close(in)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package copytrading
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatWhileLeaderIdle(t *testing.T) {
+	p := newTestOKXProvider(newOKXFake(), Config{Heartbeat: 20 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan Signal, 8)
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx, out) }()
+
+	select {
+	case sig := <-out:
+		if sig.Action != ActionHeartbeat || sig.Timestamp.IsZero() || sig.Symbol != "" {
+			t.Fatalf("expected a bare heartbeat, got %+v", sig)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a heartbeat from an idle provider")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeartbeatQuietWhileSignalsFlow(t *testing.T) {
+	out := make(chan Signal, 64)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wrapped, stop := withHeartbeat(ctx, out, 40*time.Millisecond, realClock{})
+
+	for i := 0; i < 10; i++ {
+		wrapped <- Signal{Symbol: "BTCUSDT", Action: ActionAddLong}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	for len(out) > 0 {
+		if sig := <-out; sig.Action == ActionHeartbeat {
+			t.Fatal("no heartbeat is due while real signals keep flowing")
+		}
+	}
+
+	if same, _ := withHeartbeat(ctx, out, 0, realClock{}); same != chan<- Signal(out) {
+		t.Fatal("a zero Heartbeat must leave out untouched")
+	}
+}
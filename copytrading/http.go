@@ -0,0 +1,232 @@
+package copytrading
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxResponseBytes bounds a single decoded response body.
+const defaultMaxResponseBytes int64 = 8 << 20
+
+// ErrResponseTooLarge is returned when a response body exceeds Config.MaxResponseBytes.
+var ErrResponseTooLarge = errors.New("response body exceeds size limit")
+
+// defaultHTTPClient builds the client used when Config.HTTPClient is unset, routed
+// through proxyURL when one is given.
+func defaultHTTPClient(proxyURL string) (*http.Client, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	proxyURL = strings.TrimSpace(proxyURL)
+	if proxyURL == "" {
+		return client, nil
+	}
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https or socks5", proxyURL)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", proxyURL)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	client.Transport = transport
+	return client, nil
+}
+
+// defaultUserAgent is sent unless Config.Headers sets one: OKX's priapi endpoints
+// may answer non-browser clients with empty data.
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+// headerClient returns a copy of client that sets the default User-Agent and headers
+// on every request.
+func headerClient(client *http.Client, headers map[string]string) *http.Client {
+	set := http.Header{}
+	set.Set("User-Agent", defaultUserAgent)
+	for name, value := range headers {
+		set.Set(name, value)
+	}
+	withHeaders := *client
+	withHeaders.Transport = &headerTransport{base: client.Transport, headers: set}
+	return &withHeaders
+}
+
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// baseURLOf returns Config.BaseURL without a trailing slash, or production when
+// unset.
+func baseURLOf(cfg Config, production string) string {
+	if base := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/"); base != "" {
+		return base
+	}
+	return production
+}
+
+// acceptGzip asks the venue for a compressed payload. Setting the header ourselves
+// disables the transport's transparent decoding, so readBody handles it.
+func acceptGzip(req *http.Request) {
+	req.Header.Set("Accept-Encoding", "gzip")
+}
+
+// readBody reads at most limit decompressed bytes from resp.
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		limit = defaultMaxResponseBytes
+	}
+
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip response: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w (%d bytes)", ErrResponseTooLarge, limit)
+	}
+	return data, nil
+}
+
+// decodeJSON decodes a size-limited, possibly gzipped JSON response into v.
+func decodeJSON(resp *http.Response, limit int64, v interface{}) error {
+	data, err := readBody(resp, limit)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Retry policy of doRequest. Vars so tests can shorten them.
+var (
+	// retryServerErrors is how many times a 5xx is retried, waiting retryBaseDelay and
+	// doubling before each attempt.
+	retryServerErrors = 2
+	retryBaseDelay    = 500 * time.Millisecond
+	// maxRetryAfter bounds how long a 429 is waited out in place; a longer
+	// Retry-After is returned to the caller, whose poll loop backs off instead.
+	maxRetryAfter = 30 * time.Second
+)
+
+// doRequest sends req, riding out transient failures: a 429 is retried once after
+// its Retry-After (seconds or an HTTP date, default retryBaseDelay), a 5xx is
+// retried with backoff, and any other status is returned as is for the caller to
+// check. Waits honor the request's context; an HTTP-date Retry-After is measured
+// against clock. Requests with a body must be built with a replayable one
+// (bytes.Reader, strings.Reader, ...).
+func doRequest(client *http.Client, clock Clock, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	rateLimited := false
+	serverErrors := 0
+	delay := retryBaseDelay
+	for {
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var wait time.Duration
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && !rateLimited:
+			rateLimited = true
+			wait = retryAfter(resp.Header.Get("Retry-After"), clock.Now())
+			if wait > maxRetryAfter {
+				return resp, nil
+			}
+		case resp.StatusCode >= 500 && serverErrors < retryServerErrors:
+			serverErrors++
+			wait = delay
+			delay *= 2
+		default:
+			return resp, nil
+		}
+
+		if req.Body != nil && req.GetBody == nil {
+			// the body was consumed and cannot be replayed
+			return resp, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		resp.Body.Close()
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date,
+// falling back to retryBaseDelay.
+func retryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return retryBaseDelay
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return retryBaseDelay
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return retryBaseDelay
+}
+
+// sleepContext waits for d, or returns ctx's error if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,194 @@
+package copytrading
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedStatuses answers each request with the next status, echoing the body.
+func scriptedStatuses(statuses ...int) (*http.Client, *[]string) {
+	var bodies []string
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body := ""
+		if r.Body != nil {
+			data, _ := io.ReadAll(r.Body)
+			body = string(data)
+		}
+		bodies = append(bodies, body)
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		resp := jsonResponse(status, `{}`)
+		if status == http.StatusTooManyRequests {
+			resp.Header.Set("Retry-After", "0")
+		}
+		return resp, nil
+	})}, &bodies
+}
+
+func TestDoRequestRetriesTransientFailures(t *testing.T) {
+	defer func(delay time.Duration) { retryBaseDelay = delay }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	send := func(client *http.Client) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("POST", "https://venue.test/info", strings.NewReader(`{"type":"x"}`))
+		resp, err := doRequest(client, realClock{}, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// a 429 is retried once, replaying the body
+	client, bodies := scriptedStatuses(http.StatusTooManyRequests, http.StatusOK)
+	if resp := send(client); resp.StatusCode != http.StatusOK || len(*bodies) != 2 || (*bodies)[1] != `{"type":"x"}` {
+		t.Fatalf("expected one replayed retry after the 429, got %d %q", resp.StatusCode, *bodies)
+	}
+	client, bodies = scriptedStatuses(http.StatusTooManyRequests)
+	if resp := send(client); resp.StatusCode != http.StatusTooManyRequests || len(*bodies) != 2 {
+		t.Fatalf("expected the 429 retried only once, got %d after %d attempts", resp.StatusCode, len(*bodies))
+	}
+
+	// a 5xx is retried with backoff, other 4xx fail fast
+	client, bodies = scriptedStatuses(http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+	if resp := send(client); resp.StatusCode != http.StatusOK || len(*bodies) != 3 {
+		t.Fatalf("expected the 5xx retried, got %d after %d attempts", resp.StatusCode, len(*bodies))
+	}
+	client, bodies = scriptedStatuses(http.StatusNotFound, http.StatusOK)
+	if resp := send(client); resp.StatusCode != http.StatusNotFound || len(*bodies) != 1 {
+		t.Fatalf("expected a 404 returned without retry, got %d after %d attempts", resp.StatusCode, len(*bodies))
+	}
+}
+
+func TestDoRequestHonorsContextWhileWaiting(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := jsonResponse(http.StatusTooManyRequests, `{}`)
+		resp.Header.Set("Retry-After", "20")
+		return resp, nil
+	})}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://venue.test/info", nil)
+	if _, err := doRequest(client, realClock{}, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the Retry-After wait cut short by ctx, got %v", err)
+	}
+}
+
+func TestDoRequestMeasuresRetryAfterDatesOnTheClock(t *testing.T) {
+	// the HTTP-date is due on the injected clock, although it is years ahead of the
+	// wall clock: the 429 is retried at once instead of handed back to the caller
+	due := time.Date(2035, 1, 1, 0, 0, 0, 0, time.UTC)
+	attempts := 0
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			resp := jsonResponse(http.StatusTooManyRequests, `{}`)
+			resp.Header.Set("Retry-After", due.Format(http.TimeFormat))
+			return resp, nil
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	})}
+	req, _ := http.NewRequest("GET", "https://venue.test/info", nil)
+	resp, err := doRequest(client, &fakeClock{t: due}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || attempts != 2 {
+		t.Fatalf("expected the 429 retried against the injected clock, got %d after %d attempts", resp.StatusCode, attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"3":                             3 * time.Second,
+		"Wed, 01 Jan 2025 00:00:10 GMT": 10 * time.Second,
+		"Tue, 31 Dec 2024 23:59:00 GMT": 0,
+		"":                              retryBaseDelay,
+		"soon":                          retryBaseDelay,
+	}
+	for header, want := range cases {
+		if got := retryAfter(header, now); got != want {
+			t.Errorf("retryAfter(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestProxyURLRoutesDefaultClient(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.Host)
+		io.WriteString(w, `{"code":"0","data":[{"currency":"USDT","amount":"1000"}]}`)
+	}))
+	defer proxy.Close()
+
+	p, err := NewProvider(Config{Type: "okx", Identifier: "leader", ProxyURL: proxy.URL,
+		BaseURL: "http://okx.test", RequestsPerSecond: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.(*okxProvider).fetchEquity(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(proxied) != 1 || proxied[0] != "okx.test" {
+		t.Fatalf("expected the OKX request sent through the proxy, got %q", proxied)
+	}
+
+	client, err := defaultHTTPClient("socks5://127.0.0.1:1080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "https://www.okx.com", nil)
+	if u, _ := client.Transport.(*http.Transport).Proxy(req); u == nil || u.String() != "socks5://127.0.0.1:1080" {
+		t.Fatalf("expected the socks5 proxy configured, got %v", u)
+	}
+
+	for _, bad := range []string{"ftp://proxy:21", "socks5://", "http://%zz"} {
+		if _, err := NewProvider(Config{Type: "okx", Identifier: "leader", ProxyURL: bad}); err == nil {
+			t.Errorf("expected NewProvider to reject ProxyURL %q", bad)
+		}
+	}
+}
+
+func TestHeadersSetOnEveryRequest(t *testing.T) {
+	var seen []http.Header
+	fake := newOKXFake()
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		seen = append(seen, r.Header.Clone())
+		return fake.client().Transport.RoundTrip(r)
+	})}
+	p, err := NewProvider(Config{Type: "okx", Identifier: "leader", HTTPClient: client, RequestsPerSecond: -1,
+		Headers: map[string]string{"Referer": "https://www.okx.com/copy-trading"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.(*okxProvider).fetchAndEmit(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) < 3 {
+		t.Fatalf("expected every endpoint fetched, got %d requests", len(seen))
+	}
+	for _, h := range seen {
+		if h.Get("User-Agent") != defaultUserAgent || h.Get("Referer") != "https://www.okx.com/copy-trading" || h.Get("Accept-Encoding") != "gzip" {
+			t.Fatalf("expected the default User-Agent and configured headers, got %v", h)
+		}
+	}
+
+	seen = nil
+	p, _ = NewProvider(Config{Type: "okx", Identifier: "leader", HTTPClient: client, RequestsPerSecond: -1,
+		Headers: map[string]string{"user-agent": "follower/1.0"}})
+	if _, err := p.(*okxProvider).fetchEquity(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := seen[0].Get("User-Agent"); got != "follower/1.0" {
+		t.Fatalf("expected Headers to override the User-Agent, got %q", got)
+	}
+}
@@ -2,83 +2,424 @@ package copytrading
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"nofx/market"
+	"golang.org/x/crypto/sha3"
 )
 
 type hyperliquidProvider struct {
-	user         string
-	pollInterval time.Duration
-	client       *http.Client
-	lastTID      int64
-	initialized  bool
-	lastPositions map[string]float64       // signed size: long >0, short <0
-	lastPrices    map[string]float64        // last seen fill price per symbol
+	mu sync.RWMutex // guards lastTID and tracker
+
+	user        string
+	client      *http.Client
+	baseURL     string
+	lastTID     int64
+	tracker     *positionTracker
+	store       StateStore
+	maxBody     int64
+	stablecoin  stablecoinValuer
+	equity      string         // EquityBasis
+	shadow      *shadowMonitor // only touched by the poll loop
+	emitted     *emittedKeys   // only touched by the poll loop
+	poll        *adaptivePoll
+	backoff     *errorBackoff // only touched by the poll loop
+	errs        errorReporter
+	pause       *PauseController
+	stream      string // follow-mode variant, see streamVariant
+	onDuplicate DuplicatePolicy
+	watch       *watchHandle // set while Run is active
+	clock       Clock
+	logger      Logger
+	metrics     Metrics
+	heartbeat   time.Duration
+	bus         *SignalBus
+	transport   string // TransportREST or TransportWS
+	wsURL       string
+
+	verifyFills       bool
+	strictAddress     bool
+	importHistory     bool
+	historyLookback   time.Duration
+	stats             *statsTracker
+	includeOpenOrders bool
+	pendingOut        chan<- PendingOrder
+	includeProtective bool
+	protectiveOut     chan<- ProtectiveOrder
+	protective        map[int64]hyperliquidOpenOrder // only touched by the poll loop
+	protectiveSeeded  bool
+	openOrders        map[int64]hyperliquidOpenOrder // only touched by the poll loop
+	ordersSeeded      bool
+
+	stop           *stopSignal // closed by Stop
+	closeAllOnStop bool
+	bufferSize     int
+	overflow       OverflowPolicy
 }
 
-func newHyperliquidProvider(user string, pollInterval time.Duration, client *http.Client) Provider {
+func newHyperliquidProvider(cfg Config) Provider {
 	return &hyperliquidProvider{
-		user:         strings.TrimSpace(user),
-		pollInterval: pollInterval,
-		client:       client,
-		lastPositions: make(map[string]float64),
-		lastPrices:    make(map[string]float64),
+		user:        strings.TrimSpace(cfg.Identifier),
+		client:      cfg.HTTPClient,
+		baseURL:     baseURLOf(cfg, hyperliquidBaseURL),
+		tracker:     newVenueTracker(cfg, "hyperliquid"),
+		store:       cfg.StateStore,
+		maxBody:     cfg.MaxResponseBytes,
+		stablecoin:  newStablecoinValuer(cfg),
+		equity:      cfg.EquityBasis,
+		shadow:      newShadowMonitor(cfg),
+		emitted:     newEmittedKeys(cfg),
+		poll:        newAdaptivePoll(cfg),
+		backoff:     newErrorBackoff(cfg),
+		errs:        newErrorReporter(cfg, "Hyperliquid"),
+		pause:       pauseOf(cfg),
+		stream:      streamVariant(cfg),
+		onDuplicate: cfg.OnDuplicate,
+		clock:       clockOf(cfg.Clock),
+		logger:      loggerOf(cfg.Logger),
+		metrics:     metricsOf(cfg.Metrics),
+		heartbeat:   cfg.Heartbeat,
+		bus:         cfg.Bus,
+		transport:   transportOf(cfg),
+		wsURL:       hyperliquidWSURL,
+
+		verifyFills:       cfg.VerifyFills,
+		strictAddress:     cfg.StrictAddress,
+		importHistory:     cfg.ImportHistory,
+		historyLookback:   cfg.HistoryLookback,
+		stats:             newStatsTracker(),
+		includeOpenOrders: cfg.IncludeOpenOrders && cfg.PendingOrders != nil,
+		pendingOut:        cfg.PendingOrders,
+		includeProtective: cfg.IncludeProtectiveOrders && cfg.ProtectiveOrders != nil,
+		protectiveOut:     cfg.ProtectiveOrders,
+		protective:        make(map[int64]hyperliquidOpenOrder),
+		openOrders:        make(map[int64]hyperliquidOpenOrder),
+
+		stop:           newStopSignal(),
+		closeAllOnStop: cfg.CloseAllOnStop,
+		bufferSize:     cfg.BufferSize,
+		overflow:       cfg.Overflow,
 	}
 }
 
-func (p *hyperliquidProvider) Run(stopCh <-chan struct{}, out chan<- Signal) error {
-	if p.user == "" {
-		return fmt.Errorf("hyperliquid provider requires wallet address")
+func (p *hyperliquidProvider) Run(ctx context.Context, out chan<- Signal) error {
+	if err := ValidateHyperliquidAddress(p.user, p.strictAddress); err != nil {
+		return err
+	}
+
+	watch, err := defaultWatchRegistry.register(watchKey("hyperliquid", p.user, p.stream), p.onDuplicate)
+	if err != nil {
+		return err
+	}
+	p.watch = watch
+	defer watch.release()
+
+	out, stopHeartbeat := withHeartbeat(ctx, out, p.heartbeat, p.clock)
+	defer stopHeartbeat()
+	out, stopBuffer := withBuffer(ctx, out, p.bufferSize, p.overflow, p.metrics, p.logger)
+	defer stopBuffer()
+
+	p.loadState()
+	defer p.saveState()
+	if p.importHistory {
+		if err := p.importStats(ctx); err != nil {
+			p.logger.Warnf("⚠️  Hyperliquid history import failed: %v", err)
+		}
 	}
 
-	ticker := time.NewTicker(p.pollInterval)
-	defer ticker.Stop()
+	if p.transport == TransportWS {
+		streamCtx, cancel := p.stop.context(ctx)
+		defer cancel()
+		p.runStream(streamCtx, out)
+		if ctx.Err() == nil && p.stop.stopped() {
+			p.closeAll(ctx, out)
+		}
+		return nil
+	}
 
 	for {
-		if err := p.fetchAndEmit(out); err != nil {
-			log.Printf("⚠️  Hyperliquid provider error: %v", err)
+		err := p.fetchAndEmit(ctx, out)
+		if err != nil {
+			p.errs.report(p.stateKey(), err)
+		} else {
+			// persist every poll so a crash loses at most one interval
+			p.saveState()
 		}
+		p.backoff.observe(err)
 
+		timer := time.NewTimer(p.backoff.wait(p.poll.interval()))
 		select {
-		case <-stopCh:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-p.stop.done():
+			timer.Stop()
+			p.closeAll(ctx, out)
 			return nil
-		case <-ticker.C:
+		case <-timer.C:
 		}
 	}
 }
 
-func (p *hyperliquidProvider) fetchAndEmit(out chan<- Signal) error {
-	fills, err := p.fetchFills()
-	if err != nil {
+// Stop asks Run to return after the poll in progress (see Stopper).
+func (p *hyperliquidProvider) Stop() {
+	p.stop.stop()
+}
+
+// closeAll emits a close for every mirrored position when Config.CloseAllOnStop is
+// set.
+func (p *hyperliquidProvider) closeAll(ctx context.Context, out chan<- Signal) {
+	if !p.closeAllOnStop {
+		return
+	}
+	p.mu.Lock()
+	signals := p.tracker.closeAll()
+	p.mu.Unlock()
+	p.emit(ctx, signals, out)
+}
+
+const (
+	hyperliquidBaseURL = "https://api.hyperliquid.xyz"
+	hyperliquidWSURL   = "wss://api.hyperliquid.xyz/ws"
+)
+
+// hyperliquidWSMessage is a websocket push; Data depends on Channel.
+type hyperliquidWSMessage struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// runStream drives the provider from the userFills and webData2 channels instead of
+// polling: pushed fills are buffered and every pushed account state runs the same
+// diff as a poll, so the signal stream is identical. The first state after start
+// seeds the snapshot silently, and the transport reconnects until ctx is done.
+func (p *hyperliquidProvider) runStream(ctx context.Context, out chan<- Signal) {
+	var pending []hyperliquidFill
+	subscribe := func(kind string) interface{} {
+		return map[string]interface{}{
+			"method":       "subscribe",
+			"subscription": map[string]string{"type": kind, "user": p.user},
+		}
+	}
+	ws := &wsTransport{
+		logger:        p.logger,
+		name:          "Hyperliquid",
+		url:           p.wsURL,
+		subscriptions: []interface{}{subscribe("userFills"), subscribe("webData2")},
+		pingMessage:   map[string]string{"method": "ping"},
+		// handle runs on the transport's read goroutine, one message at a time
+		handle: func(msg []byte) {
+			var push hyperliquidWSMessage
+			if err := json.Unmarshal(msg, &push); err != nil {
+				return
+			}
+			switch push.Channel {
+			case "userFills":
+				var data struct {
+					Fills []hyperliquidFill `json:"fills"`
+				}
+				if err := json.Unmarshal(push.Data, &data); err != nil {
+					p.logger.Warnf("⚠️  Hyperliquid fills push undecodable: %v", err)
+					return
+				}
+				pending = append(pending, data.Fills...)
+			case "webData2":
+				var data struct {
+					ClearinghouseState hyperliquidStateRaw `json:"clearinghouseState"`
+				}
+				if err := json.Unmarshal(push.Data, &data); err != nil {
+					p.logger.Warnf("⚠️  Hyperliquid state push undecodable: %v", err)
+					return
+				}
+				state, err := data.ClearinghouseState.normalize()
+				if err == nil {
+					err = p.apply(ctx, pending, state, out)
+				}
+				if err != nil {
+					p.errs.report(p.stateKey(), err)
+					return
+				}
+				pending = nil
+			}
+		},
+	}
+	ws.run(ctx)
+}
+
+// ValidateHyperliquidAddress checks that addr is a wallet address: "0x" followed by
+// 40 hex digits. Hyperliquid answers an unknown or mistyped address with an empty
+// account rather than an error, so a bad address would otherwise follow nobody
+// forever. With strict, addr must also be in its EIP-55 checksummed form.
+func ValidateHyperliquidAddress(addr string, strict bool) error {
+	if !strings.HasPrefix(addr, "0x") {
+		return fmt.Errorf("hyperliquid address %q must start with 0x", addr)
+	}
+	if len(addr) != 42 {
+		return fmt.Errorf("hyperliquid address %q must be 42 characters, got %d", addr, len(addr))
+	}
+	if _, err := hex.DecodeString(addr[2:]); err != nil {
+		return fmt.Errorf("hyperliquid address %q is not hex", addr)
+	}
+	if strict {
+		if want := checksumAddress(addr); addr != want {
+			return fmt.Errorf("hyperliquid address %q fails its EIP-55 checksum, expected %s", addr, want)
+		}
+	}
+	return nil
+}
+
+// checksumAddress returns addr in EIP-55 mixed case: each letter is upper-cased when
+// the matching nibble of the Keccak-256 hash of the lower-case hex is 8 or more.
+func checksumAddress(addr string) string {
+	lower := strings.ToLower(addr[2:])
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(lower))
+	sum := hash.Sum(nil)
+
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := sum[i/2] >> 4
+		if i%2 == 1 {
+			nibble = sum[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+func (p *hyperliquidProvider) stateKey() string {
+	return stateKey("hyperliquid", p.user)
+}
+
+// emit sends signals that pass deduplication to out and the bus. It gives up once
+// ctx is done, so a consumer that stopped reading cannot hang the provider.
+func (p *hyperliquidProvider) emit(ctx context.Context, signals []Signal, out chan<- Signal) {
+	for _, sig := range signals {
+		if !p.emitted.admit(sig.DedupKey) || !p.watch.admit(sig, p.clock.Now()) {
+			continue
+		}
+		p.logger.Debugf("📤 Hyperliquid %s %s notional=%.2f", sig.Symbol, sig.Action, sig.NotionalUSD)
+		p.metrics.IncSignal(string(sig.Action), sig.Symbol)
+		if out != nil {
+			select {
+			case out <- sig:
+			case <-ctx.Done():
+				return
+			}
+		}
+		p.bus.Publish(p.stateKey(), sig)
+		p.shadow.record(sig)
+	}
+}
+
+// Cursor returns the last processed fill id (tid).
+func (p *hyperliquidProvider) Cursor() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastTID
+}
+
+// Initialized reports whether the leader snapshot has been seeded.
+func (p *hyperliquidProvider) Initialized() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.tracker.initialized
+}
+
+// Ready is closed after the first successful fetch of the leader's book.
+func (p *hyperliquidProvider) Ready() <-chan struct{} {
+	return p.tracker.ready
+}
+
+// HealthCheck fetches the leader's state once, so an unknown wallet or an unreachable
+// endpoint is reported before Run starts.
+func (p *hyperliquidProvider) HealthCheck(ctx context.Context) error {
+	if err := ValidateHyperliquidAddress(p.user, p.strictAddress); err != nil {
 		return err
 	}
+	if _, err := p.fetchState(ctx); err != nil {
+		return fmt.Errorf("hyperliquid leader %s: %w", p.user, err)
+	}
+	return nil
+}
+
+// positions returns a copy of the mirrored leader book.
+func (p *hyperliquidProvider) positions() map[string]PositionMeta {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return copyPositions(p.tracker.lastPositions)
+}
+
+// Snapshot returns the leader's mirrored book with last known prices (see
+// SnapshotReporter).
+func (p *hyperliquidProvider) Snapshot() map[string]PositionMeta {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.tracker.snapshot()
+}
+
+func (p *hyperliquidProvider) loadState() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, ok := loadState(p.store, p.logger, p.stateKey()); ok {
+		p.lastTID = s.LastTID
+		p.tracker.restoreState(s)
+	}
+}
+
+func (p *hyperliquidProvider) saveState() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.store == nil || !p.tracker.initialized {
+		return
+	}
+	s := ProviderState{LastTID: p.lastTID, SavedAt: p.clock.Now()}
+	p.tracker.exportState(&s)
+	saveState(p.store, p.logger, p.stateKey(), s)
+}
+
+func (p *hyperliquidProvider) fetchAndEmit(ctx context.Context, out chan<- Signal) (err error) {
+	defer observeFetch(p.metrics, "hyperliquid/poll", time.Now(), &err)
+	// fills only refine prices; the position snapshot is authoritative, so a fills
+	// outage must not hold back the diff (which may carry a close)
+	fills, err := p.fetchFills(ctx)
+	if err != nil {
+		p.logger.Warnf("⚠️  Hyperliquid fills unavailable, diffing positions without them: %v", err)
+		fills = nil
+	}
 
-	state, err := p.fetchState()
+	state, err := p.fetchState(ctx)
 	if err != nil {
 		return err
 	}
+	return p.apply(ctx, fills, state, out)
+}
 
+// apply folds new fills into prices and stats, diffs the account state against the
+// mirrored book and emits the resulting signals. Polling and streaming share it.
+func (p *hyperliquidProvider) apply(ctx context.Context, fills []hyperliquidFill, state *hyperliquidState, out chan<- Signal) error {
+	if p.verifyFills {
+		fills = p.verifiedFills(ctx, fills)
+	}
 	if state.AccountValue <= 0 {
-		return fmt.Errorf("invalid Hyperliquid account value")
+		return fmt.Errorf("%w: hyperliquid account value %g", ErrNoEquity, state.AccountValue)
 	}
 
 	// track latest price per symbol from fills
+	p.mu.Lock()
 	maxTID := p.lastTID
-	sort.Slice(fills, func(i, j int) bool {
-		if fills[i].Time == fills[j].Time {
-			return fills[i].TID < fills[j].TID
-		}
-		return fills[i].Time < fills[j].Time
-	})
+	sortHyperliquidFills(fills)
 
 	for _, fill := range fills {
 		if fill.TID <= p.lastTID {
@@ -91,240 +432,487 @@ func (p *hyperliquidProvider) fetchAndEmit(out chan<- Signal) error {
 			continue
 		}
 
-		price := fill.price()
-		if price > 0 {
-			p.lastPrices[symbol] = price
+		p.tracker.recordFill(symbol, fill.price(), fill.size(), time.UnixMilli(fill.Time))
+		if fill.Side == "B" || fill.Side == "A" {
+			p.tracker.recordFillSide(symbol, fill.Side == "B")
+		}
+		if sf := fill.statsFill(); sf.StartPosition != 0 && (sf.StartPosition > 0) != (sf.Delta > 0) {
+			p.tracker.recordCloseFill(symbol, fill.price(), math.Min(math.Abs(sf.Delta), math.Abs(sf.StartPosition)), sf.ClosedPnL)
+		}
+		if fill.Liquidation != nil {
+			p.tracker.recordLiquidation(symbol)
+		}
+		// stats start from the first poll unless history was imported
+		if p.tracker.initialized || p.stats.imported {
+			p.stats.record(symbol, fill.statsFill())
 		}
 
 		if fill.TID > maxTID {
 			maxTID = fill.TID
 		}
 	}
-	if maxTID > p.lastTID {
+	newFills := maxTID > p.lastTID
+	if newFills {
 		p.lastTID = maxTID
 	}
 
-	// diff positions: compare current sizes with last snapshot
-	if !p.initialized {
-		for sym, meta := range state.Positions {
-			p.lastPositions[sym] = meta.Size
+	positions := make(map[string]PositionMeta, len(state.Positions))
+	for symbol, meta := range state.Positions {
+		positions[symbol] = PositionMeta{
+			Size:       meta.Size,
+			Leverage:   meta.Leverage,
+			MarginMode: meta.MarginMode,
+			EntryPrice: meta.EntryPrice,
+			LiqPrice:   meta.LiqPrice,
 		}
-		p.initialized = true
-		return nil
 	}
 
-	for sym, meta := range state.Positions {
-		prev := p.lastPositions[sym]
-		delta := meta.Size - prev
-		if delta == 0 {
-			continue
-		}
-		currSym := convertHyperliquidSymbol(sym)
-		price := p.lastPrices[currSym]
-		if price <= 0 {
-			if md, err := market.Get(currSym); err == nil && md.CurrentPrice > 0 {
-				price = md.CurrentPrice
-				p.lastPrices[currSym] = price
-			}
-		}
-		if price <= 0 {
-			// keep snapshot, wait for price next round
+	signals := p.tracker.update(positions, p.stablecoin.toUSD(state.equity(p.equity)))
+	p.mu.Unlock()
+	p.poll.observe(newFills || len(signals) > 0)
+	signals = suppressWhilePaused(p.pause, p.logger, "Hyperliquid", signals)
+
+	p.emit(ctx, signals, out)
+	p.shadow.compare(positions)
+
+	if p.includeOpenOrders || p.includeProtective {
+		return p.emitOpenOrders(ctx)
+	}
+	return nil
+}
+
+// emitOpenOrders diffs the leader's resting and conditional orders against the
+// previous poll. Orders resting at startup are seeded silently, like the position
+// snapshot.
+func (p *hyperliquidProvider) emitOpenOrders(ctx context.Context) error {
+	orders, err := p.fetchOpenOrders(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[int64]hyperliquidOpenOrder, len(orders))
+	triggers := make(map[int64]hyperliquidOpenOrder)
+	for _, order := range orders {
+		if order.IsTrigger {
+			triggers[order.OID] = order
 			continue
 		}
-		// handle flip: close prev then open new
-		if prev > 0 && meta.Size < 0 {
-			out <- Signal{
-				Symbol:         currSym,
-				Action:         ActionCloseLong,
-				NotionalUSD:    math.Abs(prev) * price,
-				Price:          price,
-				LeaderEquity:   state.AccountValue,
-				LeaderLeverage: meta.Leverage,
-				MarginMode:     meta.MarginMode,
-				Timestamp:      time.Now(),
-				DeltaSize:      -prev,
-				LeaderPosBefore: prev,
-				LeaderPosAfter:  0,
-			}
-			out <- Signal{
-				Symbol:         currSym,
-				Action:         ActionOpenShort,
-				NotionalUSD:    math.Abs(meta.Size) * price,
-				Price:          price,
-				LeaderEquity:   state.AccountValue,
-				LeaderLeverage: meta.Leverage,
-				MarginMode:     meta.MarginMode,
-				Timestamp:      time.Now(),
-				DeltaSize:      meta.Size,
-				LeaderPosBefore: 0,
-				LeaderPosAfter:  meta.Size,
+		current[order.OID] = order
+	}
+
+	if p.includeProtective {
+		p.emitProtectiveOrders(ctx, triggers)
+	}
+	if !p.includeOpenOrders {
+		return nil
+	}
+
+	if p.ordersSeeded && !p.pause.Paused() {
+		for oid, order := range current {
+			if _, ok := p.openOrders[oid]; !ok {
+				select {
+				case p.pendingOut <- order.pendingOrder(PendingOrderOpen, time.UnixMilli(order.Timestamp)):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
 			}
-			p.lastPositions[sym] = meta.Size
-			continue
 		}
-		if prev < 0 && meta.Size > 0 {
-			out <- Signal{
-				Symbol:         currSym,
-				Action:         ActionCloseShort,
-				NotionalUSD:    math.Abs(prev) * price,
-				Price:          price,
-				LeaderEquity:   state.AccountValue,
-				LeaderLeverage: meta.Leverage,
-				MarginMode:     meta.MarginMode,
-				Timestamp:      time.Now(),
-				DeltaSize:      -prev,
-				LeaderPosBefore: prev,
-				LeaderPosAfter:  0,
+		for oid, order := range p.openOrders {
+			if _, ok := current[oid]; !ok {
+				select {
+				case p.pendingOut <- order.pendingOrder(PendingOrderGone, p.clock.Now()):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
 			}
-			out <- Signal{
-				Symbol:         currSym,
-				Action:         ActionOpenLong,
-				NotionalUSD:    math.Abs(meta.Size) * price,
-				Price:          price,
-				LeaderEquity:   state.AccountValue,
-				LeaderLeverage: meta.Leverage,
-				MarginMode:     meta.MarginMode,
-				Timestamp:      time.Now(),
-				DeltaSize:      meta.Size,
-				LeaderPosBefore: 0,
-				LeaderPosAfter:  meta.Size,
-			}
-			p.lastPositions[sym] = meta.Size
-			continue
 		}
+	}
+	p.openOrders = current
+	p.ordersSeeded = true
+	return nil
+}
 
-		action := deriveActionFromDelta(prev, meta.Size)
-		if action == "" {
-			p.lastPositions[sym] = meta.Size
-			continue
-		}
-		s := Signal{
-			Symbol:         currSym,
-			Action:         action,
-			NotionalUSD:    math.Abs(delta) * price,
-			Price:          price,
-			LeaderEquity:   state.AccountValue,
-			LeaderLeverage: meta.Leverage,
-			MarginMode:     meta.MarginMode,
-			Timestamp:      time.Now(),
-			DeltaSize:      delta,
-			LeaderPosBefore: prev,
-			LeaderPosAfter:  meta.Size,
-		}
-		out <- s
-		p.lastPositions[sym] = meta.Size
-	}
-	// handle symbols that were closed (now absent)
-	for sym, prev := range p.lastPositions {
-		if _, ok := state.Positions[sym]; ok {
-			continue
+func (p *hyperliquidProvider) fetchFills(ctx context.Context) (_ []hyperliquidFill, err error) {
+	defer observeFetch(p.metrics, "hyperliquid/fills", time.Now(), &err)
+	body := map[string]interface{}{
+		"type": "userFills",
+		"user": p.user,
+	}
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/info", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	acceptGzip(req)
+
+	resp, err := doRequest(p.client, p.clock, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("hyperliquid fills error: %s", resp.Status)
+	}
+
+	var fills []hyperliquidFill
+	if err := decodeJSON(resp, p.maxBody, &fills); err != nil {
+		return nil, err
+	}
+	return fills, nil
+}
+
+// Stats returns the leader's realized results seen so far.
+func (p *hyperliquidProvider) Stats() LeaderStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.stats.snapshot()
+}
+
+// importStats folds the leader's fills within the lookback window into Stats().
+func (p *hyperliquidProvider) importStats(ctx context.Context) error {
+	lookback := p.historyLookback
+	if lookback <= 0 {
+		lookback = defaultHistoryLookback
+	}
+	fills, err := p.fetchFillsSince(ctx, p.clock.Now().Add(-lookback))
+	if err != nil {
+		return err
+	}
+	sortHyperliquidFills(fills)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, fill := range fills {
+		if symbol := convertHyperliquidSymbol(fill.Coin); symbol != "" {
+			p.stats.record(symbol, fill.statsFill())
 		}
-		if prev == 0 {
-			delete(p.lastPositions, sym)
+	}
+	p.stats.imported = true
+	return nil
+}
+
+func (p *hyperliquidProvider) fetchFillsSince(ctx context.Context, since time.Time) (_ []hyperliquidFill, err error) {
+	defer observeFetch(p.metrics, "hyperliquid/fills", time.Now(), &err)
+	body := map[string]interface{}{
+		"type":      "userFillsByTime",
+		"user":      p.user,
+		"startTime": since.UnixMilli(),
+	}
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/info", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	acceptGzip(req)
+
+	resp, err := doRequest(p.client, p.clock, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("hyperliquid fill history error: %s", resp.Status)
+	}
+
+	var fills []hyperliquidFill
+	if err := decodeJSON(resp, p.maxBody, &fills); err != nil {
+		return nil, err
+	}
+	return fills, nil
+}
+
+// verifiedFills drops new fills whose order cannot be confirmed for this user, so a
+// spoofed fill never advances the cursor or updates prices.
+func (p *hyperliquidProvider) verifiedFills(ctx context.Context, fills []hyperliquidFill) []hyperliquidFill {
+	cursor := p.Cursor()
+	verified := make(map[int64]bool)
+	kept := make([]hyperliquidFill, 0, len(fills))
+	for _, fill := range fills {
+		if fill.TID <= cursor {
+			kept = append(kept, fill)
 			continue
 		}
-		currSym := convertHyperliquidSymbol(sym)
-		price := p.lastPrices[currSym]
-		if price <= 0 {
-			if md, err := market.Get(currSym); err == nil && md.CurrentPrice > 0 {
-				price = md.CurrentPrice
-				p.lastPrices[currSym] = price
-			}
+		ok, checked := verified[fill.OID]
+		if !checked {
+			ok = p.verifyFill(ctx, fill)
+			verified[fill.OID] = ok
 		}
-		if price <= 0 {
-			delete(p.lastPositions, sym)
+		if !ok {
+			p.logger.Warnf("⚠️  Hyperliquid fill tid=%d oid=%d hash=%s could not be verified, dropped", fill.TID, fill.OID, fill.Hash)
 			continue
 		}
-		action := ActionCloseLong
-		if prev < 0 {
-			action = ActionCloseShort
-		}
-		s := Signal{
-			Symbol:         currSym,
-			Action:         action,
-			NotionalUSD:    math.Abs(prev) * price,
-			Price:          price,
-			LeaderEquity:   state.AccountValue,
-			LeaderLeverage: 0,
-			MarginMode:     "",
-			Timestamp:      time.Now(),
-			DeltaSize:      -prev,
-			LeaderPosBefore: prev,
-			LeaderPosAfter:  0,
-		}
-		out <- s
-		delete(p.lastPositions, sym)
+		kept = append(kept, fill)
 	}
+	return kept
+}
 
-	return nil
+// verifyFill confirms the fill's order exists for this user on the same coin.
+func (p *hyperliquidProvider) verifyFill(ctx context.Context, fill hyperliquidFill) bool {
+	if fill.OID == 0 {
+		return false
+	}
+	status, err := p.fetchOrderStatus(ctx, fill.OID)
+	if err != nil {
+		p.logger.Warnf("⚠️  Hyperliquid order status error oid=%d: %v", fill.OID, err)
+		return false
+	}
+	return status.Status == "order" &&
+		status.Order.Order.OID == fill.OID &&
+		strings.EqualFold(status.Order.Order.Coin, fill.Coin)
 }
 
-func (p *hyperliquidProvider) fetchFills() ([]hyperliquidFill, error) {
+func (p *hyperliquidProvider) fetchOrderStatus(ctx context.Context, oid int64) (_ *hyperliquidOrderStatus, err error) {
+	defer observeFetch(p.metrics, "hyperliquid/order_status", time.Now(), &err)
 	body := map[string]interface{}{
-		"type": "userFills",
+		"type": "orderStatus",
 		"user": p.user,
+		"oid":  oid,
 	}
 	data, _ := json.Marshal(body)
-	req, err := http.NewRequest("POST", "https://api.hyperliquid.xyz/info", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/info", bytes.NewReader(data))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	acceptGzip(req)
 
-	resp, err := p.client.Do(req)
+	resp, err := doRequest(p.client, p.clock, req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("hyperliquid fills error: %s", resp.Status)
+		return nil, fmt.Errorf("hyperliquid order status error: %s", resp.Status)
 	}
 
-	var fills []hyperliquidFill
-	if err := json.NewDecoder(resp.Body).Decode(&fills); err != nil {
+	var status hyperliquidOrderStatus
+	if err := decodeJSON(resp, p.maxBody, &status); err != nil {
 		return nil, err
 	}
-	return fills, nil
+	return &status, nil
+}
+
+// hyperliquidOrderStatus is the orderStatus response; Status is "order" when the
+// order exists and "unknownOid" otherwise.
+type hyperliquidOrderStatus struct {
+	Status string `json:"status"`
+	Order  struct {
+		Order struct {
+			Coin string `json:"coin"`
+			OID  int64  `json:"oid"`
+		} `json:"order"`
+		Status string `json:"status"`
+	} `json:"order"`
 }
 
-func (p *hyperliquidProvider) fetchState() (*hyperliquidState, error) {
+func (p *hyperliquidProvider) fetchState(ctx context.Context) (_ *hyperliquidState, err error) {
+	defer observeFetch(p.metrics, "hyperliquid/state", time.Now(), &err)
 	body := map[string]interface{}{
 		"type": "clearinghouseState",
 		"user": p.user,
 	}
 	data, _ := json.Marshal(body)
-	req, err := http.NewRequest("POST", "https://api.hyperliquid.xyz/info", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/info", bytes.NewReader(data))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	acceptGzip(req)
 
-	resp, err := p.client.Do(req)
+	resp, err := doRequest(p.client, p.clock, req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	// the API answers a malformed wallet with 422; an unused but well-formed
+	// address is indistinguishable from an empty account
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		return nil, fmt.Errorf("%w: hyperliquid rejected wallet %q", ErrLeaderNotFound, p.user)
+	}
 	if resp.StatusCode >= 400 {
 		return nil, fmt.Errorf("hyperliquid state error: %s", resp.Status)
 	}
 
 	var result hyperliquidStateRaw
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := decodeJSON(resp, p.maxBody, &result); err != nil {
 		return nil, err
 	}
 
 	return result.normalize()
 }
 
+func (p *hyperliquidProvider) fetchOpenOrders(ctx context.Context) (_ []hyperliquidOpenOrder, err error) {
+	defer observeFetch(p.metrics, "hyperliquid/open_orders", time.Now(), &err)
+	body := map[string]interface{}{
+		"type": "frontendOpenOrders",
+		"user": p.user,
+	}
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/info", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	acceptGzip(req)
+
+	resp, err := doRequest(p.client, p.clock, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("hyperliquid open orders error: %s", resp.Status)
+	}
+
+	var orders []hyperliquidOpenOrder
+	if err := decodeJSON(resp, p.maxBody, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// emitProtectiveOrders diffs the leader's stop/take-profit orders, reporting moved
+// triggers as updates.
+func (p *hyperliquidProvider) emitProtectiveOrders(ctx context.Context, current map[int64]hyperliquidOpenOrder) {
+	if p.protectiveSeeded && !p.pause.Paused() {
+		for oid, order := range current {
+			prev, ok := p.protective[oid]
+			switch {
+			case !ok:
+				select {
+				case p.protectiveOut <- order.protectiveOrder(PendingOrderOpen, time.UnixMilli(order.Timestamp)):
+				case <-ctx.Done():
+					return
+				}
+			case prev.TriggerPx != order.TriggerPx || prev.Sz != order.Sz:
+				select {
+				case p.protectiveOut <- order.protectiveOrder(PendingOrderUpdated, p.clock.Now()):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		for oid, order := range p.protective {
+			if _, ok := current[oid]; !ok {
+				select {
+				case p.protectiveOut <- order.protectiveOrder(PendingOrderGone, p.clock.Now()):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+	p.protective = current
+	p.protectiveSeeded = true
+}
+
+type hyperliquidOpenOrder struct {
+	Coin       string `json:"coin"`
+	Side       string `json:"side"` // "B" bid / "A" ask
+	LimitPx    string `json:"limitPx"`
+	Sz         string `json:"sz"`
+	OID        int64  `json:"oid"`
+	Timestamp  int64  `json:"timestamp"`
+	ReduceOnly bool   `json:"reduceOnly"`
+	IsTrigger  bool   `json:"isTrigger"`
+	TriggerPx  string `json:"triggerPx"`
+	OrderType  string `json:"orderType"` // e.g. "Stop Market", "Take Profit Limit"
+}
+
+func (o hyperliquidOpenOrder) protectiveOrder(status PendingOrderStatus, ts time.Time) ProtectiveOrder {
+	trigger, _ := strconv.ParseFloat(o.TriggerPx, 64)
+	size, _ := strconv.ParseFloat(o.Sz, 64)
+	side, positionSide := "buy", "short"
+	if strings.EqualFold(o.Side, "A") {
+		side, positionSide = "sell", "long"
+	}
+	kind := ProtectiveStopLoss
+	if strings.Contains(strings.ToLower(o.OrderType), "take profit") {
+		kind = ProtectiveTakeProfit
+	}
+	return ProtectiveOrder{
+		Symbol:       convertHyperliquidSymbol(o.Coin),
+		OrderID:      strconv.FormatInt(o.OID, 10),
+		Kind:         kind,
+		Side:         side,
+		PositionSide: positionSide,
+		TriggerPrice: trigger,
+		Size:         size,
+		Status:       status,
+		Timestamp:    ts,
+	}
+}
+
+func (o hyperliquidOpenOrder) pendingOrder(status PendingOrderStatus, ts time.Time) PendingOrder {
+	price, _ := strconv.ParseFloat(o.LimitPx, 64)
+	size, _ := strconv.ParseFloat(o.Sz, 64)
+	side := "buy"
+	if strings.EqualFold(o.Side, "A") {
+		side = "sell"
+	}
+	return PendingOrder{
+		Symbol:      convertHyperliquidSymbol(o.Coin),
+		OrderID:     strconv.FormatInt(o.OID, 10),
+		Side:        side,
+		Price:       price,
+		Size:        size,
+		NotionalUSD: price * size,
+		ReduceOnly:  o.ReduceOnly,
+		Status:      status,
+		Timestamp:   ts,
+	}
+}
+
 type hyperliquidFill struct {
-	Coin string  `json:"coin"`
-	Dir  string  `json:"dir"`
-	Px   string  `json:"px"`
-	Sz   string  `json:"sz"`
-	Time int64   `json:"time"`
-	TID  int64   `json:"tid"`
+	Coin string `json:"coin"`
+	Dir  string `json:"dir"`
+	Px   string `json:"px"`
+	Sz   string `json:"sz"`
+	Time int64  `json:"time"`
+	TID  int64  `json:"tid"`
+	OID  int64  `json:"oid"`
+	Hash string `json:"hash"`
+	// Side is "B" (buy) or "A" (sell); StartPosition is the signed size before the fill.
+	Side          string `json:"side"`
+	StartPosition string `json:"startPosition"`
+	ClosedPnl     string `json:"closedPnl"`
+	// Liquidation is set on fills that liquidated the user.
+	Liquidation *struct {
+		MarkPx string `json:"markPx"`
+		Method string `json:"method"`
+	} `json:"liquidation"`
+}
+
+func sortHyperliquidFills(fills []hyperliquidFill) {
+	sort.Slice(fills, func(i, j int) bool {
+		if fills[i].Time == fills[j].Time {
+			return fills[i].TID < fills[j].TID
+		}
+		return fills[i].Time < fills[j].Time
+	})
+}
+
+func (f hyperliquidFill) statsFill() statsFill {
+	start, _ := strconv.ParseFloat(f.StartPosition, 64)
+	pnl, _ := strconv.ParseFloat(f.ClosedPnl, 64)
+	delta := f.size()
+	if strings.EqualFold(f.Side, "A") {
+		delta = -delta
+	}
+	return statsFill{
+		TID:           f.TID,
+		Time:          time.UnixMilli(f.Time),
+		StartPosition: start,
+		Delta:         delta,
+		ClosedPnL:     pnl,
+	}
 }
 
 func (f hyperliquidFill) price() float64 {
@@ -339,24 +927,39 @@ func (f hyperliquidFill) size() float64 {
 
 type hyperliquidState struct {
 	AccountValue float64
-	Positions    map[string]hyperliquidPositionMeta
+	Withdrawable float64                            // usable equity not tied up as margin
+	Positions    map[string]hyperliquidPositionMeta // by canonical symbol, e.g. "BTCUSDT"
+}
+
+// equity returns the account value for the configured basis.
+func (s *hyperliquidState) equity(basis string) float64 {
+	if basis == EquityAvailable && s.Withdrawable > 0 {
+		return s.Withdrawable
+	}
+	return s.AccountValue
 }
 
 type hyperliquidPositionMeta struct {
 	MarginMode string
 	Leverage   int
 	Size       float64 // signed size: long>0, short<0
+	EntryPrice float64
+	LiqPrice   float64
 }
 
 type hyperliquidStateRaw struct {
 	MarginSummary struct {
 		AccountValue string `json:"accountValue"`
 	} `json:"marginSummary"`
+	Withdrawable   string `json:"withdrawable"`
 	AssetPositions []struct {
 		Position struct {
-			Coin     string `json:"coin"`
-			Szi      string `json:"szi"`
-			Leverage struct {
+			Coin    string `json:"coin"`
+			Szi     string `json:"szi"`
+			EntryPx string `json:"entryPx"`
+			// LiquidationPx is null for positions that cannot be liquidated
+			LiquidationPx *string `json:"liquidationPx"`
+			Leverage      struct {
 				Type  string  `json:"type"`
 				Value float64 `json:"value"`
 			} `json:"leverage"`
@@ -366,22 +969,34 @@ type hyperliquidStateRaw struct {
 
 func (s *hyperliquidStateRaw) normalize() (*hyperliquidState, error) {
 	accountValue, _ := strconv.ParseFloat(s.MarginSummary.AccountValue, 64)
+	withdrawable, _ := strconv.ParseFloat(s.Withdrawable, 64)
 	state := &hyperliquidState{
 		AccountValue: accountValue,
+		Withdrawable: withdrawable,
 		Positions:    make(map[string]hyperliquidPositionMeta),
 	}
 
 	for _, asset := range s.AssetPositions {
-		coin := strings.ToUpper(asset.Position.Coin)
+		symbol := convertHyperliquidSymbol(asset.Position.Coin)
+		if symbol == "" {
+			continue
+		}
 		lev := int(asset.Position.Leverage.Value)
 		if lev <= 0 {
 			lev = 1
 		}
 		size, _ := strconv.ParseFloat(asset.Position.Szi, 64)
-		state.Positions[coin] = hyperliquidPositionMeta{
-			MarginMode: asset.Position.Leverage.Type,
+		entry, _ := strconv.ParseFloat(asset.Position.EntryPx, 64)
+		var liq float64
+		if asset.Position.LiquidationPx != nil {
+			liq, _ = strconv.ParseFloat(*asset.Position.LiquidationPx, 64)
+		}
+		state.Positions[symbol] = hyperliquidPositionMeta{
+			MarginMode: normalizeMarginMode(asset.Position.Leverage.Type),
 			Leverage:   lev,
 			Size:       size,
+			EntryPrice: entry,
+			LiqPrice:   liq,
 		}
 	}
 
@@ -403,6 +1018,9 @@ func mapHyperliquidAction(dir string) SignalAction {
 	}
 }
 
+// convertHyperliquidSymbol maps a Hyperliquid coin to the canonical symbol every
+// book, price and signal is keyed by: "BTC" becomes "BTCUSDT", and a coin already
+// quoted in USDT is kept as is.
 func convertHyperliquidSymbol(coin string) string {
 	coin = strings.TrimSpace(coin)
 	if coin == "" {
@@ -0,0 +1,567 @@
+package copytrading
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hyperliquidFake serves canned /info responses keyed by request type.
+type hyperliquidFake struct {
+	mu        sync.Mutex
+	responses map[string]string
+}
+
+func newHyperliquidFake() *hyperliquidFake {
+	return &hyperliquidFake{responses: map[string]string{
+		"userFills":          `[]`,
+		"clearinghouseState": `{"marginSummary":{"accountValue":"1000"},"assetPositions":[]}`,
+		"frontendOpenOrders": `[]`,
+	}}
+}
+
+func (f *hyperliquidFake) set(kind, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[kind] = body
+}
+
+func (f *hyperliquidFake) client() *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var req struct {
+			Type string `json:"type"`
+			OID  int64  `json:"oid"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return jsonResponse(http.StatusBadRequest, `{}`), nil
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		key := req.Type
+		if req.OID != 0 {
+			key = fmt.Sprintf("%s:%d", req.Type, req.OID)
+		}
+		body, ok := f.responses[key]
+		if !ok {
+			return jsonResponse(http.StatusNotFound, `{}`), nil
+		}
+		return jsonResponse(http.StatusOK, body), nil
+	})}
+}
+
+func newTestHyperliquidProvider(fake *hyperliquidFake, cfg Config) *hyperliquidProvider {
+	cfg.Identifier = "0x0000000000000000000000000000000000000001"
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Hour
+	}
+	cfg.HTTPClient = fake.client()
+	return newHyperliquidProvider(cfg).(*hyperliquidProvider)
+}
+
+func TestHyperliquidPendingOrders(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("frontendOpenOrders", `[{"coin":"BTC","side":"B","limitPx":"90000","sz":"0.5","oid":1,"timestamp":1700000000000}]`)
+
+	pending := make(chan PendingOrder, 8)
+	p := newTestHyperliquidProvider(fake, Config{IncludeOpenOrders: true, PendingOrders: pending})
+	out := make(chan Signal, 8)
+
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("orders resting at startup must be seeded silently")
+	}
+
+	fake.set("frontendOpenOrders", `[
+		{"coin":"BTC","side":"B","limitPx":"90000","sz":"0.5","oid":1,"timestamp":1700000000000},
+		{"coin":"ETH","side":"A","limitPx":"3000","sz":"2","oid":2,"timestamp":1700000001000,"reduceOnly":true},
+		{"coin":"ETH","side":"A","limitPx":"2500","sz":"2","oid":3,"timestamp":1700000001000,"isTrigger":true}
+	]`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected one new pending order, got %d", len(pending))
+	}
+	ev := <-pending
+	if ev.Symbol != "ETHUSDT" || ev.Side != "sell" || ev.Price != 3000 || ev.Size != 2 ||
+		ev.NotionalUSD != 6000 || !ev.ReduceOnly || ev.Status != PendingOrderOpen || ev.OrderID != "2" {
+		t.Fatalf("unexpected pending order: %+v", ev)
+	}
+
+	fake.set("frontendOpenOrders", `[{"coin":"ETH","side":"A","limitPx":"3000","sz":"2","oid":2,"timestamp":1700000001000,"reduceOnly":true}]`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	ev = <-pending
+	if ev.OrderID != "1" || ev.Status != PendingOrderGone || ev.Symbol != "BTCUSDT" {
+		t.Fatalf("expected BTC order gone, got %+v", ev)
+	}
+	if len(out) != 0 {
+		t.Fatalf("open orders must not produce position signals")
+	}
+}
+
+func TestHyperliquidCursorAdvancesAcrossPolls(t *testing.T) {
+	fake := newHyperliquidFake()
+	p := newTestHyperliquidProvider(fake, Config{})
+	out := make(chan Signal, 8)
+
+	var reporter CursorReporter = p
+	if reporter.Initialized() || reporter.Cursor() != 0 {
+		t.Fatal("fresh provider must start uninitialized at cursor 0")
+	}
+
+	fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","time":1700000000000,"tid":7},
+		{"coin":"BTC","px":"101","sz":"1","time":1700000000001,"tid":9}]`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Initialized() || p.Cursor() != 9 {
+		t.Fatalf("expected initialized at tid 9, got %v/%d", p.Initialized(), p.Cursor())
+	}
+
+	// older fills never move the cursor backwards
+	fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","time":1700000000000,"tid":7},
+		{"coin":"ETH","px":"10","sz":"1","time":1700000000005,"tid":15}]`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if p.Cursor() != 15 {
+		t.Fatalf("expected cursor 15, got %d", p.Cursor())
+	}
+}
+
+func TestHyperliquidUsesInjectedClock(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	store := newMemoryStateStore()
+	fake := newHyperliquidFake()
+	fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","time":1700000000000,"tid":1}]`)
+	p := newTestHyperliquidProvider(fake, Config{Clock: clock, ReentryWindow: 10 * time.Minute, StateStore: store})
+	out := make(chan Signal, 8)
+
+	setBTC := func(size string) {
+		fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+			{"position":{"coin":"BTC","szi":"`+size+`","leverage":{"type":"cross","value":5}}}]}`)
+	}
+	poll := func() {
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setBTC("1")
+	poll()
+	clock.Advance(time.Minute)
+	setBTC("0")
+	poll()
+	if sig := <-out; sig.Action != ActionCloseLong || !sig.Timestamp.Equal(clock.Now()) {
+		t.Fatalf("close must be stamped by the injected clock: %+v", sig)
+	}
+
+	// 9 minutes later on the fake clock is still inside the re-entry window
+	clock.Advance(9 * time.Minute)
+	setBTC("1")
+	poll()
+	if sig := <-out; sig.Action != ActionOpenLong || !sig.IsReentry || !sig.Timestamp.Equal(clock.Now()) {
+		t.Fatalf("expected re-entry at fake time, got %+v", sig)
+	}
+
+	p.saveState()
+	if saved, err := store.Load(p.stateKey()); err != nil || !saved.SavedAt.Equal(clock.Now()) {
+		t.Fatalf("expected state stamped by the injected clock: %+v %v", saved, err)
+	}
+}
+
+func TestHyperliquidVerifyFillsDropsUnverifiable(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("orderStatus:11", `{"status":"order","order":{"order":{"coin":"BTC","oid":11},"status":"filled"}}`)
+	fake.set("orderStatus:12", `{"status":"unknownOid"}`)
+	fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","time":1700000000000,"tid":1,"oid":11},
+		{"coin":"ETH","px":"999999","sz":"1","time":1700000001000,"tid":2,"oid":12}]`)
+
+	p := newTestHyperliquidProvider(fake, Config{VerifyFills: true})
+	if err := p.fetchAndEmit(context.Background(), make(chan Signal, 8)); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Cursor() != 1 {
+		t.Fatalf("unverified fill must not advance the cursor, got %d", p.Cursor())
+	}
+	if p.tracker.lastPrices["BTCUSDT"] != 100 {
+		t.Fatalf("verified fill must update prices, got %v", p.tracker.lastPrices)
+	}
+	if _, ok := p.tracker.lastPrices["ETHUSDT"]; ok {
+		t.Fatalf("unverified fill must not update prices, got %v", p.tracker.lastPrices)
+	}
+}
+
+func TestHyperliquidImportHistorySeedsStats(t *testing.T) {
+	fake := newHyperliquidFake()
+	history := `[
+		{"coin":"BTC","px":"100","sz":"1","side":"B","startPosition":"0","closedPnl":"0","time":1700000000000,"tid":1},
+		{"coin":"BTC","px":"150","sz":"1","side":"A","startPosition":"1","closedPnl":"50","time":1700007200000,"tid":2},
+		{"coin":"ETH","px":"10","sz":"2","side":"A","startPosition":"0","closedPnl":"0","time":1700010800000,"tid":3},
+		{"coin":"ETH","px":"20","sz":"2","side":"B","startPosition":"-2","closedPnl":"-20","time":1700014400000,"tid":4}]`
+	fake.set("userFillsByTime", history)
+	// the regular poll returns the same recent fills; they must not be counted twice
+	fake.set("userFills", history)
+
+	p := newTestHyperliquidProvider(fake, Config{ImportHistory: true, HistoryLookback: 24 * time.Hour})
+	out := make(chan Signal, 8)
+	stop := make(chan struct{})
+	close(stop)
+	if err := RunUntil(p, stop, out); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out) != 0 {
+		t.Fatalf("history import must not emit signals, got %d", len(out))
+	}
+	stats := p.Stats()
+	if stats.ClosedTrades != 2 || stats.Wins != 1 || stats.WinRate != 0.5 {
+		t.Fatalf("unexpected trade counts: %+v", stats)
+	}
+	if stats.RealizedPnLUSD != 30 {
+		t.Fatalf("expected realized PnL 30, got %+v", stats)
+	}
+	if stats.AvgHoldTime != 90*time.Minute {
+		t.Fatalf("expected average hold of 1.5h, got %v", stats.AvgHoldTime)
+	}
+}
+
+func TestHyperliquidStatsEmptyWithoutImport(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("userFills", `[{"coin":"BTC","px":"150","sz":"1","side":"A","startPosition":"1","closedPnl":"50","time":1700007200000,"tid":2}]`)
+	p := newTestHyperliquidProvider(fake, Config{})
+	if err := p.fetchAndEmit(context.Background(), make(chan Signal, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if stats := p.Stats(); stats.ClosedTrades != 0 || stats.RealizedPnLUSD != 0 {
+		t.Fatalf("fills seen on the seed poll are history, not new trades: %+v", stats)
+	}
+}
+
+func TestHyperliquidPriceVWAPAcrossCycleFills(t *testing.T) {
+	for _, tc := range []struct {
+		vwap     bool
+		notional float64
+	}{{false, 4 * 130}, {true, 4 * 117.5}} {
+		fake := newHyperliquidFake()
+		p := newTestHyperliquidProvider(fake, Config{PriceVWAP: tc.vwap})
+		out := make(chan Signal, 8)
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+
+		fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","time":1700000000000,"tid":1},
+			{"coin":"BTC","px":"110","sz":"1","time":1700000001000,"tid":2},
+			{"coin":"BTC","px":"130","sz":"2","time":1700000002000,"tid":3}]`)
+		fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+			{"position":{"coin":"BTC","szi":"4","leverage":{"type":"cross","value":5}}}]}`)
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 1 {
+			t.Fatalf("vwap=%v: expected one open, got %d", tc.vwap, len(out))
+		}
+		if sig := <-out; math.Abs(sig.NotionalUSD-tc.notional) > 1e-9 {
+			t.Fatalf("vwap=%v: expected notional %.2f, got %+v", tc.vwap, tc.notional, sig)
+		}
+	}
+}
+
+func TestHyperliquidProtectiveOrders(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("frontendOpenOrders", `[]`)
+
+	protective := make(chan ProtectiveOrder, 8)
+	p := newTestHyperliquidProvider(fake, Config{IncludeProtectiveOrders: true, ProtectiveOrders: protective})
+	out := make(chan Signal, 8)
+
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	fake.set("frontendOpenOrders", `[
+		{"coin":"ETH","side":"B","limitPx":"3000","sz":"2","oid":2,"timestamp":1700000001000},
+		{"coin":"ETH","side":"A","limitPx":"2500","sz":"2","oid":3,"timestamp":1700000001000,"isTrigger":true,"triggerPx":"2500","orderType":"Stop Market","reduceOnly":true}
+	]`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(protective) != 1 {
+		t.Fatalf("expected only the stop order, got %d", len(protective))
+	}
+	ev := <-protective
+	if ev.Symbol != "ETHUSDT" || ev.Kind != ProtectiveStopLoss || ev.TriggerPrice != 2500 ||
+		ev.Side != "sell" || ev.PositionSide != "long" || ev.Status != PendingOrderOpen || ev.OrderID != "3" {
+		t.Fatalf("unexpected protective order: %+v", ev)
+	}
+
+	// the leader trails the stop up
+	fake.set("frontendOpenOrders", `[{"coin":"ETH","side":"A","limitPx":"2700","sz":"2","oid":3,"timestamp":1700000001000,"isTrigger":true,"triggerPx":"2700","orderType":"Stop Market","reduceOnly":true}]`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	ev = <-protective
+	if ev.Status != PendingOrderUpdated || ev.TriggerPrice != 2700 {
+		t.Fatalf("expected trailed stop update, got %+v", ev)
+	}
+	if len(out) != 0 {
+		t.Fatalf("protective orders must not produce position signals")
+	}
+}
+
+func TestHyperliquidAvailableEquityBasis(t *testing.T) {
+	raw := hyperliquidStateRaw{Withdrawable: "400"}
+	raw.MarginSummary.AccountValue = "1000"
+	state, err := raw.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.equity(EquityTotal) != 1000 || state.equity(EquityAvailable) != 400 {
+		t.Fatalf("unexpected equity: total=%v available=%v", state.equity(EquityTotal), state.equity(EquityAvailable))
+	}
+}
+
+func TestHyperliquidClosesSurviveFillsOutage(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("userFills", `[{"coin":"ETH","px":"10","sz":"1","time":1700000000000,"tid":1}]`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"ETH","szi":"1","leverage":{"type":"cross","value":5}}}]}`)
+	p := newTestHyperliquidProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("userFills", `not json`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[]}`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("a fills outage must not abort the cycle: %v", err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("expected the close to be emitted, got %d signals", len(out))
+	}
+	if sig := <-out; sig.Action != ActionCloseLong || sig.Symbol != "ETHUSDT" {
+		t.Fatalf("unexpected signal: %+v", sig)
+	}
+}
+
+func TestHyperliquidLiquidationFillMarksClose(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("userFills", `[{"coin":"ETH","px":"10","sz":"1","time":1700000000000,"tid":1}]`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"ETH","szi":"1","leverage":{"type":"cross","value":5}}}]}`)
+	p := newTestHyperliquidProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("userFills", `[{"coin":"ETH","px":"8","sz":"1","time":1700000001000,"tid":2,"liquidation":{"markPx":"8","method":"market"}}]`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"800"},"assetPositions":[]}`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if sig := <-out; sig.Action != ActionCloseLong || !sig.Liquidation || sig.Urgency != UrgencyHigh {
+		t.Fatalf("expected a high-urgency liquidation close, got %+v", sig)
+	}
+}
+
+func TestHyperliquidClosesCarryLeaderClosePriceAndPnL(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","side":"B","startPosition":"0","time":1700000000000,"tid":1}]`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"BTC","szi":"1","leverage":{"type":"cross","value":5}}}]}`)
+	p := newTestHyperliquidProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	// the leader flips: closes the long at 110, then opens a short at 112
+	fake.set("userFills", `[
+		{"coin":"BTC","px":"110","sz":"1","side":"A","startPosition":"1","closedPnl":"10","time":1700000060000,"tid":2},
+		{"coin":"BTC","px":"112","sz":"1","side":"A","startPosition":"0","time":1700000061000,"tid":3}]`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1010"},"assetPositions":[
+		{"position":{"coin":"BTC","szi":"-1","leverage":{"type":"cross","value":5}}}]}`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected close and open, got %d signals", len(out))
+	}
+	closeSig, openSig := <-out, <-out
+	if closeSig.Action != ActionCloseLong || closeSig.Price != 110 || closeSig.RealizedPnLUSD != 10 || closeSig.NotionalUSD != 110 {
+		t.Fatalf("close must be priced at the close fill with the leader's PnL: %+v", closeSig)
+	}
+	if openSig.Action != ActionOpenShort || openSig.Price != 112 || openSig.RealizedPnLUSD != 0 {
+		t.Fatalf("unexpected open leg: %+v", openSig)
+	}
+}
+
+func TestHyperliquidOpensCarryLeaderLiquidationPrice(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","time":1700000000000,"tid":1}]`)
+	p := newTestHyperliquidProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"BTC","szi":"1","liquidationPx":"82.5","leverage":{"type":"cross","value":5}}},
+		{"position":{"coin":"ETH","szi":"1","liquidationPx":null,"leverage":{"type":"cross","value":5}}}]}`)
+	fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","time":1700000000000,"tid":1},
+		{"coin":"ETH","px":"10","sz":"1","time":1700000000000,"tid":2}]`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	liq := map[string]float64{}
+	for len(out) > 0 {
+		sig := <-out
+		liq[sig.Symbol] = sig.LeaderLiqPrice
+	}
+	if liq["BTCUSDT"] != 82.5 || liq["ETHUSDT"] != 0 {
+		t.Fatalf("unexpected liquidation prices: %v", liq)
+	}
+}
+
+func TestHyperliquidSignalsCarryLeaderFillTime(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	fake := newHyperliquidFake()
+	fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","time":1700000000000,"tid":1}]`)
+	p := newTestHyperliquidProvider(fake, Config{Clock: clock})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("userFills", `[{"coin":"BTC","px":"101","sz":"1","time":1700000060000,"tid":2}]`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"BTC","szi":"1","leverage":{"type":"cross","value":5}}}]}`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	sig := <-out
+	if !sig.Timestamp.Equal(time.UnixMilli(1700000060000)) || !sig.DetectedAt.Equal(clock.Now()) {
+		t.Fatalf("expected the fill time and the observation time, got %v / %v", sig.Timestamp, sig.DetectedAt)
+	}
+
+	// a change without a fill is stamped when it was observed
+	clock.Advance(time.Minute)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[]}`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if sig := <-out; !sig.Timestamp.Equal(clock.Now()) || !sig.DetectedAt.Equal(clock.Now()) {
+		t.Fatalf("expected an unfilled close stamped at detection, got %v / %v", sig.Timestamp, sig.DetectedAt)
+	}
+}
+
+func TestHyperliquidKeysBooksByCanonicalSymbol(t *testing.T) {
+	raw := hyperliquidStateRaw{}
+	if err := json.Unmarshal([]byte(`{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"purr","szi":"10","leverage":{"type":"cross","value":3}}},
+		{"position":{"coin":"FOOUSDT","szi":"-2","leverage":{"type":"cross","value":3}}}]}`), &raw); err != nil {
+		t.Fatal(err)
+	}
+	state, err := raw.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Positions) != 2 || state.Positions["PURRUSDT"].Size != 10 || state.Positions["FOOUSDT"].Size != -2 {
+		t.Fatalf("expected positions keyed PURRUSDT and FOOUSDT, got %v", state.Positions)
+	}
+
+	fake := newHyperliquidFake()
+	fake.set("userFills", `[{"coin":"PURR","px":"0.2","sz":"1","time":1700000000000,"tid":1}]`)
+	p := newTestHyperliquidProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	fake.set("userFills", `[{"coin":"PURR","px":"0.21","sz":"10","time":1700000060000,"tid":2},
+		{"coin":"FOOUSDT","px":"5","sz":"2","time":1700000060000,"tid":3}]`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"PURR","szi":"10","leverage":{"type":"cross","value":3}}},
+		{"position":{"coin":"FOOUSDT","szi":"-2","leverage":{"type":"cross","value":3}}}]}`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Signal{}
+	for len(out) > 0 {
+		sig := <-out
+		got[sig.Symbol] = sig
+	}
+	if sig := got["PURRUSDT"]; sig.Action != ActionOpenLong || sig.Price != 0.21 {
+		t.Fatalf("expected PURRUSDT opened at its fill, got %+v", got)
+	}
+	if sig := got["FOOUSDT"]; sig.Action != ActionOpenShort || sig.Price != 5 {
+		t.Fatalf("expected FOOUSDT opened at its fill without a doubled suffix, got %+v", got)
+	}
+	if _, ok := p.positions()["PURRUSDT"]; !ok || len(p.positions()) != 2 {
+		t.Fatalf("expected the book keyed by canonical symbol, got %v", p.positions())
+	}
+}
+
+func TestHyperliquidStateReadableWhileEmitting(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"ETH","szi":"1","leverage":{"type":"cross","value":5}}}]}`)
+	p := newTestHyperliquidProvider(fake, Config{})
+	if err := p.fetchAndEmit(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("userFills", `[{"coin":"ETH","px":"12","sz":"1","time":1700000000000,"tid":3}]`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"ETH","szi":"2","leverage":{"type":"cross","value":5}}}]}`)
+	out := make(chan Signal)
+	checkReadsWhileBlocked(t, out, func() error { return p.fetchAndEmit(context.Background(), out) }, func() bool {
+		p.saveState()
+		p.Stats()
+		eth := p.Snapshot()["ETHUSDT"]
+		return p.Initialized() && p.Cursor() == 3 && eth.Size == 2 && eth.Price == 12
+	})
+}
+
+func TestValidateHyperliquidAddress(t *testing.T) {
+	const checksummed = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed" // EIP-55 test vector
+	lower := strings.ToLower(checksummed)
+	cases := []struct {
+		addr          string
+		loose, strict bool
+	}{
+		{checksummed, true, true},
+		{lower, true, false},
+		{"0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed", true, false}, // one letter's case flipped
+		{lower[:41], false, false},
+		{lower + "0", false, false},
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaeg", false, false},
+		{"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed00", false, false},
+		{"", false, false},
+	}
+	for _, c := range cases {
+		if err := ValidateHyperliquidAddress(c.addr, false); (err == nil) != c.loose {
+			t.Errorf("%q: expected valid=%v, got %v", c.addr, c.loose, err)
+		}
+		if err := ValidateHyperliquidAddress(c.addr, true); (err == nil) != c.strict {
+			t.Errorf("%q strict: expected valid=%v, got %v", c.addr, c.strict, err)
+		}
+	}
+
+	p := newHyperliquidProvider(Config{Identifier: lower, StrictAddress: true})
+	if err := p.Run(context.Background(), nil); err == nil || !strings.Contains(err.Error(), checksummed) {
+		t.Fatalf("expected Run to reject the unchecksummed address and suggest %s, got %v", checksummed, err)
+	}
+}
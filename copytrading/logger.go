@@ -0,0 +1,29 @@
+package copytrading
+
+import "log"
+
+// Logger receives the package's diagnostics. Config.Logger plugs in a structured
+// logger; the default writes Info and above through the standard log package, as
+// the providers always have, and drops Debug.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// stdLogger is the default Logger.
+type stdLogger struct{}
+
+func (stdLogger) Debugf(string, ...interface{})             {}
+func (stdLogger) Infof(format string, args ...interface{})  { log.Printf(format, args...) }
+func (stdLogger) Warnf(format string, args ...interface{})  { log.Printf(format, args...) }
+func (stdLogger) Errorf(format string, args ...interface{}) { log.Printf(format, args...) }
+
+// loggerOf returns l, or the standard logger when l is nil.
+func loggerOf(l Logger) Logger {
+	if l == nil {
+		return stdLogger{}
+	}
+	return l
+}
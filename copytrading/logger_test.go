@@ -0,0 +1,116 @@
+package copytrading
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger keeps every line with its level.
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) record(level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.record("debug", format, args...)
+}
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.record("info", format, args...)
+}
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.record("warn", format, args...)
+}
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.record("error", format, args...)
+}
+
+func (l *recordingLogger) count(level string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, line := range l.lines {
+		if strings.HasPrefix(line, level+" ") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLoggerReceivesProviderDiagnostics(t *testing.T) {
+	logger := &recordingLogger{}
+	fake := newBybitFake()
+	p := newTestBybitProvider(fake, Config{Logger: logger})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	fake.set("list", bybitPositions(
+		`{"symbol":"BTCUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"100"}`,
+		`{"symbol":"ETHUSDT","side":"Sell","sizeX":"500000000","leverageE2":"300","entryPrice":"10"}`,
+	))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || logger.count("debug") != 2 {
+		t.Fatalf("expected a Debug line per emitted signal, got %d signals and %q", len(out), logger.lines)
+	}
+
+	p.errs.report(p.stateKey(), errors.New("boom"))
+	if logger.count("error") != 1 {
+		t.Fatalf("expected the poll error logged at Error, got %q", logger.lines)
+	}
+
+	// a nil Logger falls back to the standard log package
+	loggerOf(nil).Debugf("dropped")
+	if _, ok := loggerOf(nil).(stdLogger); !ok {
+		t.Fatal("expected the standard logger when none is configured")
+	}
+}
+
+func TestMalformedNumbersAndChildErrorsUseTheConfiguredLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	if _, ok := parseOKXFloat(logger, "pos", "1,5", "BTC-USDT-SWAP"); ok {
+		t.Fatal("expected a malformed OKX number rejected")
+	}
+	if _, ok := parseBybitE8(logger, "12abc"); ok {
+		t.Fatal("expected a malformed Bybit number rejected")
+	}
+
+	var trade okxTradeRecord
+	if err := json.Unmarshal([]byte(`{"fillTime":"soon"}`), &trade); err != nil {
+		t.Fatal(err)
+	}
+	okx := newTestOKXProvider(newOKXFake(), Config{Logger: logger})
+	if err := okx.apply(context.Background(), []okxTradeRecord{trade}, nil, 1000, make(chan Signal, 8)); err != nil {
+		t.Fatal(err)
+	}
+
+	var amount binanceFloat
+	if err := json.Unmarshal([]byte(`"1e"`), &amount); err != nil {
+		t.Fatal(err)
+	}
+	binance := newTestBinanceProvider(newBinanceFake(), Config{Logger: logger})
+	if got := binance.number("positionAmount", amount); got != 0 {
+		t.Fatalf("expected a malformed Binance number decoded as 0, got %v", got)
+	}
+
+	composite := NewComposite([]Provider{failingChild{errors.New("leader gone")}}, CompositeConfig{Logger: logger})
+	if err := composite.Run(context.Background(), make(chan Signal, 8)); err != nil {
+		t.Fatal(err)
+	}
+
+	if logger.count("warn") != 5 {
+		t.Fatalf("expected every diagnostic routed to the configured logger, got %q", logger.lines)
+	}
+}
@@ -0,0 +1,36 @@
+package copytrading
+
+import "time"
+
+// Metrics receives the providers' instrumentation: one IncSignal per emitted
+// signal, one IncDropped per signal dropped by a full Config.BufferSize buffer, and
+// one ObserveFetch per upstream fetch with its latency and outcome.
+// Endpoints are named "<venue>/<fetch>", e.g. "okx/equity", with "<venue>/poll"
+// covering a whole poll. The prometheus subpackage provides a scrapeable
+// implementation.
+type Metrics interface {
+	IncSignal(action, symbol string)
+	IncDropped(action, symbol string)
+	ObserveFetch(endpoint string, d time.Duration, err error)
+}
+
+// noopMetrics is the default Metrics.
+type noopMetrics struct{}
+
+func (noopMetrics) IncSignal(string, string)                  {}
+func (noopMetrics) IncDropped(string, string)                 {}
+func (noopMetrics) ObserveFetch(string, time.Duration, error) {}
+
+// metricsOf returns m, or a no-op when m is nil.
+func metricsOf(m Metrics) Metrics {
+	if m == nil {
+		return noopMetrics{}
+	}
+	return m
+}
+
+// observeFetch reports a fetch begun at start. Deferred with a pointer to the
+// fetch's named error, it sees the outcome after the return.
+func observeFetch(m Metrics, endpoint string, start time.Time, err *error) {
+	m.ObserveFetch(endpoint, time.Since(start), *err)
+}
@@ -0,0 +1,70 @@
+package copytrading
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics keeps every observation.
+type recordingMetrics struct {
+	mu      sync.Mutex
+	signals []string
+	dropped []string
+	fetches map[string]int
+	errors  map[string]int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{fetches: map[string]int{}, errors: map[string]int{}}
+}
+
+func (m *recordingMetrics) IncSignal(action, symbol string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.signals = append(m.signals, action+" "+symbol)
+}
+
+func (m *recordingMetrics) IncDropped(action, symbol string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped = append(m.dropped, action+" "+symbol)
+}
+
+func (m *recordingMetrics) ObserveFetch(endpoint string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetches[endpoint]++
+	if err != nil {
+		m.errors[endpoint]++
+	}
+}
+
+func TestProvidersReportMetrics(t *testing.T) {
+	metrics := newRecordingMetrics()
+	fake := newBybitFake()
+	p := newTestBybitProvider(fake, Config{Metrics: metrics})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	fake.set("list", bybitPositions(`{"symbol":"BTCUSDT","side":"Buy","sizeX":"100000000","leverageE2":"500","entryPrice":"100"}`))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics.signals) != 1 || metrics.signals[0] != "open_long BTCUSDT" {
+		t.Fatalf("expected the emitted open counted, got %q", metrics.signals)
+	}
+	if metrics.fetches["bybit/poll"] != 2 || metrics.fetches["bybit/equity"] != 2 || metrics.fetches["bybit/positions"] != 2 {
+		t.Fatalf("expected every fetch observed, got %v", metrics.fetches)
+	}
+
+	fake.set("leader-income", `{"retCode":10001,"retMsg":"leader not found"}`)
+	if err := p.fetchAndEmit(context.Background(), out); err == nil {
+		t.Fatal("expected the failed envelope surfaced")
+	}
+	if metrics.errors["bybit/equity"] != 1 || metrics.errors["bybit/poll"] != 1 || metrics.errors["bybit/positions"] != 0 {
+		t.Fatalf("expected the failure counted against equity and the poll, got %v", metrics.errors)
+	}
+}
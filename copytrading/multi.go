@@ -0,0 +1,92 @@
+package copytrading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MultiProvider follows a basket of leaders with a single Run: it runs one provider
+// per leader and fans their signals into one out channel, each tagged with
+// Signal.LeaderID. Run returns once every child has returned, joining their errors;
+// a failing child does not stop the others. Use RunUntil to drive it from a stop
+// channel.
+type MultiProvider struct {
+	children []Provider
+	ids      []string
+}
+
+// NewMultiProvider builds a provider for each config. Leaders are identified as
+// "venue:identifier" (e.g. "okx:abc", Hyperliquid addresses lowercased), which must
+// be unique within the basket.
+func NewMultiProvider(cfgs []Config) (*MultiProvider, error) {
+	m := &MultiProvider{}
+	seen := make(map[string]bool, len(cfgs))
+	for _, cfg := range cfgs {
+		id := leaderID(cfg)
+		if seen[id] {
+			return nil, fmt.Errorf("%w: leader %s listed twice", ErrInvalidConfig, id)
+		}
+		seen[id] = true
+		p, err := NewProvider(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("leader %s: %w", id, err)
+		}
+		m.children = append(m.children, p)
+		m.ids = append(m.ids, id)
+	}
+	return m, nil
+}
+
+// leaderID names the leader behind cfg for Signal.LeaderID.
+func leaderID(cfg Config) string {
+	return leaderKey(strings.TrimSuffix(cfg.Type, "_wallet"), strings.TrimSpace(cfg.Identifier))
+}
+
+func (m *MultiProvider) Run(ctx context.Context, out chan<- Signal) error {
+	if len(m.children) == 0 {
+		return errors.New("multi provider requires at least one leader")
+	}
+
+	errs := make([]error, len(m.children))
+	var wg sync.WaitGroup
+	for i, child := range m.children {
+		childOut := make(chan Signal)
+		wg.Add(2)
+		go func(i int, child Provider) {
+			defer wg.Done()
+			defer close(childOut)
+			if err := child.Run(ctx, childOut); err != nil {
+				errs[i] = fmt.Errorf("leader %s: %w", m.ids[i], err)
+			}
+		}(i, child)
+		go func(id string) {
+			defer wg.Done()
+			for sig := range childOut {
+				sig.LeaderID = id
+				if out == nil {
+					continue
+				}
+				select {
+				case out <- sig:
+				case <-ctx.Done():
+					// keep draining so the child is never stuck on its send
+				}
+			}
+		}(m.ids[i])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Stop stops every child that supports it (see Stopper). Children that do not keep
+// running, and so keep Run from returning, until its ctx is done.
+func (m *MultiProvider) Stop() {
+	for _, child := range m.children {
+		if stopper, ok := child.(Stopper); ok {
+			stopper.Stop()
+		}
+	}
+}
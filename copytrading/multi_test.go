@@ -0,0 +1,72 @@
+package copytrading
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// failingChild returns err from Run at once.
+type failingChild struct{ err error }
+
+func (c failingChild) Run(context.Context, chan<- Signal) error { return c.err }
+
+func TestMultiProviderTagsAndJoinsLeaders(t *testing.T) {
+	a, b := newScriptedChild(nil), newScriptedChild(nil)
+	errDown := errors.New("leader down")
+	m, err := NewMultiProvider([]Config{
+		{Type: "fake", Identifier: "a", Fake: a},
+		{Type: "fake", Identifier: "b", Fake: b},
+		{Type: "fake", Identifier: "c", Fake: failingChild{errDown}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make(chan Signal)
+	stopCh := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- RunUntil(m, stopCh, out) }()
+
+	// the leaders' signals interleave on the one channel, each tagged with its leader
+	var got []string
+	for i, child := range []*scriptedChild{a, b, a, b} {
+		child.signals <- Signal{Symbol: "BTCUSDT", Action: ActionOpenLong, DedupKey: string(rune('0' + i))}
+		sig := <-out
+		got = append(got, sig.LeaderID+"/"+sig.DedupKey)
+	}
+	if strings.Join(got, ",") != "fake:a/0,fake:b/1,fake:a/2,fake:b/3" {
+		t.Fatalf("expected the signals tagged with their leaders in order, got %v", got)
+	}
+
+	// the failed leader does not stop the others; its error is reported once all return
+	close(stopCh)
+	if err := <-done; !errors.Is(err, errDown) || !strings.Contains(err.Error(), "fake:c") {
+		t.Fatalf("expected the failed leader's error joined, got %v", err)
+	}
+}
+
+func TestNewMultiProviderRejectsBadBaskets(t *testing.T) {
+	child := newScriptedChild(nil)
+	if _, err := NewMultiProvider([]Config{
+		{Type: "fake", Identifier: "a", Fake: child},
+		{Type: "fake", Identifier: " a ", Fake: child},
+	}); err == nil {
+		t.Fatal("expected the same leader listed twice rejected")
+	}
+	// the same Hyperliquid wallet in a different letter case is the same leader
+	if _, err := NewMultiProvider([]Config{
+		{Type: "hyperliquid", Identifier: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{Type: "hyperliquid_wallet", Identifier: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+	}); !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "listed twice") {
+		t.Fatalf("expected a mixed-case duplicate wallet rejected, got %v", err)
+	}
+	if _, err := NewMultiProvider([]Config{{Type: "okx"}}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected an invalid child config rejected, got %v", err)
+	}
+	m, _ := NewMultiProvider(nil)
+	if err := m.Run(context.Background(), nil); err == nil {
+		t.Fatal("expected an empty basket rejected by Run")
+	}
+}
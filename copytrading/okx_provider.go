@@ -1,90 +1,438 @@
 package copytrading
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math"
 	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	"nofx/market"
 )
 
 type okxProvider struct {
+	mu sync.RWMutex // guards lastFillTime and tracker
+
 	uniqueName   string
-	pollInterval time.Duration
 	client       *http.Client
+	baseURL      string
 	lastFillTime int64
-	initialized  bool
-	lastPositions map[string]float64       // signed size
-	lastPrices    map[string]float64       // last fill price per symbol
+	tracker      *positionTracker
+	store        StateStore
+	maxBody      int64
+	stablecoin   stablecoinValuer
+	equity       string         // EquityBasis
+	shadow       *shadowMonitor // only touched by the poll loop
+	emitted      *emittedKeys   // only touched by the poll loop
+	poll         *adaptivePoll
+	backoff      *errorBackoff // only touched by the poll loop
+	errs         errorReporter
+	pause        *PauseController
+	stream       string // follow-mode variant, see streamVariant
+	onDuplicate  DuplicatePolicy
+	watch        *watchHandle     // set while Run is active
+	contracts    okxContractSpecs // only touched by the poll loop
+	cache        *SharedCache
+	margin       bool // also follow instType=MARGIN
+	clock        Clock
+	logger       Logger
+	metrics      Metrics
+	heartbeat    time.Duration
+	bus          *SignalBus
+	transport    string // TransportREST or TransportWS
+	wsURL        string
+
+	stop           *stopSignal // closed by Stop
+	closeAllOnStop bool
+	bufferSize     int
+	overflow       OverflowPolicy
 }
 
-func newOKXProvider(uniqueName string, pollInterval time.Duration, client *http.Client) Provider {
-	return &okxProvider{
-		uniqueName:   strings.TrimSpace(uniqueName),
-		pollInterval: pollInterval,
-		client:       client,
-		lastPositions: make(map[string]float64),
-		lastPrices:    make(map[string]float64),
+func newOKXProvider(cfg Config) Provider {
+	p := &okxProvider{
+		uniqueName:  strings.TrimSpace(cfg.Identifier),
+		client:      cfg.HTTPClient,
+		baseURL:     baseURLOf(cfg, okxBaseURL),
+		tracker:     newVenueTracker(cfg, "okx"),
+		cache:       sharedCacheOf(cfg),
+		margin:      cfg.OKXIncludeMargin,
+		store:       cfg.StateStore,
+		maxBody:     cfg.MaxResponseBytes,
+		stablecoin:  newStablecoinValuer(cfg),
+		equity:      cfg.EquityBasis,
+		shadow:      newShadowMonitor(cfg),
+		emitted:     newEmittedKeys(cfg),
+		poll:        newAdaptivePoll(cfg),
+		backoff:     newErrorBackoff(cfg),
+		errs:        newErrorReporter(cfg, "OKX"),
+		pause:       pauseOf(cfg),
+		stream:      streamVariant(cfg),
+		onDuplicate: cfg.OnDuplicate,
+		clock:       clockOf(cfg.Clock),
+		logger:      loggerOf(cfg.Logger),
+		metrics:     metricsOf(cfg.Metrics),
+		heartbeat:   cfg.Heartbeat,
+		bus:         cfg.Bus,
+		transport:   transportOf(cfg),
+		wsURL:       okxWSURL,
+
+		stop:           newStopSignal(),
+		closeAllOnStop: cfg.CloseAllOnStop,
+		bufferSize:     cfg.BufferSize,
+		overflow:       cfg.Overflow,
 	}
+	// market data knows the instrument, not the hedge leg
+	shared := p.tracker.marketPrice
+	p.tracker.marketPrice = func(key string) (float64, error) { return shared(okxSymbol(key)) }
+	return p
 }
 
-func (p *okxProvider) Run(stopCh <-chan struct{}, out chan<- Signal) error {
+func (p *okxProvider) Run(ctx context.Context, out chan<- Signal) error {
 	if p.uniqueName == "" {
 		return fmt.Errorf("okx provider requires uniqueName")
 	}
 
-	ticker := time.NewTicker(p.pollInterval)
-	defer ticker.Stop()
+	watch, err := defaultWatchRegistry.register(watchKey("okx", p.uniqueName, p.stream), p.onDuplicate)
+	if err != nil {
+		return err
+	}
+	p.watch = watch
+	defer watch.release()
+
+	out, stopHeartbeat := withHeartbeat(ctx, out, p.heartbeat, p.clock)
+	defer stopHeartbeat()
+	out, stopBuffer := withBuffer(ctx, out, p.bufferSize, p.overflow, p.metrics, p.logger)
+	defer stopBuffer()
+
+	p.loadState()
+	defer p.saveState()
+
+	if p.transport == TransportWS {
+		streamCtx, cancel := p.stop.context(ctx)
+		defer cancel()
+		p.runStream(streamCtx, out)
+		if ctx.Err() == nil && p.stop.stopped() {
+			p.closeAll(ctx, out)
+		}
+		return nil
+	}
 
 	for {
-		if err := p.fetchAndEmit(out); err != nil {
-			log.Printf("⚠️  OKX provider error: %v", err)
+		err := p.fetchAndEmit(ctx, out)
+		if err != nil {
+			p.errs.report(p.stateKey(), err)
+		} else {
+			// persist every poll so a crash loses at most one interval
+			p.saveState()
 		}
+		p.backoff.observe(err)
 
+		timer := time.NewTimer(p.backoff.wait(p.poll.interval()))
 		select {
-		case <-stopCh:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-p.stop.done():
+			timer.Stop()
+			p.closeAll(ctx, out)
 			return nil
-		case <-ticker.C:
+		case <-timer.C:
 		}
 	}
 }
 
-func (p *okxProvider) fetchAndEmit(out chan<- Signal) error {
-	trades, err := p.fetchTrades()
+// Stop asks Run to return after the poll in progress (see Stopper).
+func (p *okxProvider) Stop() {
+	p.stop.stop()
+}
+
+// closeAll emits a close for every mirrored position when Config.CloseAllOnStop is
+// set.
+func (p *okxProvider) closeAll(ctx context.Context, out chan<- Signal) {
+	if !p.closeAllOnStop {
+		return
+	}
+	p.mu.Lock()
+	signals := p.tracker.closeAll()
+	p.mu.Unlock()
+	p.emit(ctx, signals, out)
+}
+
+const (
+	okxBaseURL            = "https://www.okx.com"
+	okxWSURL              = "wss://ws.okx.com:8443/ws/v5/business"
+	okxWSPositionsChannel = "copytrading-public-lead-positions"
+	okxWSFillsChannel     = "copytrading-public-lead-fills"
+	// OKX drops connections idle for 30s; its keepalive is a bare "ping" text frame
+	okxWSPingInterval = 20 * time.Second
+)
+
+// okxWSMessage is a channel push (or a subscribe/error event).
+type okxWSMessage struct {
+	Event string `json:"event"`
+	Msg   string `json:"msg"`
+	Arg   struct {
+		Channel string `json:"channel"`
+	} `json:"arg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// runStream seeds the book over REST on every (re)connect, then applies pushed fills
+// and position updates incrementally through the same diff as polling. Position
+// pushes carry only the changed instruments; a zero size removes one.
+func (p *okxProvider) runStream(ctx context.Context, out chan<- Signal) {
+	var (
+		book    map[string]okxPositionMeta
+		equity  float64
+		pending []okxTradeRecord
+	)
+	resync := func() {
+		trades := p.fetchAllTrades(ctx)
+		accountValue, positions, err := p.fetchBook(ctx)
+		if err == nil {
+			err = p.apply(ctx, trades, positions, accountValue, out)
+		}
+		if err != nil {
+			p.logger.Warnf("⚠️  OKX resync failed, waiting for pushes: %v", err)
+			return
+		}
+		book, equity, pending = positions, accountValue, nil
+	}
+	args := []map[string]string{
+		{"channel": okxWSPositionsChannel, "uniqueName": p.uniqueName},
+		{"channel": okxWSFillsChannel, "uniqueName": p.uniqueName},
+	}
+	ws := &wsTransport{
+		logger:        p.logger,
+		name:          "OKX",
+		url:           p.wsURL,
+		subscriptions: []interface{}{map[string]interface{}{"op": "subscribe", "args": args}},
+		pingMessage:   []byte("ping"),
+		pingInterval:  okxWSPingInterval,
+		connected:     resync,
+		// handle runs on the transport's read goroutine, one message at a time
+		handle: func(msg []byte) {
+			if string(msg) == "pong" {
+				return
+			}
+			var push okxWSMessage
+			if err := json.Unmarshal(msg, &push); err != nil {
+				return
+			}
+			if push.Event == "error" {
+				p.logger.Warnf("⚠️  OKX websocket error: %s", push.Msg)
+				return
+			}
+			switch push.Arg.Channel {
+			case okxWSFillsChannel:
+				var trades []okxTradeRecord
+				if err := json.Unmarshal(push.Data, &trades); err != nil {
+					p.logger.Warnf("⚠️  OKX fills push undecodable: %v", err)
+					return
+				}
+				pending = append(pending, trades...)
+			case okxWSPositionsChannel:
+				var rows []okxPositionEntry
+				if err := json.Unmarshal(push.Data, &rows); err != nil {
+					p.logger.Warnf("⚠️  OKX positions push undecodable: %v", err)
+					return
+				}
+				if book == nil {
+					return // not seeded yet; the next resync picks the change up
+				}
+				for _, row := range rows {
+					instType := strings.ToUpper(row.InstType)
+					if instType == "" {
+						instType = "SWAP"
+					}
+					symbol, meta, ok := p.positionMeta(ctx, row, instType)
+					if !ok {
+						continue
+					}
+					if meta.SizeValid && meta.Size == 0 {
+						delete(book, symbol)
+						continue
+					}
+					book[symbol] = meta
+				}
+				if err := p.apply(ctx, pending, book, equity, out); err != nil {
+					p.errs.report(p.stateKey(), err)
+					return
+				}
+				pending = nil
+			}
+		},
+	}
+	ws.run(ctx)
+}
+
+func (p *okxProvider) stateKey() string {
+	return stateKey("okx", p.uniqueName)
+}
+
+// emit sends signals that pass deduplication to out and the bus. It gives up once
+// ctx is done, so a consumer that stopped reading cannot hang the provider.
+func (p *okxProvider) emit(ctx context.Context, signals []Signal, out chan<- Signal) {
+	for _, sig := range signals {
+		if !p.emitted.admit(sig.DedupKey) || !p.watch.admit(sig, p.clock.Now()) {
+			continue
+		}
+		sig.Symbol = okxSymbol(sig.Symbol)
+		p.logger.Debugf("📤 OKX %s %s notional=%.2f", sig.Symbol, sig.Action, sig.NotionalUSD)
+		p.metrics.IncSignal(string(sig.Action), sig.Symbol)
+		if out != nil {
+			select {
+			case out <- sig:
+			case <-ctx.Done():
+				return
+			}
+		}
+		p.bus.Publish(p.stateKey(), sig)
+		p.shadow.record(sig)
+	}
+}
+
+// Cursor returns the fill time (epoch ms) of the last processed trade.
+func (p *okxProvider) Cursor() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastFillTime
+}
+
+// Initialized reports whether the leader snapshot has been seeded.
+func (p *okxProvider) Initialized() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.tracker.initialized
+}
+
+// Ready is closed after the first successful fetch of the leader's book.
+func (p *okxProvider) Ready() <-chan struct{} {
+	return p.tracker.ready
+}
+
+// HealthCheck fetches the leader's equity once, so an unknown uniqueName or an unreachable
+// endpoint is reported before Run starts.
+func (p *okxProvider) HealthCheck(ctx context.Context) error {
+	if p.uniqueName == "" {
+		return fmt.Errorf("okx provider requires uniqueName")
+	}
+	if _, err := p.fetchEquity(ctx); err != nil {
+		return fmt.Errorf("okx leader %s: %w", p.uniqueName, err)
+	}
+	return nil
+}
+
+// positions returns a copy of the mirrored leader book, hedge legs netted per
+// symbol.
+func (p *okxProvider) positions() map[string]PositionMeta {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return netOKXLegs(p.tracker.lastPositions)
+}
+
+// Snapshot returns the leader's mirrored book with last known prices (see
+// SnapshotReporter).
+func (p *okxProvider) Snapshot() map[string]PositionMeta {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return netOKXLegs(p.tracker.snapshot())
+}
+
+func (p *okxProvider) loadState() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, ok := loadState(p.store, p.logger, p.stateKey()); ok {
+		p.lastFillTime = s.LastFillTime
+		p.tracker.restoreState(s)
+	}
+}
+
+func (p *okxProvider) saveState() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.store == nil || !p.tracker.initialized {
+		return
+	}
+	s := ProviderState{LastFillTime: p.lastFillTime, SavedAt: p.clock.Now()}
+	p.tracker.exportState(&s)
+	saveState(p.store, p.logger, p.stateKey(), s)
+}
+
+func (p *okxProvider) fetchAndEmit(ctx context.Context, out chan<- Signal) (err error) {
+	defer observeFetch(p.metrics, "okx/poll", time.Now(), &err)
+	trades := p.fetchAllTrades(ctx)
+	accountValue, positions, err := p.fetchBook(ctx)
 	if err != nil {
 		return err
 	}
+	return p.apply(ctx, trades, positions, accountValue, out)
+}
 
-	accountValue, err := p.fetchEquity()
+// fetchAllTrades returns the leader's recent fills. Fills only refine prices; the
+// position snapshot is authoritative, so a fills outage must not hold back the diff
+// (which may carry a close).
+func (p *okxProvider) fetchAllTrades(ctx context.Context) []okxTradeRecord {
+	trades, err := p.fetchTrades(ctx, "SWAP")
 	if err != nil {
-		return err
+		p.logger.Warnf("⚠️  OKX fills unavailable, diffing positions without them: %v", err)
+		trades = nil
+	}
+	if p.margin {
+		marginTrades, err := p.fetchTrades(ctx, "MARGIN")
+		if err != nil {
+			p.logger.Warnf("⚠️  OKX margin fills unavailable, diffing positions without them: %v", err)
+		}
+		trades = append(trades, marginTrades...)
+	}
+	return trades
+}
+
+// fetchBook returns the leader's equity and full position book.
+func (p *okxProvider) fetchBook(ctx context.Context) (float64, map[string]okxPositionMeta, error) {
+	accountValue, err := p.fetchEquity(ctx)
+	if err != nil {
+		return 0, nil, err
 	}
 	if accountValue <= 0 {
-		return fmt.Errorf("okx equity invalid")
+		return 0, nil, fmt.Errorf("%w: okx equity %g", ErrNoEquity, accountValue)
 	}
 
-	positions, err := p.fetchPositions()
+	positions, err := p.fetchPositions(ctx, "SWAP")
 	if err != nil {
-		return err
+		return 0, nil, err
+	}
+	if p.margin {
+		marginPositions, err := p.fetchPositions(ctx, "MARGIN")
+		if err != nil {
+			return 0, nil, err
+		}
+		positions = mergeOKXPositions(positions, marginPositions)
 	}
+	return accountValue, positions, nil
+}
 
+// apply folds new fills into prices, diffs the position book against the mirrored
+// one and emits the resulting signals. Polling and streaming share it.
+func (p *okxProvider) apply(ctx context.Context, trades []okxTradeRecord, positions map[string]okxPositionMeta, accountValue float64, out chan<- Signal) error {
 	sort.Slice(trades, func(i, j int) bool {
-		if trades[i].FillTime == trades[j].FillTime {
+		if trades[i].FillTime.ms == trades[j].FillTime.ms {
 			return trades[i].OrdID < trades[j].OrdID
 		}
-		return trades[i].FillTime < trades[j].FillTime
+		return trades[i].FillTime.ms < trades[j].FillTime.ms
 	})
 
+	p.mu.Lock()
 	maxFill := p.lastFillTime
 	for _, trade := range trades {
-		if trade.FillTime <= p.lastFillTime {
+		if trade.FillTime.malformed != "" {
+			p.logger.Warnf("⚠️  OKX unexpected number format field=fillTime value=%q ref=%s", trade.FillTime.malformed, trade.OrdID)
+		}
+		if trade.FillTime.ms <= p.lastFillTime {
 			continue
 		}
 
@@ -93,182 +441,85 @@ func (p *okxProvider) fetchAndEmit(out chan<- Signal) error {
 			continue
 		}
 
-		avgPx, _ := strconv.ParseFloat(trade.AvgPx, 64)
-		if avgPx > 0 {
-			p.lastPrices[symbol] = avgPx
+		side := strings.ToLower(trade.Side)
+		posSide := strings.ToLower(trade.PosSide)
+		for _, key := range okxFillKeys(symbol, posSide) {
+			if avgPx, ok := parseOKXFloat(p.logger, "avgPx", trade.AvgPx, trade.InstID); ok {
+				size, _ := parseOKXFloat(p.logger, "sz", trade.Size, trade.InstID)
+				p.tracker.recordFill(key, avgPx, size, time.UnixMilli(trade.FillTime.ms))
+			}
+			if side == "buy" || side == "sell" {
+				p.tracker.recordFillSide(key, side == "buy")
+			}
+			// OKX reports no per-fill PnL, but hedge-mode fills say which leg they close
+			if (posSide == "long" && side == "sell") || (posSide == "short" && side == "buy") {
+				if avgPx, ok := parseOKXFloat(p.logger, "avgPx", trade.AvgPx, trade.InstID); ok {
+					size, _ := parseOKXFloat(p.logger, "sz", trade.Size, trade.InstID)
+					p.tracker.recordCloseFill(key, avgPx, size, 0)
+				}
+			}
 		}
-		if trade.FillTime > maxFill {
-			maxFill = trade.FillTime
+		if trade.FillTime.ms > maxFill {
+			maxFill = trade.FillTime.ms
 		}
 	}
-	if maxFill > p.lastFillTime {
+	newFills := maxFill > p.lastFillTime
+	if newFills {
 		p.lastFillTime = maxFill
 	}
 
-	// initialize snapshot without emitting historical signals
-	if !p.initialized {
-		for sym, meta := range positions {
-			p.lastPositions[sym] = meta.Size
-		}
-		p.initialized = true
-		return nil
-	}
-
+	snapshot := make(map[string]PositionMeta, len(positions))
 	for sym, meta := range positions {
-		prev := p.lastPositions[sym]
-		delta := meta.Size - prev
-		if delta == 0 {
-			continue
-		}
-		price := p.lastPrices[sym]
-		if price <= 0 {
-			if md, err := market.Get(sym); err == nil && md.CurrentPrice > 0 {
-				price = md.CurrentPrice
-				p.lastPrices[sym] = price
-			}
-		}
-		if price <= 0 {
-			continue
-		}
-		// direction flip
-		if prev > 0 && meta.Size < 0 {
-			out <- Signal{
-				Symbol:         sym,
-				Action:         ActionCloseLong,
-				NotionalUSD:    math.Abs(prev) * price,
-				Price:          price,
-				LeaderEquity:   accountValue,
-				LeaderLeverage: meta.Leverage,
-				MarginMode:     meta.MarginMode,
-				Timestamp:      time.Now(),
-				DeltaSize:      -prev,
-				LeaderPosBefore: prev,
-				LeaderPosAfter:  0,
-			}
-			out <- Signal{
-				Symbol:         sym,
-				Action:         ActionOpenShort,
-				NotionalUSD:    math.Abs(meta.Size) * price,
-				Price:          price,
-				LeaderEquity:   accountValue,
-				LeaderLeverage: meta.Leverage,
-				MarginMode:     meta.MarginMode,
-				Timestamp:      time.Now(),
-				DeltaSize:      meta.Size,
-				LeaderPosBefore: 0,
-				LeaderPosAfter:  meta.Size,
+		prev, known := p.tracker.position(sym)
+		size, leverage := meta.Size, meta.Leverage
+		if !meta.SizeValid {
+			// an unparseable size must not look like a flat position (phantom close)
+			if !known {
+				continue
 			}
-			p.lastPositions[sym] = meta.Size
-			continue
+			size = prev.Size
 		}
-		if prev < 0 && meta.Size > 0 {
-			out <- Signal{
-				Symbol:         sym,
-				Action:         ActionCloseShort,
-				NotionalUSD:    math.Abs(prev) * price,
-				Price:          price,
-				LeaderEquity:   accountValue,
-				LeaderLeverage: meta.Leverage,
-				MarginMode:     meta.MarginMode,
-				Timestamp:      time.Now(),
-				DeltaSize:      -prev,
-				LeaderPosBefore: prev,
-				LeaderPosAfter:  0,
-			}
-			out <- Signal{
-				Symbol:         sym,
-				Action:         ActionOpenLong,
-				NotionalUSD:    math.Abs(meta.Size) * price,
-				Price:          price,
-				LeaderEquity:   accountValue,
-				LeaderLeverage: meta.Leverage,
-				MarginMode:     meta.MarginMode,
-				Timestamp:      time.Now(),
-				DeltaSize:      meta.Size,
-				LeaderPosBefore: 0,
-				LeaderPosAfter:  meta.Size,
+		if !meta.LeverageValid {
+			leverage = 1
+			if known && prev.Leverage > 0 {
+				leverage = prev.Leverage
 			}
-			p.lastPositions[sym] = meta.Size
-			continue
 		}
-
-		action := deriveActionFromDelta(prev, meta.Size)
-		if action == "" {
-			p.lastPositions[sym] = meta.Size
-			continue
+		snapshot[sym] = PositionMeta{
+			Size:       size,
+			Leverage:   leverage,
+			MarginMode: meta.MarginMode,
+			EntryPrice: meta.EntryPrice,
+			LiqPrice:   meta.LiqPrice,
 		}
-	out <- Signal{
-		Symbol:         sym,
-		Action:         action,
-		NotionalUSD:    math.Abs(delta) * price,
-		Price:          price,
-		LeaderEquity:   accountValue,
-		LeaderLeverage: meta.Leverage,
-		MarginMode:     meta.MarginMode,
-		Timestamp:      time.Now(),
-		DeltaSize:      delta,
-			LeaderPosBefore: prev,
-			LeaderPosAfter:  meta.Size,
-		}
-		p.lastPositions[sym] = meta.Size
 	}
 
-	// handle symbols that disappeared -> full close
-	for sym, prev := range p.lastPositions {
-		if _, ok := positions[sym]; ok {
-			continue
-		}
-		if prev == 0 {
-			delete(p.lastPositions, sym)
-			continue
-		}
-		price := p.lastPrices[sym]
-		if price <= 0 {
-			if md, err := market.Get(sym); err == nil && md.CurrentPrice > 0 {
-				price = md.CurrentPrice
-				p.lastPrices[sym] = price
-			}
-		}
-		if price <= 0 {
-			delete(p.lastPositions, sym)
-			continue
-		}
-		action := ActionCloseLong
-		if prev < 0 {
-			action = ActionCloseShort
-		}
-		out <- Signal{
-			Symbol:         sym,
-			Action:         action,
-			NotionalUSD:    math.Abs(prev) * price,
-			LeaderEquity:   accountValue,
-			LeaderLeverage: 0,
-			MarginMode:     "",
-			Timestamp:      time.Now(),
-			DeltaSize:      -prev,
-			LeaderPosBefore: prev,
-			LeaderPosAfter:  0,
-		}
-		delete(p.lastPositions, sym)
-	}
+	signals := p.tracker.update(snapshot, accountValue)
+	p.mu.Unlock()
+	p.poll.observe(newFills || len(signals) > 0)
+	signals = suppressWhilePaused(p.pause, p.logger, "OKX", signals)
 
+	p.emit(ctx, signals, out)
+	p.shadow.compare(netOKXLegs(snapshot))
 	return nil
 }
 
-func (p *okxProvider) fetchTrades() ([]okxTradeRecord, error) {
+func (p *okxProvider) fetchTrades(ctx context.Context, instType string) (_ []okxTradeRecord, err error) {
+	defer observeFetch(p.metrics, "okx/trades", time.Now(), &err)
 	params := url.Values{}
 	params.Set("uniqueName", p.uniqueName)
-	params.Set("instType", "SWAP")
+	params.Set("instType", instType)
 	params.Set("limit", "50")
-	params.Set("t", fmt.Sprintf("%d", time.Now().UnixMilli()))
-	endpoint := fmt.Sprintf("https://www.okx.com/priapi/v5/ecotrade/public/community/user/trade-records?%s", params.Encode())
+	params.Set("t", fmt.Sprintf("%d", p.clock.Now().UnixMilli()))
+	endpoint := fmt.Sprintf("%s/priapi/v5/ecotrade/public/community/user/trade-records?%s", p.baseURL, params.Encode())
 
-	req, err := http.NewRequest("GET", endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
+	acceptGzip(req)
 
-	resp, err := p.client.Do(req)
+	resp, err := doRequest(p.client, p.clock, req)
 	if err != nil {
 		return nil, err
 	}
@@ -279,25 +530,27 @@ func (p *okxProvider) fetchTrades() ([]okxTradeRecord, error) {
 	}
 
 	var result okxTradeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := decodeJSON(resp, p.maxBody, &result); err != nil {
 		return nil, err
 	}
 
 	return result.Data, nil
 }
 
-func (p *okxProvider) fetchEquity() (float64, error) {
+func (p *okxProvider) fetchEquity(ctx context.Context) (_ float64, err error) {
+	defer observeFetch(p.metrics, "okx/equity", time.Now(), &err)
 	params := url.Values{}
 	params.Set("uniqueName", p.uniqueName)
-	params.Set("t", fmt.Sprintf("%d", time.Now().UnixMilli()))
-	endpoint := fmt.Sprintf("https://www.okx.com/priapi/v5/ecotrade/public/community/user/asset?%s", params.Encode())
+	params.Set("t", fmt.Sprintf("%d", p.clock.Now().UnixMilli()))
+	endpoint := fmt.Sprintf("%s/priapi/v5/ecotrade/public/community/user/asset?%s", p.baseURL, params.Encode())
 
-	req, err := http.NewRequest("GET", endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return 0, err
 	}
+	acceptGzip(req)
 
-	resp, err := p.client.Do(req)
+	resp, err := doRequest(p.client, p.clock, req)
 	if err != nil {
 		return 0, err
 	}
@@ -308,32 +561,50 @@ func (p *okxProvider) fetchEquity() (float64, error) {
 	}
 
 	var result okxAssetResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := decodeJSON(resp, p.maxBody, &result); err != nil {
 		return 0, err
 	}
+	if result.Code != "" && result.Code != "0" {
+		return 0, fmt.Errorf("okx asset error: %s %s", result.Code, result.Msg)
+	}
+	// a lead trader always has asset rows, if only zero balances; none at all means
+	// the uniqueName is unknown
+	if len(result.Data) == 0 {
+		return 0, fmt.Errorf("%w: okx uniqueName %q", ErrLeaderNotFound, p.uniqueName)
+	}
 
 	for _, asset := range result.Data {
 		if strings.EqualFold(asset.Currency, "USDT") {
-			value, _ := strconv.ParseFloat(asset.Amount, 64)
-			return value, nil
+			if p.equity == EquityAvailable {
+				if avail, ok := asset.available(); ok {
+					return p.stablecoin.toUSD(avail), nil
+				}
+			}
+			value, ok := parseOKXFloat(p.logger, "amount", asset.Amount, asset.Currency)
+			if !ok {
+				return 0, fmt.Errorf("okx equity unparseable: %q", asset.Amount)
+			}
+			return p.stablecoin.toUSD(value), nil
 		}
 	}
 
-	return 0, fmt.Errorf("okx equity not found")
+	return 0, fmt.Errorf("%w: okx leader holds no USDT", ErrNoEquity)
 }
 
-func (p *okxProvider) fetchMarginModes() (map[string]string, error) {
+func (p *okxProvider) fetchMarginModes(ctx context.Context) (_ map[string]string, err error) {
+	defer observeFetch(p.metrics, "okx/margin_modes", time.Now(), &err)
 	params := url.Values{}
 	params.Set("uniqueName", p.uniqueName)
-	params.Set("t", fmt.Sprintf("%d", time.Now().UnixMilli()))
-	endpoint := fmt.Sprintf("https://www.okx.com/priapi/v5/ecotrade/public/community/user/position-current?%s", params.Encode())
+	params.Set("t", fmt.Sprintf("%d", p.clock.Now().UnixMilli()))
+	endpoint := fmt.Sprintf("%s/priapi/v5/ecotrade/public/community/user/position-current?%s", p.baseURL, params.Encode())
 
-	req, err := http.NewRequest("GET", endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
+	acceptGzip(req)
 
-	resp, err := p.client.Do(req)
+	resp, err := doRequest(p.client, p.clock, req)
 	if err != nil {
 		return nil, err
 	}
@@ -344,35 +615,116 @@ func (p *okxProvider) fetchMarginModes() (map[string]string, error) {
 	}
 
 	var result okxPositionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := decodeJSON(resp, p.maxBody, &result); err != nil {
 		return nil, err
 	}
 
 	positions := make(map[string]string)
 	for _, entry := range result.Data {
 		for _, pos := range entry.PosData {
-			positions[pos.InstID] = strings.ToLower(pos.MarginMode)
+			positions[pos.InstID] = normalizeMarginMode(pos.MarginMode)
 		}
 	}
 	return positions, nil
 }
 
+// okxContractSpecs caches the coin amount of one contract per instrument. It is only
+// touched by the poll loop.
+type okxContractSpecs struct {
+	values   map[string]float64
+	loadedAt time.Time
+}
+
+// okxSpecsRefresh bounds how often an unknown instrument triggers a reload.
+const okxSpecsRefresh = 10 * time.Minute
+
+// contractValue returns ctVal*ctMult for instID, loading the instrument list on first
+// use and again when a new instrument appears.
+func (p *okxProvider) contractValue(ctx context.Context, instID string) (float64, error) {
+	instID = strings.ToUpper(strings.TrimSpace(instID))
+	if value, ok := p.contracts.values[instID]; ok {
+		return value, nil
+	}
+	if p.contracts.values == nil || p.clock.Now().Sub(p.contracts.loadedAt) >= okxSpecsRefresh {
+		// the instrument list is public, so providers of every leader share it
+		values, err := p.cache.get("okx", "instruments:SWAP", func() (any, error) { return p.fetchContractSpecs(ctx) })
+		if err != nil {
+			return 0, err
+		}
+		p.contracts = okxContractSpecs{values: values.(map[string]float64), loadedAt: p.clock.Now()}
+	}
+	if value, ok := p.contracts.values[instID]; ok {
+		return value, nil
+	}
+	return 0, fmt.Errorf("unknown instrument %s", instID)
+}
+
+func (p *okxProvider) fetchContractSpecs(ctx context.Context) (_ map[string]float64, err error) {
+	defer observeFetch(p.metrics, "okx/contract_specs", time.Now(), &err)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/v5/public/instruments?instType=SWAP", nil)
+	if err != nil {
+		return nil, err
+	}
+	acceptGzip(req)
+
+	resp, err := doRequest(p.client, p.clock, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("okx instruments error: %s", resp.Status)
+	}
+
+	var result okxInstrumentResponse
+	if err := decodeJSON(resp, p.maxBody, &result); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]float64, len(result.Data))
+	for _, inst := range result.Data {
+		ctVal, ok := parseOKXFloat(p.logger, "ctVal", inst.CtVal, inst.InstID)
+		if !ok || ctVal <= 0 {
+			continue
+		}
+		ctMult, ok := parseOKXFloat(p.logger, "ctMult", inst.CtMult, inst.InstID)
+		if !ok || ctMult <= 0 {
+			ctMult = 1
+		}
+		values[strings.ToUpper(inst.InstID)] = ctVal * ctMult
+	}
+	return values, nil
+}
+
+type okxInstrumentResponse struct {
+	Code string          `json:"code"`
+	Data []okxInstrument `json:"data"`
+	Msg  string          `json:"msg"`
+}
+
+type okxInstrument struct {
+	InstID string `json:"instId"`
+	CtVal  string `json:"ctVal"`
+	CtMult string `json:"ctMult"`
+}
+
 type okxTradeResponse struct {
-	Code string            `json:"code"`
-	Data []okxTradeRecord  `json:"data"`
-	Msg  string            `json:"msg"`
+	Code string           `json:"code"`
+	Data []okxTradeRecord `json:"data"`
+	Msg  string           `json:"msg"`
 }
 
 type okxTradeRecord struct {
-	InstID   string `json:"instId"`
-	Side     string `json:"side"`
-	PosSide  string `json:"posSide"`
-	AvgPx    string `json:"avgPx"`
-	Size     string `json:"sz"`
-	Value    string `json:"value"`
-	FillTime int64  `json:"fillTime,string"`
-	OrdID    string `json:"ordId"`
-	Lever    string `json:"lever"`
+	InstID   string    `json:"instId"`
+	Side     string    `json:"side"`
+	PosSide  string    `json:"posSide"`
+	AvgPx    string    `json:"avgPx"`
+	Size     string    `json:"sz"`
+	Value    string    `json:"value"`
+	FillTime okxMillis `json:"fillTime"`
+	OrdID    string    `json:"ordId"`
+	Lever    string    `json:"lever"`
 }
 
 type okxAssetResponse struct {
@@ -384,6 +736,19 @@ type okxAssetResponse struct {
 type okxAssetRow struct {
 	Currency string `json:"currency"`
 	Amount   string `json:"amount"`
+	// usable equity under portfolio/multi-currency margin, when exposed
+	AvailEq  string `json:"availEq"`
+	AvailBal string `json:"availBal"`
+}
+
+// available returns the usable equity, preferring availEq over availBal.
+func (r okxAssetRow) available() (float64, bool) {
+	for _, raw := range []string{r.AvailEq, r.AvailBal} {
+		if value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil && value > 0 {
+			return value, true
+		}
+	}
+	return 0, false
 }
 
 type okxPositionResponse struct {
@@ -398,10 +763,131 @@ type okxPositionParent struct {
 
 type okxPositionEntry struct {
 	InstID     string `json:"instId"`
+	InstType   string `json:"instType"`
 	MarginMode string `json:"mgnMode"`
 	PosSide    string `json:"posSide"`
 	Pos        string `json:"pos"`
 	Lever      string `json:"lever"`
+	AvgPx      string `json:"avgPx"`
+	LiqPx      string `json:"liqPx"`
+	// MARGIN only: the currency pos is held in, and the borrowed currency/amount
+	PosCcy  string `json:"posCcy"`
+	LiabCcy string `json:"liabCcy"`
+	Liab    string `json:"liab"`
+}
+
+// okxMarginPosition normalizes a spot-margin position into the signed-size model:
+// holding the base currency is long, owing it (a borrow-driven short) is short.
+// Spot sizes are already in coins.
+func okxMarginPosition(logger Logger, pos okxPositionEntry) okxPositionMeta {
+	base := strings.ToUpper(strings.SplitN(pos.InstID, "-", 2)[0])
+	size, sizeOK := parseOKXFloat(logger, "pos", pos.Pos, pos.InstID)
+	size = math.Abs(size)
+	entry, _ := strconv.ParseFloat(pos.AvgPx, 64)
+	short := strings.EqualFold(pos.PosSide, "short")
+	switch {
+	case strings.EqualFold(pos.LiabCcy, base):
+		short = true
+		if liab, ok := parseOKXFloat(logger, "liab", pos.Liab, pos.InstID); ok {
+			size, sizeOK = math.Abs(liab), true
+		}
+	case pos.PosCcy != "" && !strings.EqualFold(pos.PosCcy, base):
+		// pos is held in the quote currency: the proceeds of a short sale
+		short = true
+		if entry > 0 {
+			size = size / entry
+		} else {
+			sizeOK = false
+		}
+	}
+	if short {
+		size = -size
+	}
+	lever, leverOK := parseOKXFloat(logger, "lever", pos.Lever, pos.InstID)
+	if leverOK && lever <= 0 {
+		lever = 1
+	}
+	liq, _ := strconv.ParseFloat(pos.LiqPx, 64)
+	return okxPositionMeta{
+		Size:          size,
+		EntryPrice:    entry,
+		LiqPrice:      liq,
+		Leverage:      int(lever),
+		MarginMode:    normalizeMarginMode(pos.MarginMode),
+		SizeValid:     sizeOK,
+		LeverageValid: leverOK,
+	}
+}
+
+// mergeOKXPositions adds margin positions to the perp book; a symbol held on both
+// nets into one signed size and keeps the perp's leverage and margin mode.
+func mergeOKXPositions(swap, margin map[string]okxPositionMeta) map[string]okxPositionMeta {
+	for sym, meta := range margin {
+		existing, ok := swap[sym]
+		if !ok {
+			swap[sym] = meta
+			continue
+		}
+		existing.Size += meta.Size
+		existing.SizeValid = existing.SizeValid && meta.SizeValid
+		swap[sym] = existing
+	}
+	return swap
+}
+
+// okxShortLegSuffix keys a hedge-mode short leg apart from a long leg on the same
+// instrument, so the tracker mirrors both legs independently. Emitted signals carry
+// the plain symbol.
+const okxShortLegSuffix = ":short"
+
+// okxPositionKey is the book key of a position row: its symbol, or the short leg's
+// key for a hedge-mode short.
+func okxPositionKey(symbol, posSide string) string {
+	if strings.EqualFold(posSide, "short") {
+		return symbol + okxShortLegSuffix
+	}
+	return symbol
+}
+
+// okxFillKeys lists the book keys a fill prices: its own leg, or both legs of the
+// instrument when the fill names none.
+func okxFillKeys(symbol, posSide string) []string {
+	if posSide == "" {
+		return []string{symbol, symbol + okxShortLegSuffix}
+	}
+	return []string{okxPositionKey(symbol, posSide)}
+}
+
+// okxSymbol strips the hedge leg from a book key.
+func okxSymbol(key string) string {
+	return strings.TrimSuffix(key, okxShortLegSuffix)
+}
+
+// netOKXLegs nets hedge legs into one signed size per symbol.
+func netOKXLegs(book map[string]PositionMeta) map[string]PositionMeta {
+	netted := make(map[string]PositionMeta, len(book))
+	for key, meta := range book {
+		sym := okxSymbol(key)
+		if existing, ok := netted[sym]; ok {
+			existing.Size += meta.Size
+			meta = existing
+		}
+		netted[sym] = meta
+	}
+	return netted
+}
+
+// signOKXSize signs a position size by its side. Long/short (hedge mode) rows report
+// an unsigned pos; one-way ("net") mode carries the direction in pos's own sign.
+func signOKXSize(posSide string, size float64) float64 {
+	switch strings.ToLower(posSide) {
+	case "long":
+		return math.Abs(size)
+	case "short":
+		return -math.Abs(size)
+	default: // "net"
+		return size
+	}
 }
 
 func mapOKXAction(posSide, side string) SignalAction {
@@ -437,20 +923,70 @@ type okxPositionMeta struct {
 	Size       float64
 	Leverage   int
 	MarginMode string
+	EntryPrice float64
+	LiqPrice   float64
+	// OKX sends "" or "-" for fields it doesn't have; these flags tell an absent
+	// value apart from a real zero.
+	SizeValid     bool
+	LeverageValid bool
 }
 
-func (p *okxProvider) fetchPositions() (map[string]okxPositionMeta, error) {
+// okxMillis is an epoch-millis timestamp that OKX may send as a quoted string,
+// a bare number, or an empty string. A malformed value decodes as 0 and keeps its
+// raw text in malformed so the provider can report it through its Logger.
+type okxMillis struct {
+	ms        int64
+	malformed string
+}
+
+func (m *okxMillis) UnmarshalJSON(data []byte) error {
+	*m = okxMillis{}
+	raw := strings.Trim(strings.TrimSpace(string(data)), `"`)
+	if raw == "" || raw == "null" || raw == "-" {
+		return nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		m.malformed = raw
+		return nil
+	}
+	m.ms = value
+	return nil
+}
+
+// parseOKXFloat parses an OKX numeric string, reporting ok=false for empty,
+// placeholder ("-") or malformed values instead of silently returning 0. Malformed
+// values are logged to logger.
+func parseOKXFloat(logger Logger, field, raw, ref string) (float64, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "-" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		logger.Warnf("⚠️  OKX unexpected number format field=%s value=%q ref=%s", field, raw, ref)
+		return 0, false
+	}
+	return value, true
+}
+
+func (p *okxProvider) fetchPositions(ctx context.Context, instType string) (_ map[string]okxPositionMeta, err error) {
+	defer observeFetch(p.metrics, "okx/positions", time.Now(), &err)
 	params := url.Values{}
 	params.Set("uniqueName", p.uniqueName)
-	params.Set("t", fmt.Sprintf("%d", time.Now().UnixMilli()))
-	endpoint := fmt.Sprintf("https://www.okx.com/priapi/v5/ecotrade/public/community/user/position-current?%s", params.Encode())
+	if instType != "SWAP" {
+		params.Set("instType", instType)
+	}
+	params.Set("t", fmt.Sprintf("%d", p.clock.Now().UnixMilli()))
+	endpoint := fmt.Sprintf("%s/priapi/v5/ecotrade/public/community/user/position-current?%s", p.baseURL, params.Encode())
 
-	req, err := http.NewRequest("GET", endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
+	acceptGzip(req)
 
-	resp, err := p.client.Do(req)
+	resp, err := doRequest(p.client, p.clock, req)
 	if err != nil {
 		return nil, err
 	}
@@ -461,32 +997,54 @@ func (p *okxProvider) fetchPositions() (map[string]okxPositionMeta, error) {
 	}
 
 	var result okxPositionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := decodeJSON(resp, p.maxBody, &result); err != nil {
 		return nil, err
 	}
 
 	positions := make(map[string]okxPositionMeta)
 	for _, entry := range result.Data {
 		for _, pos := range entry.PosData {
-			symbol := formatOKXSymbol(pos.InstID)
-			if symbol == "" {
-				continue
-			}
-			size, _ := strconv.ParseFloat(pos.Pos, 64)
-			lever, _ := strconv.ParseFloat(pos.Lever, 64)
-			if lever <= 0 {
-				lever = 1
-			}
-			// sign by side
-			if strings.ToLower(pos.PosSide) == "short" {
-				size = -size
-			}
-			positions[symbol] = okxPositionMeta{
-				Size:       size,
-				Leverage:   int(lever),
-				MarginMode: strings.ToLower(pos.MarginMode),
+			if symbol, meta, ok := p.positionMeta(ctx, pos, instType); ok {
+				positions[symbol] = meta
 			}
 		}
 	}
 	return positions, nil
 }
+
+// positionMeta normalizes one position row into a signed size in coins, keyed by
+// okxPositionKey.
+func (p *okxProvider) positionMeta(ctx context.Context, pos okxPositionEntry, instType string) (string, okxPositionMeta, bool) {
+	symbol := formatOKXSymbol(pos.InstID)
+	if symbol == "" {
+		return "", okxPositionMeta{}, false
+	}
+	if instType == "MARGIN" {
+		return symbol, okxMarginPosition(p.logger, pos), true
+	}
+	size, sizeOK := parseOKXFloat(p.logger, "pos", pos.Pos, pos.InstID)
+	if sizeOK {
+		// OKX reports contracts; convert to coins so every venue shares a base unit
+		ctVal, err := p.contractValue(ctx, pos.InstID)
+		if err != nil {
+			p.logger.Warnf("⚠️  OKX contract spec unavailable for %s: %v", pos.InstID, err)
+		}
+		size, sizeOK = size*ctVal, err == nil
+	}
+	lever, leverOK := parseOKXFloat(p.logger, "lever", pos.Lever, pos.InstID)
+	if leverOK && lever <= 0 {
+		lever = 1
+	}
+	size = signOKXSize(pos.PosSide, size)
+	entry, _ := strconv.ParseFloat(pos.AvgPx, 64)
+	liq, _ := strconv.ParseFloat(pos.LiqPx, 64)
+	return okxPositionKey(symbol, pos.PosSide), okxPositionMeta{
+		Size:          size,
+		EntryPrice:    entry,
+		LiqPrice:      liq,
+		Leverage:      int(lever),
+		MarginMode:    normalizeMarginMode(pos.MarginMode),
+		SizeValid:     sizeOK,
+		LeverageValid: leverOK,
+	}, true
+}
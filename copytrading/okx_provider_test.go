@@ -0,0 +1,530 @@
+package copytrading
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// okxFake serves canned priapi responses keyed by the last path segment,
+// preferring a "segment?instType=X" key when the request names one.
+type okxFake struct {
+	mu        sync.Mutex
+	responses map[string]string
+	requests  map[string]int
+}
+
+func newOKXFake() *okxFake {
+	return &okxFake{requests: make(map[string]int), responses: map[string]string{
+		"trade-records":    `{"code":"0","data":[]}`,
+		"asset":            `{"code":"0","data":[{"currency":"USDT","amount":"1000"}]}`,
+		"position-current": `{"code":"0","data":[{"posData":[]}]}`,
+		"instruments":      `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","ctVal":"1","ctMult":"1"}]}`,
+	}}
+}
+
+func (f *okxFake) set(endpoint, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[endpoint] = body
+}
+
+func (f *okxFake) client() *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		endpoint := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests[endpoint]++
+		body, ok := f.responses[endpoint+"?instType="+r.URL.Query().Get("instType")]
+		if !ok {
+			body, ok = f.responses[endpoint]
+		}
+		if !ok {
+			return jsonResponse(http.StatusNotFound, `{}`), nil
+		}
+		return jsonResponse(http.StatusOK, body), nil
+	})}
+}
+
+func newTestOKXProvider(fake *okxFake, cfg Config) *okxProvider {
+	cfg.Identifier = "leader"
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Hour
+	}
+	cfg.HTTPClient = fake.client()
+	if cfg.SharedCache == nil {
+		// keep instrument fixtures from leaking between tests through the shared cache
+		cfg.SharedCache = NewSharedCache(0, nil)
+	}
+	return newOKXProvider(cfg).(*okxProvider)
+}
+
+func okxPositions(entries ...string) string {
+	return `{"code":"0","data":[{"posData":[` + strings.Join(entries, ",") + `]}]}`
+}
+
+func TestOKXUnparseableFieldsDoNotCorruptPositions(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"2","lever":"10"}`))
+
+	p := newTestOKXProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	// missing pos/lever and an empty fillTime must not look like a flat position
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"-","fillTime":"","ordId":"2"}]}`)
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"","lever":"-"}`))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("expected no phantom close, got %+v", <-out)
+	}
+	if meta, _ := p.tracker.position("BTCUSDT"); meta.Size != 2 || meta.Leverage != 10 {
+		t.Fatalf("position corrupted: %+v", meta)
+	}
+
+	// once the size is readable again a real change is diffed normally
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"3","lever":"garbage"}`))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	sig := <-out
+	if sig.Action != ActionAddLong || sig.DeltaSize != 1 || sig.LeaderLeverage != 10 || sig.Price != 100 {
+		t.Fatalf("unexpected signal: %+v", sig)
+	}
+}
+
+func TestParseOKXFloat(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want float64
+		ok   bool
+	}{
+		{"1.5", 1.5, true},
+		{"0", 0, true},
+		{"", 0, false},
+		{"-", 0, false},
+		{"abc", 0, false},
+		{" 2 ", 2, true},
+	}
+	for _, tc := range cases {
+		got, ok := parseOKXFloat(stdLogger{}, "test", tc.raw, "")
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("parseOKXFloat(%q) = %v, %v; want %v, %v", tc.raw, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestOKXOversizedResponseIsRejected(t *testing.T) {
+	fake := newOKXFake()
+	rows := make([]string, 0, 200)
+	for i := 0; i < 200; i++ {
+		rows = append(rows, `{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}`)
+	}
+	// positions are critical, so an oversized snapshot fails the cycle
+	fake.set("position-current", okxPositions(rows...))
+
+	p := newTestOKXProvider(fake, Config{MaxResponseBytes: 1024})
+	err := p.fetchAndEmit(context.Background(), make(chan Signal, 1))
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func TestOKXDecodesGzipResponses(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"code":"0","data":[{"currency":"USDT","amount":"1234.5"}]}`))
+	gz.Close()
+
+	p := newTestOKXProvider(newOKXFake(), Config{})
+	p.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("expected gzip to be requested")
+		}
+		resp := jsonResponse(http.StatusOK, "")
+		resp.Header.Set("Content-Encoding", "gzip")
+		resp.Body = io.NopCloser(bytes.NewReader(buf.Bytes()))
+		return resp, nil
+	})}
+
+	equity, err := p.fetchEquity(context.Background())
+	if err != nil || equity != 1234.5 {
+		t.Fatalf("expected gzip body decoded, got %v, %v", equity, err)
+	}
+}
+
+func TestOKXCursorAdvancesAcrossPolls(t *testing.T) {
+	fake := newOKXFake()
+	p := newTestOKXProvider(fake, Config{})
+	out := make(chan Signal, 8)
+
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Initialized() || p.Cursor() != 1700000000000 {
+		t.Fatalf("unexpected cursor state: %v/%d", p.Initialized(), p.Cursor())
+	}
+
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000005000","ordId":"2"}]}`)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if p.Cursor() != 1700000005000 {
+		t.Fatalf("expected cursor to advance, got %d", p.Cursor())
+	}
+}
+
+func TestOKXEquityValuedAtDepeggedStablecoinRate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		cfg    Config
+		equity float64
+	}{
+		{"default 1:1", Config{}, 1000},
+		{"depegged", Config{
+			StablecoinRateSymbol: "USDTUSD",
+			PriceOracle: PriceOracleFunc(func(symbol string) (float64, error) {
+				if symbol != "USDTUSD" {
+					t.Fatalf("unexpected oracle symbol %s", symbol)
+				}
+				return 0.9, nil
+			}),
+		}, 900},
+		{"rate unavailable", Config{
+			StablecoinRateSymbol: "USDTUSD",
+			PriceOracle: PriceOracleFunc(func(string) (float64, error) {
+				return 0, errors.New("no quote")
+			}),
+		}, 1000},
+	} {
+		fake := newOKXFake()
+		fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+		p := newTestOKXProvider(fake, tc.cfg)
+		out := make(chan Signal, 8)
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatalf("%s: seed: %v", tc.name, err)
+		}
+
+		fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}`))
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(out) != 1 {
+			t.Fatalf("%s: expected one open, got %d", tc.name, len(out))
+		}
+		if sig := <-out; math.Abs(sig.LeaderEquity-tc.equity) > 1e-9 {
+			t.Fatalf("%s: expected leader equity %.2f, got %.2f", tc.name, tc.equity, sig.LeaderEquity)
+		}
+	}
+}
+
+func TestOKXContractsNetAgainstHyperliquidCoins(t *testing.T) {
+	okxFake := newOKXFake()
+	okxFake.set("instruments", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","ctVal":"0.01","ctMult":"1"}]}`)
+	okxFake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"short","pos":"30","lever":"10"}`))
+	okx := newTestOKXProvider(okxFake, Config{})
+
+	hlFake := newHyperliquidFake()
+	hlFake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"BTC","szi":"0.5","leverage":{"type":"cross","value":5}}}]}`)
+	hl := newTestHyperliquidProvider(hlFake, Config{})
+
+	out := make(chan Signal, 8)
+	if err := okx.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if err := hl.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	okxBTC, hlBTC := okx.positions()["BTCUSDT"], hl.positions()["BTCUSDT"]
+	if math.Abs(okxBTC.Size+0.3) > 1e-9 {
+		t.Fatalf("expected 30 contracts of 0.01 BTC to be -0.3 BTC, got %v", okxBTC.Size)
+	}
+	// 0.5 BTC long on Hyperliquid against 0.3 BTC short on OKX
+	if net := okxBTC.Size + hlBTC.Size; math.Abs(net-0.2) > 1e-9 {
+		t.Fatalf("expected combined exposure of 0.2 BTC, got %v", net)
+	}
+}
+
+func (f *okxFake) requestCount(endpoint string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[endpoint]
+}
+
+func TestOKXProvidersShareVenueLookups(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cache := NewSharedCache(time.Minute, clock)
+	fake := newOKXFake()
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}`))
+
+	first := newTestOKXProvider(fake, Config{SharedCache: cache, Clock: clock})
+	second := newTestOKXProvider(fake, Config{SharedCache: cache, Clock: clock})
+	second.uniqueName = "other-leader"
+	out := make(chan Signal, 8)
+	for _, p := range []*okxProvider{first, second} {
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := fake.requestCount("instruments"); n != 1 {
+		t.Fatalf("expected one shared instruments request within the TTL, got %d", n)
+	}
+	// private data is never shared
+	if n := fake.requestCount("position-current"); n != 2 {
+		t.Fatalf("expected per-leader position requests, got %d", n)
+	}
+
+	clock.Advance(2 * time.Minute)
+	third := newTestOKXProvider(fake, Config{SharedCache: cache, Clock: clock})
+	if _, err := third.contractValue(context.Background(), "BTC-USDT-SWAP"); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.requestCount("instruments"); n != 2 {
+		t.Fatalf("expected a refetch after the TTL, got %d", n)
+	}
+}
+
+func TestOKXEquityBasis(t *testing.T) {
+	for _, tc := range []struct {
+		basis  string
+		equity float64
+	}{{"", 1000}, {EquityTotal, 1000}, {EquityAvailable, 600}} {
+		fake := newOKXFake()
+		fake.set("asset", `{"code":"0","data":[{"currency":"USDT","amount":"1000","availEq":"600"}]}`)
+		fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+		p := newTestOKXProvider(fake, Config{EquityBasis: tc.basis})
+		out := make(chan Signal, 8)
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+
+		fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}`))
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 1 {
+			t.Fatalf("basis %q: expected one open, got %d", tc.basis, len(out))
+		}
+		if sig := <-out; sig.LeaderEquity != tc.equity {
+			t.Fatalf("basis %q: expected leader equity %v, got %v", tc.basis, tc.equity, sig.LeaderEquity)
+		}
+	}
+}
+
+func TestOKXClosesSurviveFillsOutage(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}`))
+	p := newTestOKXProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fills endpoint starts failing while the leader closes
+	fake.set("trade-records", `not json`)
+	fake.set("position-current", okxPositions())
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatalf("a fills outage must not abort the cycle: %v", err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("expected the close to be emitted, got %d signals", len(out))
+	}
+	if sig := <-out; sig.Action != ActionCloseLong || sig.Price != 100 {
+		t.Fatalf("unexpected signal: %+v", sig)
+	}
+	if p.lastFillTime != 1700000000000 {
+		t.Fatalf("cursor must not move without fills, got %d", p.lastFillTime)
+	}
+
+	// a positions outage still aborts
+	fake.set("position-current", `not json`)
+	if err := p.fetchAndEmit(context.Background(), out); err == nil {
+		t.Fatal("expected a positions failure to abort the cycle")
+	}
+}
+
+func TestOKXFollowsSpotMarginPositions(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	p := newTestOKXProvider(fake, Config{OKXIncludeMargin: true})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("trade-records?instType=MARGIN", `{"code":"0","data":[{"instId":"ETH-USDT","avgPx":"3000","fillTime":"1700000060000","ordId":"2"},{"instId":"BTC-USDT","avgPx":"60000","fillTime":"1700000060000","ordId":"3"}]}`)
+	fake.set("position-current?instType=MARGIN", okxPositions(
+		`{"instId":"ETH-USDT","mgnMode":"cross","posCcy":"ETH","pos":"2","lever":"3"}`,
+		`{"instId":"BTC-USDT","mgnMode":"isolated","liabCcy":"BTC","liab":"-0.5","posCcy":"USDT","pos":"30000","avgPx":"60000","lever":"3"}`,
+	))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Signal{}
+	for len(out) > 0 {
+		sig := <-out
+		got[sig.Symbol] = sig
+	}
+	if sig := got["ETHUSDT"]; sig.Action != ActionOpenLong || sig.DeltaSize != 2 {
+		t.Fatalf("expected a 2 ETH margin long, got %+v", sig)
+	}
+	if sig := got["BTCUSDT"]; sig.Action != ActionOpenShort || sig.DeltaSize != -0.5 {
+		t.Fatalf("expected a 0.5 BTC borrow-driven short, got %+v", sig)
+	}
+
+	// without the opt-in the MARGIN book is never queried
+	before := fake.requestCount("position-current")
+	plain := newTestOKXProvider(fake, Config{})
+	if err := plain.fetchAndEmit(context.Background(), make(chan Signal, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.requestCount("position-current") - before; n != 1 {
+		t.Fatalf("expected a single SWAP position request, got %d", n)
+	}
+}
+
+func TestOKXOpensCarryLeaderLiquidationPrice(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	p := newTestOKXProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"isolated","posSide":"short","pos":"1","lever":"10","liqPx":"109.5"}`))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if sig := <-out; sig.Action != ActionOpenShort || sig.LeaderLiqPrice != 109.5 {
+		t.Fatalf("expected the leader's liquidation price on the open, got %+v", sig)
+	}
+}
+
+func TestOKXSignalsCarryLeaderFillTime(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	p := newTestOKXProvider(fake, Config{Clock: clock})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","side":"buy","avgPx":"101","sz":"1","fillTime":"1700000060000","ordId":"2"}]}`)
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}`))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	sig := <-out
+	if !sig.Timestamp.Equal(time.UnixMilli(1700000060000)) || !sig.DetectedAt.Equal(clock.Now()) {
+		t.Fatalf("expected the fill time and the observation time, got %v / %v", sig.Timestamp, sig.DetectedAt)
+	}
+}
+
+func TestOKXNetModeShortKeepsItsSign(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	p := newTestOKXProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","side":"sell","posSide":"net","avgPx":"99","sz":"2","fillTime":"1700000060000","ordId":"2"}]}`)
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"net","pos":"-2","lever":"5"}`))
+	positions, err := p.fetchPositions(context.Background(), "SWAP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := positions["BTCUSDT"].Size; size != -2 {
+		t.Fatalf("expected the net-mode short signed negative, got %g", size)
+	}
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if sig := <-out; sig.Action != ActionOpenShort || sig.LeaderPosAfter != -2 {
+		t.Fatalf("expected a net-mode short to open short, got %+v", sig)
+	}
+	if got := signOKXSize("short", -3); got != -3 {
+		t.Fatalf("expected a short leg signed negative whatever pos's sign, got %g", got)
+	}
+}
+
+func TestOKXHedgeModeLegsAreIndependent(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"2","lever":"5"}`))
+	p := newTestOKXProvider(fake, Config{})
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+
+	// a short leg opens next to the long one
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","side":"sell","posSide":"short","avgPx":"101","sz":"1","fillTime":"1700000060000","ordId":"2"}]}`)
+	fake.set("position-current", okxPositions(
+		`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"2","lever":"5"}`,
+		`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"short","pos":"1","lever":"5"}`,
+	))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("expected only the short leg to signal, got %d signals", len(out))
+	}
+	if sig := <-out; sig.Symbol != "BTCUSDT" || sig.Action != ActionOpenShort || sig.Price != 101 || sig.DeltaSize != -1 {
+		t.Fatalf("expected the short leg opened at its own fill, got %+v", sig)
+	}
+	if net := p.positions()["BTCUSDT"].Size; net != 1 {
+		t.Fatalf("expected the legs netted to 1 BTC, got %g", net)
+	}
+
+	// the long leg closes while the short stays
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","side":"sell","posSide":"long","avgPx":"102","sz":"2","fillTime":"1700000120000","ordId":"3"}]}`)
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"short","pos":"1","lever":"5"}`))
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("expected only the long leg to signal, got %d signals", len(out))
+	}
+	if sig := <-out; sig.Symbol != "BTCUSDT" || sig.Action != ActionCloseLong || sig.Price != 102 || sig.LeaderPosBefore != 2 {
+		t.Fatalf("expected the long leg closed at its own fill, got %+v", sig)
+	}
+}
+
+func TestOKXStateReadableWhileEmitting(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5","avgPx":"100"}`))
+	p := newTestOKXProvider(fake, Config{SeedPricesFromEntry: true})
+	if err := p.fetchAndEmit(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"110","sz":"1","side":"buy","posSide":"long","fillTime":"1700000000000","ordId":"1"}]}`)
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"2","lever":"5","avgPx":"105"}`))
+	out := make(chan Signal)
+	checkReadsWhileBlocked(t, out, func() error { return p.fetchAndEmit(context.Background(), out) }, func() bool {
+		p.saveState()
+		btc := p.Snapshot()["BTCUSDT"]
+		return p.Initialized() && p.Cursor() == 1700000000000 && btc.Size == 2 && btc.Price == 110
+	})
+}
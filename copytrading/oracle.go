@@ -0,0 +1,107 @@
+package copytrading
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PriceOracle supplies reference prices (e.g. a stablecoin's USD rate) to providers.
+type PriceOracle interface {
+	Price(symbol string) (float64, error)
+}
+
+// PriceOracleFunc adapts a plain function to PriceOracle.
+type PriceOracleFunc func(symbol string) (float64, error)
+
+func (f PriceOracleFunc) Price(symbol string) (float64, error) { return f(symbol) }
+
+// marketOracle is the default oracle backed by the market data package.
+var marketOracle = PriceOracleFunc(func(symbol string) (float64, error) { return marketPrice(symbol) })
+
+// BlendedPriceOracle prices signals from a weighted blend of the leader's fill price
+// and the venue's mark and index prices instead of the fill alone. Sources that are
+// missing (no oracle, no price, or zero weight) are dropped and the remaining weights
+// renormalized; with no source at all the price falls through to market data.
+type BlendedPriceOracle struct {
+	FillWeight  float64
+	MarkWeight  float64
+	IndexWeight float64
+	Mark        PriceOracle
+	Index       PriceOracle
+}
+
+// blend returns the blended price for symbol and a description of the sources used,
+// e.g. "fill:0.50+mark:0.50". It returns 0 when no price is available.
+func (b *BlendedPriceOracle) blend(symbol string, fill float64) (float64, string) {
+	type source struct {
+		name          string
+		price, weight float64
+	}
+	candidates := []source{{name: "fill", price: fill, weight: b.FillWeight}}
+	for _, o := range []struct {
+		name   string
+		oracle PriceOracle
+		weight float64
+	}{{"mark", b.Mark, b.MarkWeight}, {"index", b.Index, b.IndexWeight}} {
+		if o.oracle == nil || o.weight <= 0 {
+			continue
+		}
+		if price, err := o.oracle.Price(symbol); err == nil {
+			candidates = append(candidates, source{name: o.name, price: price, weight: o.weight})
+		}
+	}
+
+	var total float64
+	var used []source
+	for _, c := range candidates {
+		if c.price > 0 && c.weight > 0 {
+			used = append(used, c)
+			total += c.weight
+		}
+	}
+	if len(used) == 0 {
+		price, err := marketPrice(symbol)
+		if err != nil || price <= 0 {
+			return 0, ""
+		}
+		return price, "market"
+	}
+
+	var price float64
+	parts := make([]string, 0, len(used))
+	for _, c := range used {
+		w := c.weight / total
+		price += c.price * w
+		parts = append(parts, fmt.Sprintf("%s:%.2f", c.name, w))
+	}
+	return price, strings.Join(parts, "+")
+}
+
+// stablecoinValuer converts a leader's stablecoin-denominated equity to USD.
+type stablecoinValuer struct {
+	symbol string // oracle symbol of the stablecoin's USD rate; empty means 1:1
+	oracle PriceOracle
+	logger Logger
+}
+
+func newStablecoinValuer(cfg Config) stablecoinValuer {
+	oracle := cfg.PriceOracle
+	if oracle == nil {
+		oracle = marketOracle
+	}
+	return stablecoinValuer{symbol: cfg.StablecoinRateSymbol, oracle: oracle, logger: loggerOf(cfg.Logger)}
+}
+
+// toUSD values amount at the live stablecoin rate. When the rate is unavailable the
+// amount is kept 1:1 rather than failing the poll.
+func (v stablecoinValuer) toUSD(amount float64) float64 {
+	if v.symbol == "" {
+		return amount
+	}
+	rate, err := v.oracle.Price(v.symbol)
+	if err != nil || rate <= 0 {
+		loggerOf(v.logger).Warnf("⚠️  stablecoin rate %s unavailable, assuming 1:1: %v", v.symbol, err)
+		return amount
+	}
+	return amount * rate
+}
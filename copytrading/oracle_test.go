@@ -0,0 +1,99 @@
+package copytrading
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func fixedOracle(prices map[string]float64) PriceOracle {
+	return PriceOracleFunc(func(symbol string) (float64, error) {
+		if p, ok := prices[symbol]; ok {
+			return p, nil
+		}
+		return 0, errors.New("no price")
+	})
+}
+
+// oracleQuotes serves an oracle's prices as market quotes of unknown age.
+func oracleQuotes(oracle PriceOracle) func(string) (float64, time.Time, error) {
+	return func(symbol string) (float64, time.Time, error) {
+		price, err := oracle.Price(symbol)
+		return price, time.Time{}, err
+	}
+}
+
+func stubMarketPrice(t *testing.T, prices map[string]float64) {
+	t.Helper()
+	orig := marketQuote
+	marketQuote = oracleQuotes(fixedOracle(prices))
+	t.Cleanup(func() { marketQuote = orig })
+}
+
+func TestBlendedPriceOracle(t *testing.T) {
+	stubMarketPrice(t, map[string]float64{"BTCUSDT": 99})
+	cases := []struct {
+		name   string
+		oracle BlendedPriceOracle
+		fill   float64
+		price  float64
+		source string
+	}{
+		{
+			name: "full blend",
+			oracle: BlendedPriceOracle{
+				FillWeight: 2, MarkWeight: 1, IndexWeight: 1,
+				Mark:  fixedOracle(map[string]float64{"BTCUSDT": 104}),
+				Index: fixedOracle(map[string]float64{"BTCUSDT": 108}),
+			},
+			fill:   100,
+			price:  103,
+			source: "fill:0.50+mark:0.25+index:0.25",
+		},
+		{
+			name: "missing mark renormalizes",
+			oracle: BlendedPriceOracle{
+				FillWeight: 1, MarkWeight: 1, IndexWeight: 1,
+				Mark:  fixedOracle(nil),
+				Index: fixedOracle(map[string]float64{"BTCUSDT": 110}),
+			},
+			fill:   100,
+			price:  105,
+			source: "fill:0.50+index:0.50",
+		},
+		{
+			name: "all missing falls through to market data",
+			oracle: BlendedPriceOracle{
+				FillWeight: 1, MarkWeight: 1,
+				Mark: fixedOracle(nil),
+			},
+			price:  99,
+			source: "market",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			price, source := tc.oracle.blend("BTCUSDT", tc.fill)
+			if math.Abs(price-tc.price) > 1e-9 || source != tc.source {
+				t.Fatalf("got %v (%s), want %v (%s)", price, source, tc.price, tc.source)
+			}
+		})
+	}
+}
+
+func TestTrackerRecordsBlendedPriceSource(t *testing.T) {
+	tr, _ := newTestTracker(Config{BlendedPrice: &BlendedPriceOracle{
+		FillWeight: 1, MarkWeight: 1,
+		Mark: fixedOracle(map[string]float64{"BTCUSDT": 110}),
+	}})
+	tr.update(book(nil), 1000)
+
+	signals := tr.update(book(map[string]float64{"BTCUSDT": 1}), 1000)
+	if len(signals) != 1 || signals[0].Price != 105 || signals[0].PriceSource != "fill:0.50+mark:0.50" {
+		t.Fatalf("unexpected signals: %+v", signals)
+	}
+	if tr.lastPrices["BTCUSDT"] != 100 {
+		t.Fatalf("blending must not overwrite the last fill price, got %v", tr.lastPrices["BTCUSDT"])
+	}
+}
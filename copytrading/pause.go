@@ -0,0 +1,47 @@
+package copytrading
+
+import (
+	"sync/atomic"
+)
+
+// PauseController suppresses emission across every provider sharing it. Paused
+// providers keep polling and advancing their cursors and snapshots, so resuming
+// mirrors the then-current leader state instead of replaying a backlog.
+type PauseController struct {
+	paused atomic.Bool
+}
+
+// DefaultPauseController is used by providers whose Config.Pause is nil, so one call
+// pauses every provider in the process.
+var DefaultPauseController = NewPauseController()
+
+func NewPauseController() *PauseController {
+	return &PauseController{}
+}
+
+func (c *PauseController) Pause()  { c.paused.Store(true) }
+func (c *PauseController) Resume() { c.paused.Store(false) }
+
+// Paused reports whether emission is currently suppressed.
+func (c *PauseController) Paused() bool {
+	return c != nil && c.paused.Load()
+}
+
+func pauseOf(cfg Config) *PauseController {
+	if cfg.Pause != nil {
+		return cfg.Pause
+	}
+	return DefaultPauseController
+}
+
+// suppressWhilePaused drops signals while paused; the caller has already advanced its
+// snapshot past them.
+func suppressWhilePaused(pause *PauseController, logger Logger, venue string, signals []Signal) []Signal {
+	if !pause.Paused() {
+		return signals
+	}
+	if len(signals) > 0 {
+		loggerOf(logger).Infof("⏸ %s provider paused, suppressed %d signal(s)", venue, len(signals))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package copytrading
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPauseSuppressesWithoutBacklogReplay(t *testing.T) {
+	pause := NewPauseController()
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	p := newTestOKXProvider(fake, Config{Pause: pause})
+	out := make(chan Signal, 8)
+
+	poll := func(pos string) {
+		t.Helper()
+		if pos == "" {
+			fake.set("position-current", okxPositions())
+		} else {
+			fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"`+pos+`","lever":"5"}`))
+		}
+		if err := p.fetchAndEmit(context.Background(), out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	poll("")
+	pause.Pause()
+	poll("1")
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"110","fillTime":"1700000005000","ordId":"2"}]}`)
+	poll("3")
+	if len(out) != 0 {
+		t.Fatalf("expected no emissions while paused, got %d", len(out))
+	}
+	if p.Cursor() != 1700000005000 {
+		t.Fatalf("cursor must keep advancing while paused, got %d", p.Cursor())
+	}
+
+	pause.Resume()
+	poll("3")
+	if len(out) != 0 {
+		t.Fatalf("resume must not replay the paused backlog, got %d", len(out))
+	}
+	poll("4")
+	if len(out) != 1 {
+		t.Fatalf("expected mirroring to continue from the current state, got %d", len(out))
+	}
+	if sig := <-out; sig.Action != ActionAddLong || sig.LeaderPosBefore != 3 || sig.LeaderPosAfter != 4 {
+		t.Fatalf("unexpected signal after resume: %+v", sig)
+	}
+}
+
+func TestDefaultPauseControllerIsShared(t *testing.T) {
+	a := pauseOf(Config{})
+	b := pauseOf(Config{})
+	DefaultPauseController.Pause()
+	defer DefaultPauseController.Resume()
+	if !a.Paused() || !b.Paused() {
+		t.Fatal("expected one call to pause every provider using the default controller")
+	}
+}
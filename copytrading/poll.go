@@ -0,0 +1,58 @@
+package copytrading
+
+import "time"
+
+// defaultIdleBackoffAfter is how long a leader must stay quiet before polling slows.
+const defaultIdleBackoffAfter = time.Minute
+
+// adaptivePoll stretches the poll interval toward MaxPollInterval while the leader is
+// inactive and snaps back to PollInterval on the first sign of activity. It is only
+// touched by the poll loop.
+type adaptivePoll struct {
+	min, max  time.Duration
+	idleAfter time.Duration
+	clock     Clock
+
+	current      time.Duration
+	lastActivity time.Time
+}
+
+func newAdaptivePoll(cfg Config) *adaptivePoll {
+	idleAfter := cfg.IdleBackoffAfter
+	if idleAfter <= 0 {
+		idleAfter = defaultIdleBackoffAfter
+	}
+	clock := clockOf(cfg.Clock)
+	return &adaptivePoll{
+		min:          cfg.PollInterval,
+		max:          cfg.MaxPollInterval,
+		idleAfter:    idleAfter,
+		clock:        clock,
+		current:      cfg.PollInterval,
+		lastActivity: clock.Now(),
+	}
+}
+
+// observe records the outcome of a successful poll.
+func (a *adaptivePoll) observe(active bool) {
+	now := a.clock.Now()
+	if active || a.max <= a.min {
+		a.current = a.min
+		if active {
+			a.lastActivity = now
+		}
+		return
+	}
+	if now.Sub(a.lastActivity) < a.idleAfter {
+		return
+	}
+	a.current *= 2
+	if a.current > a.max {
+		a.current = a.max
+	}
+}
+
+// interval is the wait before the next poll.
+func (a *adaptivePoll) interval() time.Duration {
+	return a.current
+}
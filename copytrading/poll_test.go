@@ -0,0 +1,57 @@
+package copytrading
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptivePollBacksOffAndResets(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	poll := newAdaptivePoll(Config{
+		PollInterval:     3 * time.Second,
+		MaxPollInterval:  time.Minute,
+		IdleBackoffAfter: 30 * time.Second,
+		Clock:            clock,
+	})
+
+	var intervals []time.Duration
+	for i := 0; i < 16; i++ {
+		clock.Advance(poll.interval())
+		poll.observe(false)
+		intervals = append(intervals, poll.interval())
+	}
+
+	if intervals[0] != 3*time.Second {
+		t.Fatalf("must keep the fast interval during the idle grace period, got %v", intervals)
+	}
+	for i := 1; i < len(intervals); i++ {
+		if intervals[i] < intervals[i-1] {
+			t.Fatalf("interval must not shrink while inactive: %v", intervals)
+		}
+	}
+	if last := intervals[len(intervals)-1]; last != time.Minute {
+		t.Fatalf("expected interval to reach the max, got %v", intervals)
+	}
+
+	clock.Advance(poll.interval())
+	poll.observe(true)
+	if poll.interval() != 3*time.Second {
+		t.Fatalf("expected reset to the fast interval on activity, got %v", poll.interval())
+	}
+	// the idle grace period restarts from the new activity
+	clock.Advance(10 * time.Second)
+	poll.observe(false)
+	if poll.interval() != 3*time.Second {
+		t.Fatalf("expected fast polling right after activity, got %v", poll.interval())
+	}
+}
+
+func TestAdaptivePollDisabledWithoutMax(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	poll := newAdaptivePoll(Config{PollInterval: 3 * time.Second, Clock: clock})
+	clock.Advance(time.Hour)
+	poll.observe(false)
+	if poll.interval() != 3*time.Second {
+		t.Fatalf("expected fixed interval without MaxPollInterval, got %v", poll.interval())
+	}
+}
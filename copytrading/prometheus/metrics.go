@@ -0,0 +1,168 @@
+// Package prometheus exposes copytrading.Metrics in the Prometheus text exposition
+// format, so a follower can be scraped without linking the Prometheus client.
+//
+//	m := prometheus.New("copytrading")
+//	http.Handle("/metrics", m)
+//	provider, err := copytrading.NewProvider(copytrading.Config{..., Metrics: m})
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/copytrading"
+)
+
+// DefaultBuckets are the fetch latency histogram's upper bounds, in seconds.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var _ copytrading.Metrics = (*Metrics)(nil)
+
+// Metrics counts emitted and dropped signals by action and symbol, fetch errors by
+// endpoint, and records fetch latency per endpoint as a histogram. It serves them
+// over HTTP.
+type Metrics struct {
+	namespace string
+	buckets   []float64
+
+	mu        sync.Mutex
+	signals   map[[2]string]uint64 // {action, symbol}
+	dropped   map[[2]string]uint64 // {action, symbol}
+	errors    map[string]uint64
+	durations map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// New returns an empty Metrics whose series are prefixed with namespace.
+func New(namespace string) *Metrics {
+	return &Metrics{
+		namespace: namespace,
+		buckets:   DefaultBuckets,
+		signals:   make(map[[2]string]uint64),
+		dropped:   make(map[[2]string]uint64),
+		errors:    make(map[string]uint64),
+		durations: make(map[string]*histogram),
+	}
+}
+
+func (m *Metrics) IncSignal(action, symbol string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.signals[[2]string{action, symbol}]++
+}
+
+func (m *Metrics) IncDropped(action, symbol string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropped[[2]string{action, symbol}]++
+}
+
+func (m *Metrics) ObserveFetch(endpoint string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.errors[endpoint]++
+	}
+	h := m.durations[endpoint]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.durations[endpoint] = h
+	}
+	seconds := d.Seconds()
+	for i, le := range m.buckets {
+		if seconds <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// ServeHTTP writes every series in the text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes every series in the text exposition format, sorted by label.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	name := m.name("signals_total")
+	fmt.Fprintf(&b, "# HELP %s Signals emitted, by action and symbol.\n# TYPE %s counter\n", name, name)
+	for _, key := range sortedSignalKeys(m.signals) {
+		fmt.Fprintf(&b, "%s{action=%q,symbol=%q} %d\n", name, key[0], key[1], m.signals[key])
+	}
+
+	name = m.name("signals_dropped_total")
+	fmt.Fprintf(&b, "# HELP %s Signals dropped by a full buffer, by action and symbol.\n# TYPE %s counter\n", name, name)
+	for _, key := range sortedSignalKeys(m.dropped) {
+		fmt.Fprintf(&b, "%s{action=%q,symbol=%q} %d\n", name, key[0], key[1], m.dropped[key])
+	}
+
+	name = m.name("fetch_errors_total")
+	fmt.Fprintf(&b, "# HELP %s Failed fetches, by endpoint.\n# TYPE %s counter\n", name, name)
+	for _, endpoint := range sortedKeys(m.errors) {
+		fmt.Fprintf(&b, "%s{endpoint=%q} %d\n", name, endpoint, m.errors[endpoint])
+	}
+
+	name = m.name("fetch_duration_seconds")
+	fmt.Fprintf(&b, "# HELP %s Fetch latency, by endpoint.\n# TYPE %s histogram\n", name, name)
+	for _, endpoint := range sortedKeys(m.durations) {
+		h := m.durations[endpoint]
+		var cumulative uint64
+		for i, le := range m.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "%s_bucket{endpoint=%q,le=%q} %d\n", name, endpoint, fmt.Sprint(le), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket{endpoint=%q,le=\"+Inf\"} %d\n", name, endpoint, h.count)
+		fmt.Fprintf(&b, "%s_sum{endpoint=%q} %g\n", name, endpoint, h.sum)
+		fmt.Fprintf(&b, "%s_count{endpoint=%q} %d\n", name, endpoint, h.count)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (m *Metrics) name(series string) string {
+	if m.namespace == "" {
+		return series
+	}
+	return m.namespace + "_" + series
+}
+
+func sortedSignalKeys(counts map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
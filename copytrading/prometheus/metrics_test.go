@@ -0,0 +1,36 @@
+package prometheus
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	m := New("copytrading")
+	m.IncSignal("open_long", "BTCUSDT")
+	m.IncSignal("open_long", "BTCUSDT")
+	m.IncDropped("add_long", "ETHUSDT")
+	m.ObserveFetch("okx/equity", 80*time.Millisecond, nil)
+	m.ObserveFetch("okx/equity", 3*time.Second, errors.New("timeout"))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`copytrading_signals_total{action="open_long",symbol="BTCUSDT"} 2`,
+		`copytrading_signals_dropped_total{action="add_long",symbol="ETHUSDT"} 1`,
+		`copytrading_fetch_errors_total{endpoint="okx/equity"} 1`,
+		`copytrading_fetch_duration_seconds_bucket{endpoint="okx/equity",le="0.1"} 1`,
+		`copytrading_fetch_duration_seconds_bucket{endpoint="okx/equity",le="5"} 2`,
+		`copytrading_fetch_duration_seconds_bucket{endpoint="okx/equity",le="+Inf"} 2`,
+		`copytrading_fetch_duration_seconds_count{endpoint="okx/equity"} 2`,
+		"# TYPE copytrading_fetch_duration_seconds histogram",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the exposition:\n%s", want, body)
+		}
+	}
+}
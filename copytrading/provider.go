@@ -1,44 +1,301 @@
 package copytrading
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math"
+	"math/rand"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
+
+	"nofx/market"
 )
 
 // SignalAction represents the normalized action type emitted by signal providers.
 type SignalAction string
 
 const (
-	ActionOpenLong  SignalAction = "open_long"
-	ActionOpenShort SignalAction = "open_short"
-	ActionCloseLong SignalAction = "close_long"
-	ActionCloseShort SignalAction = "close_short"
-	ActionAddLong   SignalAction = "add_long"    // treated as open_long with delta
-	ActionAddShort  SignalAction = "add_short"   // treated as open_short with delta
-	ActionReduceLong SignalAction = "reduce_long" // treated as close_long with delta
+	ActionOpenLong    SignalAction = "open_long"
+	ActionOpenShort   SignalAction = "open_short"
+	ActionCloseLong   SignalAction = "close_long"
+	ActionCloseShort  SignalAction = "close_short"
+	ActionAddLong     SignalAction = "add_long"    // treated as open_long with delta
+	ActionAddShort    SignalAction = "add_short"   // treated as open_short with delta
+	ActionReduceLong  SignalAction = "reduce_long" // treated as close_long with delta
 	ActionReduceShort SignalAction = "reduce_short"
+	// ActionSetPosition carries an absolute signed target in TargetSize instead of a
+	// delta; only emitted when Config.EmitTargets is set.
+	ActionSetPosition SignalAction = "set_position"
+	// ActionReverseLong (short to long) and ActionReverseShort (long to short) carry
+	// both legs of a flip in one signal; only emitted when Config.CoalesceFlips is set.
+	ActionReverseLong  SignalAction = "reverse_long"
+	ActionReverseShort SignalAction = "reverse_short"
+	// ActionHeartbeat is a non-trading liveness sentinel emitted when Config.Heartbeat
+	// is set; it carries only Timestamp and DetectedAt. Consumers must never trade it.
+	ActionHeartbeat SignalAction = "heartbeat"
 )
 
 // Signal is the normalized structure describing a leader's fill event.
 type Signal struct {
-	Symbol        string
-	Action        SignalAction
-	NotionalUSD   float64   // Absolute fill size in USD
-	Price         float64   // Leader fill price (if available)
-	LeaderEquity  float64   // Leader account equity at the moment of fill
-	LeaderLeverage int
-	MarginMode    string    // "cross" or "isolated"
-	Timestamp     time.Time
+	LeaderID       string // "venue:identifier" of the leader; set by MultiProvider
+	Symbol         string
+	Action         SignalAction
+	NotionalUSD    float64 // Absolute fill size in USD
+	Price          float64 // Leader fill price (if available)
+	LeaderEquity   float64 // Leader account equity at the moment of fill
+	LeaderLeverage int     // last known on a close reported without one
+	MarginMode     string  // "cross" or "isolated"; last known on a bare close
+	// MarginUSD is the margin behind NotionalUSD at the leader's leverage, for
+	// followers sizing by margin rather than notional.
+	MarginUSD float64
+	// EffectiveLeverage is the leverage actually carried by the position. Under cross
+	// margin the risk is pooled, so it is the leader's total cross notional over
+	// equity; isolated positions keep their per-symbol leverage.
+	EffectiveLeverage float64
+	// LeaderBookNotionalUSD is the gross USD notional of the leader's whole mirrored
+	// book after this change, for budget-style allocation across symbols.
+	LeaderBookNotionalUSD float64
+	// Timestamp is when the leader filled: the oldest new fill behind the signal, or
+	// DetectedAt for a position change observed without a fill.
+	Timestamp time.Time
+	// DetectedAt is when the change was observed; Timestamp to DetectedAt is the
+	// detection latency.
+	DetectedAt time.Time
+	// DedupKey identifies the leader change behind the signal: symbol and action plus
+	// the fill time, or the position move when no fill was seen. A provider never
+	// emits the same key twice within Config.DedupWindowSize signals.
+	DedupKey string
 	// For proportional reduce/close:
-	DeltaSize        float64 // leader position change size (signed)
-	LeaderPosBefore  float64 // leader position size before this change (signed)
-	LeaderPosAfter   float64 // leader position size after this change (signed)
+	DeltaSize       float64 // leader position change size (signed)
+	LeaderPosBefore float64 // leader position size before this change (signed)
+	LeaderPosAfter  float64 // leader position size after this change (signed)
+	// TargetSize is the signed position to hold for ActionSetPosition.
+	TargetSize float64
+	// CloseNotionalUSD and OpenNotionalUSD split a reverse signal into its legs: the
+	// close of the old side at its closing price and the open of the new side at
+	// Price. NotionalUSD is their sum, DeltaSize the whole move from LeaderPosBefore
+	// (old side) to LeaderPosAfter (new side), and MarginUSD backs the open leg only.
+	// Both are 0 on every other action.
+	CloseNotionalUSD float64
+	OpenNotionalUSD  float64
+	// IsReentry marks an open on a symbol the leader fully closed within
+	// Config.ReentryWindow, as opposed to a brand-new symbol.
+	IsReentry bool
+	// PriceSource records how Price was derived when Config.BlendedPrice is set,
+	// e.g. "fill:0.50+mark:0.50", or "market" when every blended source was missing.
+	PriceSource string
+	// LeverageAdjusted is the leverage to use instead of LeaderLeverage when the
+	// follower cannot honor the leader's (see Config.AllowedLeverage); 0 means no
+	// adjustment. LeverageWarning explains the adjustment.
+	LeverageAdjusted int
+	LeverageWarning  string
+	// StaleByDuration is how long ago the leader's oldest fill behind this signal
+	// happened, set when it exceeds Config.MaxFillLatency.
+	StaleByDuration time.Duration
+	// Confidence in (0, 1] rates how well the signal is backed: 1 for a fresh fill at
+	// a known price, lower for diffs without a fill, market data fallback prices and
+	// old fills. Followers may scale sizing by it.
+	Confidence float64
+	// Urgency lets executors with a priority queue order execution (see urgencyFor).
+	Urgency Urgency
+	// Liquidation marks a close caused by the leader being liquidated.
+	Liquidation bool
+	// RealizedPnLUSD is the leader's realized PnL on the closing fills behind a
+	// close/reduce signal, for benchmarking the follower's own execution. Such
+	// signals are priced at those fills' average price. Only venues reporting
+	// per-fill PnL (Hyperliquid) fill it in.
+	RealizedPnLUSD float64
+	// LeaderLiqPrice is the leader position's estimated liquidation price after an
+	// open or add (0 if the venue does not report it), so followers can keep a stop
+	// safely inside it.
+	LeaderLiqPrice float64
+}
+
+// Urgency ranks how quickly a signal should be executed.
+type Urgency string
+
+const (
+	UrgencyHigh   Urgency = "high"
+	UrgencyNormal Urgency = "normal"
+	UrgencyLow    Urgency = "low"
+)
+
+// urgencyFor maps an action to its urgency:
+//   - high: closes (including liquidations), reversals and both legs of a flip, which
+//     cap risk;
+//   - normal: opens and set_position targets;
+//   - low: adds and reduces, which only resize an existing position.
+//
+// Flips are detected by the tracker, which has the whole batch.
+func urgencyFor(action SignalAction) Urgency {
+	switch action {
+	case ActionCloseLong, ActionCloseShort, ActionReverseLong, ActionReverseShort:
+		return UrgencyHigh
+	case ActionAddLong, ActionAddShort, ActionReduceLong, ActionReduceShort:
+		return UrgencyLow
+	}
+	return UrgencyNormal
 }
 
-// Provider defines the behaviour for any external signal source.
+// PendingOrderStatus describes the lifecycle of a leader's resting order.
+type PendingOrderStatus string
+
+const (
+	PendingOrderOpen PendingOrderStatus = "open" // newly seen resting order
+	PendingOrderGone PendingOrderStatus = "gone" // no longer resting (filled or canceled)
+	// PendingOrderUpdated marks a protective order whose trigger or size moved
+	// (e.g. a trailing stop).
+	PendingOrderUpdated PendingOrderStatus = "updated"
+)
+
+// PendingOrder is a leader's resting (unfilled) limit order. It signals intent only and
+// is delivered separately from position Signals.
+type PendingOrder struct {
+	Symbol      string
+	OrderID     string
+	Side        string // "buy" or "sell"
+	Price       float64
+	Size        float64 // remaining size in base units
+	NotionalUSD float64
+	ReduceOnly  bool
+	Status      PendingOrderStatus
+	Timestamp   time.Time
+}
+
+// ProtectiveKind distinguishes a leader's stop-loss from its take-profit order.
+type ProtectiveKind string
+
+const (
+	ProtectiveStopLoss   ProtectiveKind = "stop_loss"
+	ProtectiveTakeProfit ProtectiveKind = "take_profit"
+)
+
+// ProtectiveOrder is a leader's conditional (stop/take-profit) order. It is delivered
+// separately from position Signals so followers can mirror the leader's risk
+// management.
+type ProtectiveOrder struct {
+	Symbol       string
+	OrderID      string
+	Kind         ProtectiveKind
+	Side         string // order side when triggered: "buy" or "sell"
+	PositionSide string // position it protects: "long" or "short"
+	TriggerPrice float64
+	Size         float64 // base units; 0 means the whole position
+	Status       PendingOrderStatus
+	Timestamp    time.Time
+}
+
+// Equity bases for Config.EquityBasis.
+const (
+	EquityTotal     = "total"
+	EquityAvailable = "available"
+)
+
+// Provider defines the behaviour for any external signal source. Run blocks until
+// ctx is done; cancelling ctx also aborts in-flight requests.
 type Provider interface {
-	Run(stopCh <-chan struct{}, out chan<- Signal) error
+	Run(ctx context.Context, out chan<- Signal) error
+}
+
+// RunUntil runs p until stopCh closes, for callers still driving providers with a
+// stop channel.
+func RunUntil(p Provider, stopCh <-chan struct{}, out chan<- Signal) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return p.Run(ctx, out)
+}
+
+// Follow modes selectable via Config.Mode.
+const (
+	ModeTrade = "trade" // default: mirror every observed position change
+	ModeNet   = "net"   // mirror only the net position per symbol at a coarse cadence
+	ModeDaily = "daily" // one net diff per day at RebalanceTimeOfDay (UTC)
+	// ModeReplicate emits the leader's whole book as ActionSetPosition targets, one
+	// per symbol in symbol order, whenever anything in it changes. Symbols closed by
+	// the change are included with a zero target.
+	ModeReplicate = "replicate"
+)
+
+// defaultNetSampleInterval is the cadence of ModeNet when SampleInterval is unset.
+const defaultNetSampleInterval = time.Minute
+
+// PositionMeta is a leader position normalized across venues.
+type PositionMeta struct {
+	Size       float64 // signed size in coins (base units): long>0, short<0
+	Leverage   int
+	MarginMode string
+	EntryPrice float64 // average entry price, 0 if the venue does not report it
+	LiqPrice   float64 // estimated liquidation price, 0 if unknown
+	Price      float64 // last known price; only filled in by Snapshot
+}
+
+// normalizeMarginMode maps a venue's margin mode to "cross" or "isolated", the values
+// Signal.MarginMode carries. Anything else becomes "", which leaves the follower's
+// margin mode alone.
+func normalizeMarginMode(mode string) string {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case "cross", "isolated":
+		return mode
+	default:
+		return ""
+	}
+}
+
+// CursorReporter is implemented by providers that expose their fill cursor for
+// diagnostics ("why did we miss a signal?").
+type CursorReporter interface {
+	Cursor() int64     // last processed fill id / fill time
+	Initialized() bool // whether the leader snapshot has been seeded
+}
+
+// SnapshotReporter is implemented by providers that can report the leader's book as
+// currently mirrored, e.g. for a UI to render it between signals. Snapshot returns a
+// copy that is safe to read while Run is active.
+type SnapshotReporter interface {
+	Snapshot() map[string]PositionMeta
+}
+
+// ReadyNotifier is implemented by providers that announce the "provider ready"
+// lifecycle event: the channel is closed once the leader's book has been fetched
+// for the first time.
+type ReadyNotifier interface {
+	Ready() <-chan struct{}
+}
+
+var (
+	// ErrLeaderNotFound means the venue does not know the leader identifier.
+	// Retrying will not help; the configuration needs fixing.
+	ErrLeaderNotFound = errors.New("copytrading: leader not found")
+	// ErrNoEquity means the leader exists but holds no equity to size signals
+	// against. It may clear once the leader funds the account.
+	ErrNoEquity = errors.New("copytrading: leader has no equity")
+	// ErrInvalidConfig wraps every configuration error NewProvider reports.
+	ErrInvalidConfig = errors.New("copytrading: invalid config")
+)
+
+// Stopper is implemented by providers that can shut down gracefully: after Stop, Run
+// finishes the poll in progress, emits a close for every mirrored position when
+// Config.CloseAllOnStop is set, and returns nil. That drain waits for out to be read;
+// cancelling Run's ctx instead returns at once, abandoning signals not yet delivered.
+type Stopper interface {
+	Stop()
+}
+
+// HealthChecker is implemented by providers that can validate their leader with a
+// single lightweight fetch before Run, e.g. to report "leader not found" in a UI
+// instead of silently emitting nothing.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
 }
 
 // Config contains shared initialization parameters for all providers.
@@ -47,28 +304,396 @@ type Config struct {
 	Identifier   string
 	PollInterval time.Duration
 	HTTPClient   *http.Client
+
+	// BaseURL replaces the venue's production host (scheme, host and any path
+	// prefix), for regional domains, testnets or an httptest.Server. Empty uses
+	// the production host. Websocket endpoints are unaffected.
+	BaseURL string
+
+	// ProxyURL routes the default HTTP client through an http://, https:// or
+	// socks5:// proxy, e.g. "socks5://127.0.0.1:1080". Ignored when HTTPClient is
+	// set; configure the proxy on that client instead.
+	ProxyURL string
+
+	// Headers are set on every outbound request, e.g. a Referer or Cookie some
+	// public community endpoints require. A browser-like User-Agent is sent unless
+	// Headers overrides it.
+	Headers map[string]string
+
+	// Fake is returned by NewProvider for Type "fake", e.g. a scripted
+	// copytradingtest.FakeProvider, so consumers can be exercised without an
+	// exchange.
+	Fake Provider
+
+	// RecordDir, when set, writes every successful upstream response into the
+	// directory as a timestamped JSON fixture, for replay with NewFixtureProvider.
+	RecordDir string
+
+	// Transport selects how the provider learns about changes: TransportREST (the
+	// default) polls every PollInterval; TransportWS seeds from REST, then streams the
+	// leader's fills and positions over a websocket instead. UseWebSocket is
+	// shorthand for TransportWS.
+	Transport    string
+	UseWebSocket bool
+
+	// MaxPollInterval, when above PollInterval, lets polling back off toward it
+	// while the leader stays quiet for IdleBackoffAfter (default 1m); any observed
+	// change snaps back to PollInterval.
+	MaxPollInterval  time.Duration
+	IdleBackoffAfter time.Duration
+
+	// MaxBackoff caps the wait after consecutive fetch errors (default 60s): each
+	// failure doubles it from PollInterval, with jitter, and the first success
+	// resets it.
+	MaxBackoff time.Duration
+
+	// Mode selects trade replication (default) or ModeNet position replication for
+	// grid/DCA leaders whose many small legs would be costly to mirror one by one.
+	Mode string
+	// RebalanceThreshold is the minimum relative size change (0.1 = 10%) ModeNet
+	// needs before re-targeting an open symbol. Opens from flat and full closes
+	// always pass.
+	RebalanceThreshold float64
+	// RebalanceTimeOfDay is the offset from 00:00 UTC at which ModeDaily emits the
+	// accumulated net diff (default midnight).
+	RebalanceTimeOfDay time.Duration
+
+	// EmitTargets expresses every change, flips included, as one ActionSetPosition
+	// per symbol with the signed target size, for executors that compute the net
+	// order themselves instead of round-tripping through flat.
+	EmitTargets bool
+
+	// CloseAllOnStop makes a provider stopped through Stopper emit a close for each
+	// position in the leader's mirrored book before Run returns, so the follower
+	// flattens when it stops mirroring the leader. The closes carry Confidence 1.
+	CloseAllOnStop bool
+
+	// CoalesceFlips merges the close and open legs of a flip into one ActionReverseLong
+	// or ActionReverseShort signal (see Signal.CloseNotionalUSD), for followers that
+	// reverse atomically with a single order. Off by default; ignored with EmitTargets
+	// and ModeReplicate, which already express flips as one target.
+	CoalesceFlips bool
+
+	// PriceVWAP prices a cycle's changes at the volume-weighted average of the
+	// cycle's new fills instead of the last fill's price.
+	PriceVWAP bool
+
+	// SampleInterval, when positive, only mirrors the net position change once per
+	// interval instead of on every poll, ignoring intra-interval wiggles.
+	SampleInterval time.Duration
+	// SampleClosesImmediately lets full closes bypass SampleInterval and fire on the
+	// poll that observes them.
+	SampleClosesImmediately bool
+
+	// ReentryWindow tags opens on symbols closed within this window as re-entries.
+	ReentryWindow time.Duration
+
+	// MaxResponseBytes caps a decoded response body (default 8 MiB).
+	MaxResponseBytes int64
+
+	// StateStore, when set, restores the cursor and mirrored book on start and
+	// saves them after every successful poll and when Run returns (see
+	// NewFileStateStore).
+	StateStore StateStore
+
+	// DedupWindowSize is how many recently emitted Signal.DedupKeys each provider
+	// remembers to drop repeated signals (default 256). Negative disables it.
+	DedupWindowSize int
+
+	// Heartbeat, when positive, makes Run emit an ActionHeartbeat signal on the out
+	// channel whenever no other signal was emitted for that long, so a watchdog can
+	// tell an idle leader from a stuck provider.
+	Heartbeat time.Duration
+
+	// BufferSize, when positive, queues up to that many signals between the provider
+	// and the out channel, so a slow consumer does not stall polling. Overflow picks
+	// what a full queue does (default OverflowBlock). Closes, liquidations and flips
+	// are never dropped, see withBuffer.
+	BufferSize int
+	Overflow   OverflowPolicy
+
+	// CatchUp, on a start restored from StateStore, compares the saved book with the
+	// leader's current one and emits the net difference as ordinary open, add,
+	// reduce and close signals (a flip becomes a close then an open). By default the
+	// current book is adopted silently, so nothing traded while offline is replayed.
+	CatchUp bool
+
+	// Logger receives the providers' diagnostics, including a Debug line per emitted
+	// signal (default: the standard log package, without Debug).
+	Logger Logger
+
+	// Metrics receives signal counts and fetch latencies (default: none recorded).
+	Metrics Metrics
+
+	// Errors, when set, receives every failed poll, prefixed with the provider's
+	// state key, so an orchestrator can count consecutive failures and restart or
+	// alert. Sends never block: errors are dropped while the channel is full.
+	// SilenceLogs stops the provider from also logging them.
+	Errors      chan<- error
+	SilenceLogs bool
+
+	// IncludeOpenOrders fetches the leader's resting orders and delivers them to
+	// PendingOrders. Only venues exposing open orders (Hyperliquid) support it.
+	IncludeOpenOrders bool
+	PendingOrders     chan<- PendingOrder
+	// IncludeProtectiveOrders fetches the leader's stop/take-profit orders and
+	// delivers them to ProtectiveOrders (Hyperliquid only).
+	IncludeProtectiveOrders bool
+	ProtectiveOrders        chan<- ProtectiveOrder
+
+	// ImportHistory seeds Stats() at startup from the leader's closed trades within
+	// HistoryLookback (default 7 days) without emitting any signals (Hyperliquid only).
+	ImportHistory   bool
+	HistoryLookback time.Duration
+
+	// VerifyFills cross-checks every new fill against the venue's order-status
+	// endpoint and drops fills that cannot be confirmed before they move the cursor
+	// or prices. Costs one request per new order (Hyperliquid only).
+	VerifyFills bool
+
+	// StrictAddress additionally requires a Hyperliquid Identifier in its EIP-55
+	// checksummed form, so a mistyped character is caught instead of following an
+	// empty wallet (Hyperliquid only). See ValidateHyperliquidAddress.
+	StrictAddress bool
+
+	// StablecoinRateSymbol, when set (e.g. "USDTUSD"), values the leader's
+	// stablecoin equity at the live rate from PriceOracle instead of 1:1, keeping
+	// equity-proportional sizing correct during a depeg.
+	StablecoinRateSymbol string
+	// SeedPricesFromEntry prices positions held at startup from the leader's entry
+	// price rather than market data (market data remains the fallback, and vice
+	// versa), so the first change after init never waits on a price.
+	SeedPricesFromEntry bool
+
+	// EquityBasis selects which leader equity feeds Signal.LeaderEquity: EquityTotal
+	// (default, raw account value) or EquityAvailable (the usable, risk-adjusted
+	// figure where the venue exposes it; total otherwise).
+	EquityBasis string
+
+	// OKXIncludeMargin additionally follows the leader's spot-margin (instType=MARGIN)
+	// positions. A margin long holds the base currency, a borrow-driven short owes it;
+	// both are merged into the signed size of the matching perp symbol.
+	OKXIncludeMargin bool
+
+	// PriceOracle supplies reference prices (default: market data).
+	PriceOracle PriceOracle
+	// BlendedPrice, when set, prices signals from a weighted blend of the fill, mark
+	// and index prices rather than the fill alone.
+	BlendedPrice *BlendedPriceOracle
+
+	// Shadow compares, on every poll, the book implied by the emitted signals with
+	// the leader's fetched book and logs divergences above ShadowTolerance (relative,
+	// default 1%). OnDivergence additionally receives each divergence.
+	Shadow          bool
+	ShadowTolerance float64
+	OnDivergence    func(Divergence)
+
+	// OnDuplicate decides what happens when this leader is already watched by another
+	// provider in the process (default DuplicateError).
+	OnDuplicate DuplicatePolicy
+
+	// AllowedLeverage reports the follower's maximum leverage for a symbol. When set,
+	// opens and adds whose leader leverage exceeds it are annotated with
+	// LeverageAdjusted/LeverageWarning before emission.
+	AllowedLeverage func(symbol string) (max int, ok bool)
+
+	// VolatilityThreshold, when set, suppresses opens and adds on a symbol whose
+	// leader fill prices within VolatilityWindow (default 5m) range wider than this
+	// fraction of their mean. Closes and reduces always pass.
+	VolatilityThreshold float64
+	VolatilityWindow    time.Duration
+
+	// MaxFillLatency, when set, annotates signals whose leader fill is older than
+	// this with StaleByDuration. With SuppressLateOpens, such late opens and adds are
+	// dropped; late closes and reduces are always emitted.
+	MaxFillLatency    time.Duration
+	SuppressLateOpens bool
+
+	// MaxPriceAge, when set, refuses market data quotes older than this as the price
+	// fallback: a change without a fill price is held and retried on the next poll
+	// rather than emitted with a NotionalUSD computed on a stale price.
+	MaxPriceAge time.Duration
+
+	// ConfirmDirectionConflicts holds, for one confirmation poll, a position change
+	// whose direction contradicts the leader's fills in the same poll (e.g. the
+	// snapshot shows a reduce while the new fills are buys on a long), instead of
+	// emitting it and reversing it on the next poll.
+	ConfirmDirectionConflicts bool
+
+	// MaxOpensPerCycle, when positive, caps the new open_* signals emitted per poll,
+	// so a basket entry is mirrored a few symbols at a time. The remaining opens wait
+	// for later polls in a stable order (earliest deferred first, then by symbol);
+	// closes, reduces and adds are never held back.
+	MaxOpensPerCycle int
+
+	// RequestsPerSecond bounds this provider's request rate to the venue (default 5;
+	// negative disables the limit). RateLimiter, when set, is used instead, so
+	// providers polling one upstream can share a single budget.
+	RequestsPerSecond float64
+	RateLimiter       *RateLimiter
+
+	// SharedCache deduplicates public lookups (market data, instrument specs) across
+	// providers of the same venue (default: DefaultSharedCache).
+	SharedCache *SharedCache
+
+	// Bus, when set, also receives every emitted signal for filtered subscribers. Run
+	// may then be given a nil out channel.
+	Bus *SignalBus
+
+	// Pause suppresses emission while paused (default: DefaultPauseController).
+	Pause *PauseController
+
+	// Clock drives every time-dependent decision and signal timestamp (default: the
+	// real clock).
+	Clock Clock
 }
 
 // NewProvider constructs the correct Provider implementation based on the type field.
 func NewProvider(cfg Config) (Provider, error) {
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
 	if cfg.HTTPClient == nil {
-		cfg.HTTPClient = &http.Client{
-			Timeout: 10 * time.Second,
+		client, err := defaultHTTPClient(cfg.ProxyURL)
+		if err != nil {
+			return nil, err
 		}
+		cfg.HTTPClient = client
 	}
 	if cfg.PollInterval <= 0 {
 		cfg.PollInterval = 3 * time.Second
 	}
+	if cfg.RecordDir != "" {
+		cfg.HTTPClient = recordingClient(cfg.HTTPClient, cfg)
+	}
+	cfg.HTTPClient = headerClient(cfg.HTTPClient, cfg.Headers)
+	cfg.HTTPClient = limitedClient(cfg.HTTPClient, rateLimiterOf(cfg))
 	switch cfg.Type {
 	case "hyperliquid_wallet", "hyperliquid":
-		return newHyperliquidProvider(cfg.Identifier, cfg.PollInterval, cfg.HTTPClient), nil
+		return newHyperliquidProvider(cfg), nil
 	case "okx_wallet", "okx":
-		return newOKXProvider(cfg.Identifier, cfg.PollInterval, cfg.HTTPClient), nil
+		return newOKXProvider(cfg), nil
+	case "binance_wallet", "binance":
+		return newBinanceProvider(cfg), nil
+	case "bybit_wallet", "bybit":
+		return newBybitProvider(cfg), nil
+	case "fake":
+		if cfg.Fake == nil {
+			return nil, fmt.Errorf("%w: fake signal source requires Config.Fake", ErrInvalidConfig)
+		}
+		return cfg.Fake, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported signal source type %q", ErrInvalidConfig, cfg.Type)
+	}
+}
+
+// validateConfig rejects configurations that would otherwise only fail inside Run,
+// or be silently replaced by a default.
+func validateConfig(cfg Config) error {
+	if cfg.PollInterval < 0 {
+		return fmt.Errorf("%w: negative PollInterval %v", ErrInvalidConfig, cfg.PollInterval)
+	}
+	if cfg.Type == "fake" {
+		return nil
+	}
+	identifier := strings.TrimSpace(cfg.Identifier)
+	if identifier == "" {
+		return fmt.Errorf("%w: %s signal source requires an Identifier", ErrInvalidConfig, cfg.Type)
+	}
+	switch cfg.Type {
+	case "hyperliquid_wallet", "hyperliquid":
+		if err := ValidateHyperliquidAddress(identifier, cfg.StrictAddress); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+	return nil
+}
+
+// errorReporter delivers a provider's poll errors to Config.Errors and the log.
+type errorReporter struct {
+	venue  string // log prefix, e.g. "OKX"
+	errs   chan<- error
+	silent bool
+	logger Logger
+}
+
+func newErrorReporter(cfg Config, venue string) errorReporter {
+	return errorReporter{venue: venue, errs: cfg.Errors, silent: cfg.SilenceLogs, logger: loggerOf(cfg.Logger)}
+}
+
+// report logs err unless silenced and offers it to the error channel without
+// blocking the poll loop.
+func (r errorReporter) report(key string, err error) {
+	if !r.silent {
+		loggerOf(r.logger).Errorf("⚠️  %s provider error: %v", r.venue, err)
+	}
+	if r.errs == nil {
+		return
+	}
+	select {
+	case r.errs <- fmt.Errorf("%s: %w", key, err):
 	default:
-		return nil, errors.New("unsupported signal source type")
 	}
 }
 
+// defaultMaxBackoff caps the error backoff when Config.MaxBackoff is unset.
+const defaultMaxBackoff = 60 * time.Second
+
+// errorBackoff spaces out polls of a failing or rate-limited endpoint. It is only
+// touched by the poll loop.
+type errorBackoff struct {
+	base, max time.Duration
+	failures  int
+	// jitter randomizes a backoff step; replaceable in tests
+	jitter func(time.Duration) time.Duration
+}
+
+func newErrorBackoff(cfg Config) *errorBackoff {
+	max := cfg.MaxBackoff
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	return &errorBackoff{base: cfg.PollInterval, max: max, jitter: equalJitter}
+}
+
+// observe records the outcome of a fetch.
+func (b *errorBackoff) observe(err error) {
+	if err != nil {
+		b.failures++
+		return
+	}
+	b.failures = 0
+}
+
+// wait returns the delay before the next fetch: next (the regular poll interval)
+// while healthy, otherwise PollInterval doubled per consecutive failure up to max.
+func (b *errorBackoff) wait(next time.Duration) time.Duration {
+	if b.failures == 0 {
+		return next
+	}
+	d := b.base
+	for i := 0; i < b.failures && d < b.max; i++ {
+		d *= 2
+	}
+	if d > b.max || d <= 0 {
+		d = b.max
+	}
+	if b.jitter != nil {
+		d = b.jitter(d)
+	}
+	return d
+}
+
+// equalJitter keeps half of d and randomizes the rest, so providers failing
+// together do not retry in lockstep.
+func equalJitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
 // deriveActionFromDelta determines action based on previous and current position size (signed).
 // Caller should handle direction flip separately if needed.
 func deriveActionFromDelta(prev, curr float64) SignalAction {
@@ -90,3 +715,190 @@ func deriveActionFromDelta(prev, curr float64) SignalAction {
 	}
 	return ""
 }
+
+// ErrStalePrice is returned by the market data fallback when the quote is older than
+// Config.MaxPriceAge; the symbol is retried on the next poll.
+var ErrStalePrice = errors.New("copytrading: market price is stale")
+
+// marketQuote is the last-resort price lookup used when no leader fill price is known,
+// with the time the quote was last updated (zero if unknown).
+var marketQuote = func(symbol string) (float64, time.Time, error) {
+	md, err := market.Get(symbol)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return md.CurrentPrice, md.UpdatedAt, nil
+}
+
+// marketPrice is marketQuote without the quote's age.
+func marketPrice(symbol string) (float64, error) {
+	price, _, err := marketQuote(symbol)
+	return price, err
+}
+
+// freshPrice wraps a quote lookup into a price lookup refusing quotes older than
+// maxAge (0 accepts any age).
+func freshPrice(quote func(string) (float64, time.Time, error), maxAge time.Duration, now func() time.Time) func(string) (float64, error) {
+	return func(symbol string) (float64, error) {
+		price, at, err := quote(symbol)
+		if err != nil {
+			return 0, err
+		}
+		if age := now().Sub(at); maxAge > 0 && !at.IsZero() && age > maxAge {
+			return 0, fmt.Errorf("%w: %s quote is %v old", ErrStalePrice, symbol, age.Round(time.Second))
+		}
+		return price, nil
+	}
+}
+
+// diffPositionsAt turns the change between two leader snapshots into signals
+// stamped at now, in symbol order. Symbols without a known price are skipped
+// so the caller can retry them later.
+func diffPositionsAt(prev, curr map[string]PositionMeta, prices map[string]float64, equity float64, now time.Time) []Signal {
+	symbols := make([]string, 0, len(prev)+len(curr))
+	for sym := range curr {
+		symbols = append(symbols, sym)
+	}
+	for sym := range prev {
+		if _, ok := curr[sym]; !ok {
+			symbols = append(symbols, sym)
+		}
+	}
+	sort.Strings(symbols)
+
+	var signals []Signal
+	for _, sym := range symbols {
+		old := prev[sym]
+		meta, ok := curr[sym]
+		if !ok {
+			// a symbol missing from the snapshot closes exactly like one reported flat
+			meta = PositionMeta{Leverage: old.Leverage, MarginMode: old.MarginMode}
+		}
+		if meta.Size == old.Size {
+			continue
+		}
+		price := prices[sym]
+		if price <= 0 {
+			continue
+		}
+		signals = append(signals, transitionSignals(sym, old.Size, meta, price, equity, now)...)
+	}
+	return signals
+}
+
+// transitionSignals describes a single symbol moving from size prev to curr.Size.
+// A direction flip is emitted as a full close followed by an open.
+func transitionSignals(symbol string, prev float64, curr PositionMeta, price, equity float64, ts time.Time) []Signal {
+	build := func(action SignalAction, before, after float64) Signal {
+		return Signal{
+			Symbol:          symbol,
+			Action:          action,
+			NotionalUSD:     math.Abs(after-before) * price,
+			Price:           price,
+			LeaderEquity:    equity,
+			LeaderLeverage:  curr.Leverage,
+			MarginMode:      curr.MarginMode,
+			Timestamp:       ts,
+			DeltaSize:       after - before,
+			LeaderPosBefore: before,
+			LeaderPosAfter:  after,
+		}
+	}
+
+	switch {
+	case prev > 0 && curr.Size < 0:
+		return []Signal{build(ActionCloseLong, prev, 0), build(ActionOpenShort, 0, curr.Size)}
+	case prev < 0 && curr.Size > 0:
+		return []Signal{build(ActionCloseShort, prev, 0), build(ActionOpenLong, 0, curr.Size)}
+	case curr.Size == 0 && prev > 0:
+		return []Signal{build(ActionCloseLong, prev, 0)}
+	case curr.Size == 0 && prev < 0:
+		return []Signal{build(ActionCloseShort, prev, 0)}
+	case prev == 0 && curr.Size > 0:
+		return []Signal{build(ActionOpenLong, 0, curr.Size)}
+	case prev == 0 && curr.Size < 0:
+		return []Signal{build(ActionOpenShort, 0, curr.Size)}
+	}
+
+	action := deriveActionFromDelta(prev, curr.Size)
+	if action == "" {
+		return nil
+	}
+	return []Signal{build(action, prev, curr.Size)}
+}
+
+// targetSignals collapses the delta signals of each symbol into a single
+// ActionSetPosition moving from the first leg's starting size to the last leg's end.
+func targetSignals(signals []Signal) []Signal {
+	var order []string
+	bySymbol := make(map[string]Signal, len(signals))
+	for _, sig := range signals {
+		target, seen := bySymbol[sig.Symbol]
+		if !seen {
+			order = append(order, sig.Symbol)
+			target = sig
+			target.Action = ActionSetPosition
+		} else {
+			before := target.LeaderPosBefore
+			target = sig
+			target.Action = ActionSetPosition
+			target.LeaderPosBefore = before
+		}
+		target.TargetSize = target.LeaderPosAfter
+		target.DeltaSize = target.LeaderPosAfter - target.LeaderPosBefore
+		target.NotionalUSD = math.Abs(target.DeltaSize) * target.Price
+		bySymbol[sig.Symbol] = target
+	}
+
+	out := make([]Signal, 0, len(order))
+	for _, sym := range order {
+		out = append(out, bySymbol[sym])
+	}
+	return out
+}
+
+// coalesceFlips merges each close immediately followed by an open of the other side
+// on the same symbol into one reverse signal. The open leg supplies the new side's
+// terms and price; a flip whose open leg was filtered out stays a plain close.
+func coalesceFlips(signals []Signal) []Signal {
+	out := signals[:0]
+	for i := 0; i < len(signals); i++ {
+		sig := signals[i]
+		if i+1 < len(signals) {
+			if action, ok := reverseAction(sig, signals[i+1]); ok {
+				closeLeg, open := sig, signals[i+1]
+				sig = open
+				sig.Action = action
+				sig.LeaderPosBefore = closeLeg.LeaderPosBefore
+				sig.DeltaSize = open.LeaderPosAfter - closeLeg.LeaderPosBefore
+				sig.CloseNotionalUSD = closeLeg.NotionalUSD
+				sig.OpenNotionalUSD = open.NotionalUSD
+				sig.NotionalUSD = closeLeg.NotionalUSD + open.NotionalUSD
+				sig.RealizedPnLUSD = closeLeg.RealizedPnLUSD
+				sig.Liquidation = closeLeg.Liquidation
+				sig.Timestamp = closeLeg.Timestamp
+				sig.Confidence = math.Min(closeLeg.Confidence, open.Confidence)
+				sig.Urgency = UrgencyHigh
+				sig.DedupKey = closeLeg.DedupKey + "+" + open.DedupKey
+				i++
+			}
+		}
+		out = append(out, sig)
+	}
+	return out
+}
+
+// reverseAction reports the reverse action when closeLeg and open are the two legs of
+// one flip.
+func reverseAction(closeLeg, open Signal) (SignalAction, bool) {
+	if closeLeg.Symbol != open.Symbol {
+		return "", false
+	}
+	switch {
+	case closeLeg.Action == ActionCloseShort && open.Action == ActionOpenLong:
+		return ActionReverseLong, true
+	case closeLeg.Action == ActionCloseLong && open.Action == ActionOpenShort:
+		return ActionReverseShort, true
+	}
+	return "", false
+}
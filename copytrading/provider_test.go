@@ -0,0 +1,417 @@
+package copytrading
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain keeps unit tests off the live market data feed; tests that need a market
+// price stub it explicitly.
+func TestMain(m *testing.M) {
+	marketQuote = func(string) (float64, time.Time, error) { return 0, time.Time{}, errors.New("no market data in tests") }
+	os.Exit(m.Run())
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestFanOutDeliversToAllSubscribers(t *testing.T) {
+	fan := NewFanOut(4)
+	a, unsubA := fan.Subscribe()
+	b, _ := fan.Subscribe()
+
+	in := make(chan Signal)
+	done := make(chan struct{})
+	go func() {
+		fan.Run(in)
+		close(done)
+	}()
+
+	in <- Signal{Symbol: "BTCUSDT", Action: ActionOpenLong}
+	if (<-a).Symbol != "BTCUSDT" || (<-b).Symbol != "BTCUSDT" {
+		t.Fatal("both subscribers must receive the signal")
+	}
+
+	// an unsubscribed reader that stops draining must not block the others
+	unsubA()
+	in <- Signal{Symbol: "ETHUSDT"}
+	in <- Signal{Symbol: "SOLUSDT"}
+	if (<-b).Symbol != "ETHUSDT" || (<-b).Symbol != "SOLUSDT" {
+		t.Fatal("remaining subscriber must keep receiving in order")
+	}
+
+	close(in)
+	<-done
+	if _, ok := <-b; ok {
+		t.Fatal("subscriber channel must close when the source ends")
+	}
+	if fan.Len() != 0 {
+		t.Fatalf("expected no subscribers after close, got %d", fan.Len())
+	}
+}
+
+func TestDiffPositions(t *testing.T) {
+	prev := map[string]PositionMeta{
+		"BTCUSDT": {Size: 2, Leverage: 5, MarginMode: "cross"},
+		"ETHUSDT": {Size: -4, Leverage: 3, MarginMode: "isolated"},
+		"SOLUSDT": {Size: 10, Leverage: 2, MarginMode: "cross"},
+	}
+	curr := map[string]PositionMeta{
+		"BTCUSDT":  {Size: -1, Leverage: 5, MarginMode: "cross"},    // flip
+		"ETHUSDT":  {Size: -1, Leverage: 3, MarginMode: "isolated"}, // partial reduce
+		"DOGEUSDT": {Size: 100, Leverage: 10, MarginMode: "cross"},  // new open
+		// SOLUSDT disappeared: full close
+	}
+	prices := map[string]float64{"BTCUSDT": 100, "ETHUSDT": 10, "SOLUSDT": 5, "DOGEUSDT": 0.1}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := map[string][]Signal{}
+	for _, sig := range diffPositionsAt(prev, curr, prices, 1000, now) {
+		if !sig.Timestamp.Equal(now) {
+			t.Fatalf("expected signals stamped at the given time, got %v", sig.Timestamp)
+		}
+		got[sig.Symbol] = append(got[sig.Symbol], sig)
+	}
+
+	if btc := got["BTCUSDT"]; len(btc) != 2 ||
+		btc[0].Action != ActionCloseLong || btc[0].LeaderPosBefore != 2 || btc[0].LeaderPosAfter != 0 ||
+		btc[1].Action != ActionOpenShort || btc[1].LeaderPosAfter != -1 || btc[1].NotionalUSD != 100 {
+		t.Fatalf("expected the flip as a close then an open, got %+v", btc)
+	}
+	if eth := got["ETHUSDT"]; len(eth) != 1 || eth[0].Action != ActionReduceShort || eth[0].DeltaSize != 3 || eth[0].NotionalUSD != 30 {
+		t.Fatalf("expected a partial short reduce, got %+v", eth)
+	}
+	if doge := got["DOGEUSDT"]; len(doge) != 1 || doge[0].Action != ActionOpenLong || doge[0].LeaderLeverage != 10 {
+		t.Fatalf("expected a new long, got %+v", doge)
+	}
+	sol := got["SOLUSDT"]
+	if len(sol) != 1 || sol[0].Action != ActionCloseLong || sol[0].Price != 5 || sol[0].NotionalUSD != 50 {
+		t.Fatalf("expected the vanished symbol closed, got %+v", sol)
+	}
+	if sol[0].LeaderLeverage != 2 || sol[0].MarginMode != "cross" || sol[0].LeaderEquity != 1000 {
+		t.Fatalf("a vanished symbol's close must carry the same fields as any close, got %+v", sol[0])
+	}
+}
+
+func TestRunCancelAbortsInFlightRequests(t *testing.T) {
+	started := make(chan struct{}, 1)
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})}
+	p, err := NewProvider(Config{Type: "hyperliquid", Identifier: "0x0000000000000000000000000000000000000002", HTTPClient: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx, make(chan Signal, 8)) }()
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the first request")
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected a clean stop, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancelling ctx must abort the in-flight request")
+	}
+}
+
+func TestErrorBackoffDoublesAndResets(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	start := clock.Now()
+	backoff := newErrorBackoff(Config{PollInterval: 3 * time.Second})
+	backoff.jitter = nil
+	failure := errors.New("429 Too Many Requests")
+
+	var waits []time.Duration
+	for i := 0; i < 3; i++ {
+		backoff.observe(failure)
+		wait := backoff.wait(3 * time.Second)
+		clock.Advance(wait)
+		waits = append(waits, wait)
+	}
+	if waits[0] != 6*time.Second || waits[1] != 12*time.Second || waits[2] != 24*time.Second {
+		t.Fatalf("expected the wait to double per failure, got %v", waits)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed != 42*time.Second {
+		t.Fatalf("expected 42s spent backing off, got %v", elapsed)
+	}
+	backoff.observe(failure)
+	backoff.observe(failure)
+	if wait := backoff.wait(3 * time.Second); wait != defaultMaxBackoff {
+		t.Fatalf("expected the wait capped at the 60s default, got %v", wait)
+	}
+
+	backoff.observe(nil)
+	if wait := backoff.wait(3 * time.Second); wait != 3*time.Second {
+		t.Fatalf("expected the poll interval after a success, got %v", wait)
+	}
+
+	for i := 0; i < 100; i++ {
+		if d := equalJitter(8 * time.Second); d < 4*time.Second || d > 8*time.Second {
+			t.Fatalf("jittered wait %v outside [4s, 8s]", d)
+		}
+	}
+}
+
+// serveFake exposes a fake venue client on a real HTTP server under /prefix.
+func serveFake(t *testing.T, fake *http.Client, prefix string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, prefix+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		resp, err := fake.Transport.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProvidersUseBaseURL(t *testing.T) {
+	hl := newHyperliquidFake()
+	hl.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"BTC","szi":"1","leverage":{"type":"cross","value":5}}}]}`)
+	server := serveFake(t, hl.client(), "/testnet")
+	p, err := NewProvider(Config{Type: "hyperliquid", Identifier: "0x0000000000000000000000000000000000000001",
+		BaseURL: server.URL + "/testnet/", RequestsPerSecond: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.(*hyperliquidProvider).fetchAndEmit(context.Background(), nil); err != nil {
+		t.Fatalf("hyperliquid against BaseURL: %v", err)
+	}
+	if p.(*hyperliquidProvider).positions()["BTCUSDT"].Size != 1 {
+		t.Fatal("expected the book fetched from BaseURL")
+	}
+
+	okx := newOKXFake()
+	server = serveFake(t, okx.client(), "")
+	p, err = NewProvider(Config{Type: "okx", Identifier: "leader", BaseURL: server.URL, RequestsPerSecond: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.(*okxProvider).fetchAndEmit(context.Background(), nil); err != nil {
+		t.Fatalf("okx against BaseURL: %v", err)
+	}
+	if okx.requestCount("asset") != 1 {
+		t.Fatal("expected OKX equity fetched from BaseURL")
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	okx := newOKXFake()
+	var good HealthChecker = newTestOKXProvider(okx, Config{})
+	if err := good.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("expected a known leader to pass, got %v", err)
+	}
+	if n := okx.requestCount("asset"); n != 1 {
+		t.Fatalf("expected a single lightweight fetch, got %d", n)
+	}
+
+	hl := newHyperliquidFake()
+	hl.set("clearinghouseState", `not json`)
+	var bad HealthChecker = newTestHyperliquidProvider(hl, Config{})
+	err := bad.HealthCheck(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "0x0000000000000000000000000000000000000001") {
+		t.Fatalf("expected the failing leader named in the error, got %v", err)
+	}
+
+	if err := newOKXProvider(Config{}).(HealthChecker).HealthCheck(context.Background()); err == nil {
+		t.Fatal("expected a missing identifier reported without a request")
+	}
+
+	composite := NewComposite([]Provider{good.(Provider), bad.(Provider)}, CompositeConfig{})
+	if err := composite.HealthCheck(context.Background()); err == nil || !strings.Contains(err.Error(), "child 1") {
+		t.Fatalf("expected the composite to report its failing child, got %v", err)
+	}
+}
+
+func TestLeaderErrorsAreTyped(t *testing.T) {
+	okx := newOKXFake()
+	p := newTestOKXProvider(okx, Config{})
+	for body, want := range map[string]error{
+		`{"code":"0","data":[]}`:                                 ErrLeaderNotFound,
+		`{"code":"0","data":[{"currency":"BTC","amount":"1"}]}`:  ErrNoEquity,
+		`{"code":"0","data":[{"currency":"USDT","amount":"0"}]}`: ErrNoEquity,
+	} {
+		okx.set("asset", body)
+		if err := p.fetchAndEmit(context.Background(), nil); !errors.Is(err, want) {
+			t.Errorf("okx %s: expected %v, got %v", body, want, err)
+		}
+	}
+	okx.set("asset", `{"code":"50011","msg":"too many requests","data":[]}`)
+	if err := p.fetchAndEmit(context.Background(), nil); err == nil || errors.Is(err, ErrLeaderNotFound) {
+		t.Errorf("an OKX error code must stay a plain, retryable error, got %v", err)
+	}
+
+	hlFake := newHyperliquidFake()
+	hlFake.set("clearinghouseState", `{"marginSummary":{"accountValue":"0"},"assetPositions":[]}`)
+	hl := newTestHyperliquidProvider(hlFake, Config{})
+	if err := hl.fetchAndEmit(context.Background(), nil); !errors.Is(err, ErrNoEquity) {
+		t.Errorf("hyperliquid empty account: expected ErrNoEquity, got %v", err)
+	}
+	hl.client = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusUnprocessableEntity, `"Failed to deserialize the JSON body"`), nil
+	})}
+	if _, err := hl.fetchState(context.Background()); !errors.Is(err, ErrLeaderNotFound) {
+		t.Errorf("hyperliquid malformed wallet: expected ErrLeaderNotFound, got %v", err)
+	}
+}
+
+func TestRunReportsErrorsWithoutBlocking(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("asset", `{"code":"0","data":[]}`)
+	errs := make(chan error, 1)
+	p := newTestOKXProvider(fake, Config{Errors: errs, SilenceLogs: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx, make(chan Signal, 8)) }()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrLeaderNotFound) || !strings.HasPrefix(err.Error(), p.stateKey()+": ") {
+			t.Fatalf("expected the poll error tagged with the provider, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the failed poll reported on Errors")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// nobody reading: the report is dropped instead of stalling the poll loop
+	reporter := newErrorReporter(Config{Errors: make(chan error), SilenceLogs: true}, "OKX")
+	reported := make(chan struct{})
+	go func() {
+		reporter.report("okx:leader", errors.New("boom"))
+		close(reported)
+	}()
+	select {
+	case <-reported:
+	case <-time.After(time.Second):
+		t.Fatal("report blocked on a full error channel")
+	}
+}
+
+func TestNormalizeMarginMode(t *testing.T) {
+	cases := map[string]string{
+		"cross":     "cross", // Hyperliquid leverage.type, OKX mgnMode
+		"isolated":  "isolated",
+		"Isolated":  "isolated",
+		" CROSS ":   "cross",
+		"cash":      "", // OKX spot without margin
+		"":          "",
+		"portfolio": "",
+	}
+	for raw, want := range cases {
+		if got := normalizeMarginMode(raw); got != want {
+			t.Errorf("normalizeMarginMode(%q) = %q, want %q", raw, got, want)
+		}
+	}
+
+	var raw hyperliquidStateRaw
+	if err := json.Unmarshal([]byte(`{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"ETH","szi":"1","leverage":{"type":"isolated","value":10,"rawUsd":"-900"}}}]}`), &raw); err != nil {
+		t.Fatal(err)
+	}
+	state, _ := raw.normalize()
+	okx := newTestOKXProvider(newOKXFake(), Config{})
+	_, meta, _ := okx.positionMeta(context.Background(), okxPositionEntry{InstID: "BTC-USDT-SWAP", MarginMode: "ISOLATED", PosSide: "long", Pos: "1", Lever: "10"}, "SWAP")
+	if state.Positions["ETHUSDT"].MarginMode != "isolated" || meta.MarginMode != "isolated" {
+		t.Fatalf("expected both venues to report isolated, got %q and %q", state.Positions["ETHUSDT"].MarginMode, meta.MarginMode)
+	}
+}
+
+// checkReadsWhileBlocked runs poll with no reader on out and checks that applied,
+// which reads the provider's state, reports the poll's change while the poll is
+// blocked delivering its signal: the provider must not hold its lock across the
+// send. Run with -race to check the locking itself.
+func checkReadsWhileBlocked(t *testing.T, out chan Signal, poll func() error, applied func() bool) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- poll() }()
+
+	seen := make(chan struct{})
+	go func() {
+		for !applied() {
+			time.Sleep(time.Millisecond)
+		}
+		close(seen)
+	}()
+	select {
+	case <-seen:
+	case <-time.After(time.Second):
+		t.Fatal("state unreadable while the poll waits on out")
+	}
+	<-out
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewProviderValidatesConfig(t *testing.T) {
+	const wallet = "0x0000000000000000000000000000000000000001"
+	cases := map[string]Config{
+		"empty identifier":       {Type: "okx"},
+		"blank identifier":       {Type: "bybit", Identifier: "  "},
+		"negative poll interval": {Type: "okx", Identifier: "leader", PollInterval: -time.Second},
+		"non-address wallet":     {Type: "hyperliquid", Identifier: "leader"},
+		"short wallet":           {Type: "hyperliquid_wallet", Identifier: wallet[:41]},
+		"non-hex wallet":         {Type: "hyperliquid", Identifier: "0x000000000000000000000000000000000000000g"},
+		"unsupported type":       {Type: "ftx", Identifier: "leader"},
+		"fake without provider":  {Type: "fake"},
+	}
+	for name, cfg := range cases {
+		if _, err := NewProvider(cfg); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: expected ErrInvalidConfig, got %v", name, err)
+		}
+	}
+
+	for _, cfg := range []Config{
+		{Type: "hyperliquid", Identifier: " " + wallet + " "},
+		{Type: "okx", Identifier: "leader"},
+		{Type: "fake", Fake: newScriptedChild(nil)},
+	} {
+		if _, err := NewProvider(cfg); err != nil {
+			t.Errorf("%+v: expected a valid config, got %v", cfg, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package copytrading
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultRequestsPerSecond is the per-provider request budget when
+// Config.RequestsPerSecond is unset.
+const defaultRequestsPerSecond = 5
+
+// RateLimiter is a token bucket spacing out requests to a venue. Providers given the
+// same limiter (Config.RateLimiter) share one budget, e.g. several leaders followed
+// on the same upstream. A nil limiter never blocks.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter allows rps requests per second on average and bursts of up to
+// burst (at least 1).
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rps, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Wait blocks until a request may be sent, or returns ctx's error if ctx is done
+// first.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		if !l.last.IsZero() {
+			l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		}
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rateLimiterOf returns the configured limiter, or a fresh one for this provider at
+// RequestsPerSecond (default 5). A negative RequestsPerSecond disables limiting.
+func rateLimiterOf(cfg Config) *RateLimiter {
+	if cfg.RateLimiter != nil {
+		return cfg.RateLimiter
+	}
+	rps := cfg.RequestsPerSecond
+	if rps < 0 {
+		return nil
+	}
+	if rps == 0 {
+		rps = defaultRequestsPerSecond
+	}
+	return NewRateLimiter(rps, int(math.Ceil(rps)))
+}
+
+// limitedClient returns a copy of client whose requests first wait on limiter.
+func limitedClient(client *http.Client, limiter *RateLimiter) *http.Client {
+	if limiter == nil {
+		return client
+	}
+	limited := *client
+	limited.Transport = &limitedTransport{base: client.Transport, limiter: limiter}
+	return &limited
+}
+
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *RateLimiter
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
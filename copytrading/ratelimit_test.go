@@ -0,0 +1,56 @@
+package copytrading
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSpacesRequests(t *testing.T) {
+	limiter := NewRateLimiter(20, 1)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first request spends the burst, the next two wait ~50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("expected requests spaced at 20 rps, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewRateLimiter(0.001, 1).Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("a cancelled ctx must fail without spending the budget, got %v", err)
+	}
+	if err := (*RateLimiter)(nil).Wait(context.Background()); err != nil {
+		t.Fatalf("a nil limiter never blocks, got %v", err)
+	}
+}
+
+func TestProvidersShareRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 1)
+	fake := newOKXFake()
+	newProvider := func(identifier string) *okxProvider {
+		p, err := NewProvider(Config{Type: "okx", Identifier: identifier, HTTPClient: fake.client(), RateLimiter: limiter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.(*okxProvider)
+	}
+	first, second := newProvider("a"), newProvider("b")
+
+	if _, err := first.fetchEquity(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := second.fetchEquity(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the second provider to wait on the shared budget, got %v", err)
+	}
+	if n := fake.requestCount("asset"); n != 1 {
+		t.Fatalf("expected one request through the shared limiter, got %d", n)
+	}
+}
@@ -0,0 +1,142 @@
+package copytrading
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DuplicatePolicy decides what happens when a second provider starts watching a leader
+// that is already being watched in this process.
+type DuplicatePolicy string
+
+const (
+	DuplicateError DuplicatePolicy = "error" // default: refuse to start the second provider
+	DuplicateDedup DuplicatePolicy = "dedup" // run both, but emit each signal only once
+)
+
+// ErrDuplicateProvider is returned by Run when the leader is already watched and the
+// policy is DuplicateError.
+var ErrDuplicateProvider = errors.New("copytrading: leader already watched by another provider")
+
+// dedupWindow bounds how long an emitted signal suppresses its duplicates.
+const dedupWindow = 5 * time.Minute
+
+// watchRegistry tracks which leader identities are being watched in this process.
+type watchRegistry struct {
+	mu      sync.Mutex
+	entries map[string]*watchEntry
+}
+
+type watchEntry struct {
+	providers int
+	dedup     bool
+	seen      map[string]time.Time
+}
+
+var defaultWatchRegistry = &watchRegistry{entries: make(map[string]*watchEntry)}
+
+// watchKey identifies a leader stream. Providers whose follow mode differs (see
+// streamVariant) emit different streams and are not duplicates of each other.
+func watchKey(venue, identifier, variant string) string {
+	return leaderKey(venue, identifier) + "|" + variant
+}
+
+// leaderKey identifies a leader as "venue:identifier". Hyperliquid addresses are
+// case-insensitive, so they are lowercased.
+func leaderKey(venue, identifier string) string {
+	if venue == "hyperliquid" {
+		identifier = strings.ToLower(identifier)
+	}
+	return stateKey(venue, identifier)
+}
+
+// streamVariant describes the follow mode shaping a provider's signal stream.
+func streamVariant(cfg Config) string {
+	switch cfg.Mode {
+	case ModeNet:
+		return fmt.Sprintf("%s:%g", ModeNet, rebalanceThreshold(cfg))
+	case "":
+		return ModeTrade
+	}
+	return cfg.Mode
+}
+
+// register claims key for a starting provider. The returned handle must be released
+// when the provider stops.
+func (r *watchRegistry) register(key string, policy DuplicatePolicy) (*watchHandle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, watched := r.entries[key]
+	if watched && policy != DuplicateDedup {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateProvider, key)
+	}
+	if !watched {
+		entry = &watchEntry{seen: make(map[string]time.Time)}
+		r.entries[key] = entry
+	}
+	entry.providers++
+	if watched {
+		entry.dedup = true
+	}
+	return &watchHandle{registry: r, key: key, entry: entry}, nil
+}
+
+// watchHandle is a provider's claim on a watched identity.
+type watchHandle struct {
+	registry *watchRegistry
+	key      string
+	entry    *watchEntry
+	once     sync.Once
+}
+
+// admit reports whether sig should be emitted. Once a duplicate provider joined with
+// DuplicateDedup, only the first provider to emit a given signal delivers it.
+func (h *watchHandle) admit(sig Signal, now time.Time) bool {
+	if h == nil {
+		return true
+	}
+	h.registry.mu.Lock()
+	defer h.registry.mu.Unlock()
+
+	if !h.entry.dedup {
+		return true
+	}
+	for key, at := range h.entry.seen {
+		if now.Sub(at) > dedupWindow {
+			delete(h.entry.seen, key)
+		}
+	}
+	key := signalDedupKey(sig)
+	if _, dup := h.entry.seen[key]; dup {
+		return false
+	}
+	h.entry.seen[key] = now
+	return true
+}
+
+func (h *watchHandle) release() {
+	if h == nil {
+		return
+	}
+	h.once.Do(func() {
+		h.registry.mu.Lock()
+		defer h.registry.mu.Unlock()
+		if h.entry.providers--; h.entry.providers <= 0 && h.registry.entries[h.key] == h.entry {
+			delete(h.registry.entries, h.key)
+		}
+	})
+}
+
+// signalDedupKey identifies the same leader change observed by different providers.
+// It is the signal's DedupKey, which carries the fill time (or entry prices), so a
+// leader repeating the same sizes within dedupWindow is not mistaken for a duplicate.
+func signalDedupKey(sig Signal) string {
+	if sig.DedupKey != "" {
+		return sig.DedupKey
+	}
+	return fmt.Sprintf("%s|%s|%g|%g", sig.Symbol, sig.Action, sig.LeaderPosBefore, sig.LeaderPosAfter)
+}
@@ -0,0 +1,121 @@
+package copytrading
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDuplicateProviderRefusedByDefault(t *testing.T) {
+	fake := newOKXFake()
+	first := newTestOKXProvider(fake, Config{})
+	second := newTestOKXProvider(fake, Config{})
+
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- RunUntil(first, stop, make(chan Signal, 8)) }()
+	<-first.Ready()
+
+	closed := make(chan struct{})
+	close(closed)
+	if err := RunUntil(second, closed, make(chan Signal, 8)); !errors.Is(err, ErrDuplicateProvider) {
+		t.Fatalf("expected duplicate provider error, got %v", err)
+	}
+
+	// a different follow mode is a different stream, not a duplicate
+	net := newTestOKXProvider(fake, Config{Mode: ModeNet})
+	if err := RunUntil(net, closed, make(chan Signal, 8)); err != nil {
+		t.Fatalf("net-mode provider must not clash with trade mode: %v", err)
+	}
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// the identity is free again once the first provider stopped
+	if err := RunUntil(second, closed, make(chan Signal, 8)); err != nil {
+		t.Fatalf("expected identity released after stop, got %v", err)
+	}
+}
+
+func TestDuplicateProviderDedup(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	cfg := Config{OnDuplicate: DuplicateDedup, PollInterval: 10 * time.Millisecond}
+	first := newTestOKXProvider(fake, cfg)
+	second := newTestOKXProvider(fake, cfg)
+
+	stop := make(chan struct{})
+	out := make(chan Signal, 8)
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for _, p := range []*okxProvider{first, second} {
+		wg.Add(1)
+		go func(p *okxProvider) {
+			defer wg.Done()
+			errs <- RunUntil(p, stop, out)
+		}(p)
+	}
+	defer func() {
+		// both must have released the identity before the next test runs
+		close(stop)
+		wg.Wait()
+	}()
+	<-first.Ready()
+	<-second.Ready()
+
+	fake.set("position-current", okxPositions(`{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}`))
+	select {
+	case sig := <-out:
+		if sig.Action != ActionOpenLong {
+			t.Fatalf("unexpected signal: %+v", sig)
+		}
+	case err := <-errs:
+		t.Fatalf("dedup policy must let the duplicate run: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the open")
+	}
+
+	select {
+	case sig := <-out:
+		t.Fatalf("duplicate signal emitted: %+v", sig)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDuplicateDedupKeepsRepeatedRoundTrips(t *testing.T) {
+	registry := &watchRegistry{entries: make(map[string]*watchEntry)}
+	first, err := registry.register("okx:leader|trade", DuplicateDedup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.release()
+	second, err := registry.register("okx:leader|trade", DuplicateDedup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.release()
+
+	// open → close → reopen → close with identical sizes, all within dedupWindow
+	start := time.Unix(1700000000, 0)
+	var trips []Signal
+	for i, action := range []SignalAction{ActionOpenLong, ActionCloseLong, ActionOpenLong, ActionCloseLong} {
+		sig := Signal{Symbol: "BTCUSDT", Action: action, DeltaSize: 1, LeaderPosAfter: 1}
+		if action == ActionCloseLong {
+			sig.DeltaSize, sig.LeaderPosBefore, sig.LeaderPosAfter = -1, 1, 0
+		}
+		sig.DedupKey = signalKey(sig, nil, nil, start.Add(time.Duration(i)*time.Minute), true)
+		trips = append(trips, sig)
+	}
+
+	now := start.Add(4 * time.Minute)
+	for i, sig := range trips {
+		if !first.admit(sig, now) {
+			t.Fatalf("signal %d (%s) of the repeated round trip was dropped as a duplicate", i, sig.Action)
+		}
+		if second.admit(sig, now) {
+			t.Fatalf("signal %d (%s) seen by the second provider must be dropped", i, sig.Action)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package copytrading
+
+import (
+	"math"
+)
+
+// defaultShadowTolerance is the relative size difference shadow mode accepts.
+const defaultShadowTolerance = 0.01
+
+// Divergence reports a symbol whose position implied by the emitted signals no longer
+// matches the leader's actual position.
+type Divergence struct {
+	Symbol  string
+	Implied float64 // signed size implied by the cumulative emitted DeltaSize
+	Actual  float64 // signed size in the freshly fetched leader book
+}
+
+// shadowMonitor replays the emitted signal stream into an implied book and compares
+// it to the leader's real book on every poll, surfacing drift such as dropped signals
+// or pricing skips. Sampled and ModeNet providers drift by design within their window.
+type shadowMonitor struct {
+	enabled   bool
+	tolerance float64
+	report    func(Divergence)
+	logger    Logger
+
+	seeded  bool
+	implied map[string]float64
+}
+
+func newShadowMonitor(cfg Config) *shadowMonitor {
+	tolerance := cfg.ShadowTolerance
+	if tolerance <= 0 {
+		tolerance = defaultShadowTolerance
+	}
+	return &shadowMonitor{
+		enabled:   cfg.Shadow,
+		tolerance: tolerance,
+		report:    cfg.OnDivergence,
+		logger:    loggerOf(cfg.Logger),
+		implied:   make(map[string]float64),
+	}
+}
+
+// record applies an emitted signal to the implied book.
+func (m *shadowMonitor) record(sig Signal) {
+	if !m.enabled || !m.seeded {
+		return
+	}
+	m.implied[sig.Symbol] += sig.DeltaSize
+}
+
+// compare checks the implied book against the leader's actual positions. The first
+// call only adopts the actual book as the baseline.
+func (m *shadowMonitor) compare(actual map[string]PositionMeta) []Divergence {
+	if !m.enabled {
+		return nil
+	}
+	if !m.seeded {
+		for sym, meta := range actual {
+			m.implied[sym] = meta.Size
+		}
+		m.seeded = true
+		return nil
+	}
+
+	symbols := make(map[string]struct{}, len(actual)+len(m.implied))
+	for sym := range actual {
+		symbols[sym] = struct{}{}
+	}
+	for sym := range m.implied {
+		symbols[sym] = struct{}{}
+	}
+
+	var divergences []Divergence
+	for sym := range symbols {
+		implied, real := m.implied[sym], actual[sym].Size
+		scale := math.Max(math.Abs(implied), math.Abs(real))
+		if scale == 0 || math.Abs(implied-real) <= m.tolerance*scale {
+			continue
+		}
+		d := Divergence{Symbol: sym, Implied: implied, Actual: real}
+		divergences = append(divergences, d)
+		loggerOf(m.logger).Warnf("🔍 shadow divergence %s: implied=%.6f actual=%.6f", sym, implied, real)
+		if m.report != nil {
+			m.report(d)
+		}
+	}
+	return divergences
+}
@@ -0,0 +1,51 @@
+package copytrading
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestShadowReportsDroppedSignal(t *testing.T) {
+	var reported []Divergence
+	cfg := Config{Shadow: true, OnDivergence: func(d Divergence) { reported = append(reported, d) }}
+	tr, clock := newTestTracker(cfg)
+	shadow := newShadowMonitor(cfg)
+
+	poll := func(sizes map[string]float64, drop string) []Divergence {
+		clock.Advance(10 * time.Second)
+		snapshot := book(sizes)
+		for _, sig := range tr.update(snapshot, 1000) {
+			if sig.Symbol == drop {
+				continue // lost between the diff engine and the consumer
+			}
+			shadow.record(sig)
+		}
+		return shadow.compare(snapshot)
+	}
+
+	poll(map[string]float64{"BTCUSDT": 1}, "")
+	if d := poll(map[string]float64{"BTCUSDT": 2, "ETHUSDT": -3}, ""); len(d) != 0 {
+		t.Fatalf("faithful stream must not diverge: %+v", d)
+	}
+	if d := poll(map[string]float64{"BTCUSDT": 2.5, "ETHUSDT": -1}, "ETHUSDT"); len(d) != 1 {
+		t.Fatalf("expected the dropped ETH reduce to surface, got %+v", d)
+	}
+	if len(reported) != 1 || reported[0].Symbol != "ETHUSDT" ||
+		math.Abs(reported[0].Implied+3) > 1e-9 || math.Abs(reported[0].Actual+1) > 1e-9 {
+		t.Fatalf("unexpected divergence report: %+v", reported)
+	}
+
+	// the drift persists until the stream catches up
+	if d := poll(map[string]float64{"BTCUSDT": 2.5, "ETHUSDT": -1}, ""); len(d) != 1 {
+		t.Fatalf("expected divergence to persist, got %+v", d)
+	}
+}
+
+func TestShadowDisabledByDefault(t *testing.T) {
+	shadow := newShadowMonitor(Config{})
+	shadow.compare(book(map[string]float64{"BTCUSDT": 1}))
+	if d := shadow.compare(book(map[string]float64{"BTCUSDT": 5})); d != nil {
+		t.Fatalf("shadow mode must be opt-in, got %+v", d)
+	}
+}
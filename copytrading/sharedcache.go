@@ -0,0 +1,96 @@
+package copytrading
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultSharedCacheTTL = 2 * time.Second
+
+// SharedCache deduplicates public lookups (market data, instrument specs) across
+// providers of the same venue within a short TTL. Keys are scoped by venue, and only
+// public data goes through it: a leader's positions, fills and equity are always
+// fetched per provider.
+type SharedCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[string]*sharedEntry
+}
+
+type sharedEntry struct {
+	mu        sync.Mutex // serializes fetches so concurrent callers share one request
+	value     any
+	fetchedAt time.Time
+	ok        bool
+}
+
+// DefaultSharedCache is used by providers whose Config.SharedCache is nil.
+var DefaultSharedCache = NewSharedCache(defaultSharedCacheTTL, nil)
+
+// NewSharedCache builds a cache whose entries stay fresh for ttl (default 2s).
+func NewSharedCache(ttl time.Duration, clock Clock) *SharedCache {
+	if ttl <= 0 {
+		ttl = defaultSharedCacheTTL
+	}
+	return &SharedCache{ttl: ttl, clock: clockOf(clock), entries: make(map[string]*sharedEntry)}
+}
+
+func sharedCacheOf(cfg Config) *SharedCache {
+	if cfg.SharedCache != nil {
+		return cfg.SharedCache
+	}
+	return DefaultSharedCache
+}
+
+// get returns the cached value for venue/key or calls fetch. Errors are not cached.
+func (c *SharedCache) get(venue, key string, fetch func() (any, error)) (any, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[venue+"|"+key]
+	if !ok {
+		entry = &sharedEntry{}
+		c.entries[venue+"|"+key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	now := c.clock.Now()
+	if entry.ok && now.Sub(entry.fetchedAt) < c.ttl {
+		return entry.value, nil
+	}
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	entry.value, entry.fetchedAt, entry.ok = value, now, true
+	return value, nil
+}
+
+// marketQuote returns a venue-scoped, cached market quote lookup.
+func (c *SharedCache) marketQuote(venue string) func(string) (float64, time.Time, error) {
+	type quote struct {
+		price float64
+		at    time.Time
+	}
+	return func(symbol string) (float64, time.Time, error) {
+		value, err := c.get(venue, "market:"+symbol, func() (any, error) {
+			price, at, err := marketQuote(symbol)
+			return quote{price, at}, err
+		})
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		q := value.(quote)
+		return q.price, q.at, nil
+	}
+}
+
+// newVenueTracker builds a tracker whose market data fallback is shared by every
+// provider of venue.
+func newVenueTracker(cfg Config, venue string) *positionTracker {
+	t := newPositionTracker(cfg)
+	t.marketPrice = freshPrice(sharedCacheOf(cfg).marketQuote(venue), cfg.MaxPriceAge, t.now)
+	return t
+}
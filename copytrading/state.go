@@ -0,0 +1,149 @@
+package copytrading
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStateNotFound is returned by a StateStore when no state was saved for a key.
+var ErrStateNotFound = errors.New("provider state not found")
+
+// ProviderState is the resumable part of a provider: its fill cursor and the
+// mirrored leader book.
+type ProviderState struct {
+	LastTID      int64                   `json:"last_tid,omitempty"`       // Hyperliquid fill cursor
+	LastFillTime int64                   `json:"last_fill_time,omitempty"` // OKX fill cursor (epoch ms)
+	Positions    map[string]PositionMeta `json:"positions"`
+	Prices       map[string]float64      `json:"prices"`
+	SavedAt      time.Time               `json:"saved_at"`
+}
+
+// StateStore persists provider state across restarts.
+type StateStore interface {
+	Load(providerKey string) (ProviderState, error)
+	Save(providerKey string, s ProviderState) error
+}
+
+// FileStateStore keeps each provider's state in its own JSON file under a
+// directory, so a restarted bot resumes where it stopped.
+type FileStateStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStateStore stores state under dir, creating it if needed.
+func NewFileStateStore(dir string) (*FileStateStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStateStore{dir: dir}, nil
+}
+
+func (f *FileStateStore) path(providerKey string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, providerKey)
+	return filepath.Join(f.dir, name+".json")
+}
+
+func (f *FileStateStore) Load(providerKey string) (ProviderState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := os.ReadFile(f.path(providerKey))
+	if errors.Is(err, os.ErrNotExist) {
+		return ProviderState{}, ErrStateNotFound
+	}
+	if err != nil {
+		return ProviderState{}, err
+	}
+	var s ProviderState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return ProviderState{}, fmt.Errorf("corrupt state file: %w", err)
+	}
+	return s, nil
+}
+
+// Save writes through a temporary file and a rename, so a crash mid-write never
+// leaves a truncated state behind.
+func (f *FileStateStore) Save(providerKey string, s ProviderState) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := f.path(providerKey)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// stateKey identifies a provider's persisted state.
+func stateKey(venue, identifier string) string {
+	return fmt.Sprintf("%s:%s", venue, identifier)
+}
+
+// saveState writes state to the store. A failing (or panicking) store is logged and
+// never takes the provider down.
+func saveState(store StateStore, logger Logger, key string, s ProviderState) {
+	if store == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			loggerOf(logger).Errorf("⚠️  copytrading state save panicked [%s]: %v", key, r)
+		}
+	}()
+	if err := store.Save(key, s); err != nil {
+		loggerOf(logger).Warnf("⚠️  copytrading state save failed [%s]: %v", key, err)
+	}
+}
+
+// loadState reads state from the store, reporting ok=false when nothing usable exists.
+func loadState(store StateStore, logger Logger, key string) (ProviderState, bool) {
+	if store == nil {
+		return ProviderState{}, false
+	}
+	s, err := store.Load(key)
+	if err != nil {
+		if !errors.Is(err, ErrStateNotFound) {
+			loggerOf(logger).Warnf("⚠️  copytrading state load failed [%s]: %v", key, err)
+		}
+		return ProviderState{}, false
+	}
+	return s, true
+}
+
+// exportState copies the tracker's book into s.
+func (t *positionTracker) exportState(s *ProviderState) {
+	s.Positions = copyPositions(t.lastPositions)
+	s.Prices = make(map[string]float64, len(t.lastPrices))
+	for sym, price := range t.lastPrices {
+		s.Prices[sym] = price
+	}
+}
+
+// restoreState resumes the tracker from a saved book. The first poll afterwards
+// either adopts the leader's current book silently or, with CatchUp, emits the
+// changes missed while offline.
+func (t *positionTracker) restoreState(s ProviderState) {
+	t.lastPositions = copyPositions(s.Positions)
+	for sym, price := range s.Prices {
+		t.recordPrice(sym, price)
+	}
+	t.lastSampleAt = t.now()
+	t.initialized = true
+	t.restored = true
+}
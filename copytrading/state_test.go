@@ -0,0 +1,180 @@
+package copytrading
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]ProviderState
+	saves  int
+}
+
+func newMemoryStateStore() *memoryStateStore {
+	return &memoryStateStore{states: make(map[string]ProviderState)}
+}
+
+func (m *memoryStateStore) Load(key string) (ProviderState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[key]
+	if !ok {
+		return ProviderState{}, ErrStateNotFound
+	}
+	return s, nil
+}
+
+func (m *memoryStateStore) Save(key string, s ProviderState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[key] = s
+	m.saves++
+	return nil
+}
+
+type failingStateStore struct{}
+
+func (failingStateStore) Load(string) (ProviderState, error) {
+	return ProviderState{}, ErrStateNotFound
+}
+func (failingStateStore) Save(string, ProviderState) error { return errors.New("disk full") }
+
+func TestRunFlushesStateOnShutdownAndResumes(t *testing.T) {
+	fake := newHyperliquidFake()
+	fake.set("userFills", `[{"coin":"BTC","px":"100","sz":"1","time":1700000000000,"tid":42}]`)
+	fake.set("clearinghouseState", `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"BTC","szi":"1.5","leverage":{"type":"cross","value":5}}}]}`)
+
+	store := newMemoryStateStore()
+	stop := make(chan struct{})
+	close(stop)
+
+	first := newTestHyperliquidProvider(fake, Config{StateStore: store})
+	if err := RunUntil(first, stop, make(chan Signal, 8)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	saved, err := store.Load(first.stateKey())
+	if err != nil {
+		t.Fatalf("expected state flushed on shutdown: %v", err)
+	}
+	if saved.LastTID != 42 || saved.Positions["BTCUSDT"].Size != 1.5 || saved.Prices["BTCUSDT"] != 100 {
+		t.Fatalf("unexpected saved state: %+v", saved)
+	}
+
+	second := newTestHyperliquidProvider(fake, Config{StateStore: store})
+	second.loadState()
+	if second.lastTID != 42 || !second.tracker.initialized {
+		t.Fatalf("expected cursor restored, got tid=%d initialized=%v", second.lastTID, second.tracker.initialized)
+	}
+	if meta, ok := second.tracker.position("BTCUSDT"); !ok || meta.Size != 1.5 {
+		t.Fatalf("expected book restored, got %+v", meta)
+	}
+}
+
+func TestRunSurvivesFailingStateStore(t *testing.T) {
+	fake := newOKXFake()
+	stop := make(chan struct{})
+	close(stop)
+
+	p := newTestOKXProvider(fake, Config{StateStore: failingStateStore{}})
+	if err := RunUntil(p, stop, make(chan Signal, 8)); err != nil {
+		t.Fatalf("a failed save must not fail Run: %v", err)
+	}
+}
+
+func TestFileStateStoreRoundTrip(t *testing.T) {
+	store, err := NewFileStateStore(filepath.Join(t.TempDir(), "state"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("okx:leader"); !errors.Is(err, ErrStateNotFound) {
+		t.Fatalf("expected ErrStateNotFound for a fresh store, got %v", err)
+	}
+
+	want := ProviderState{
+		LastFillTime: 1700000000000,
+		Positions:    map[string]PositionMeta{"BTCUSDT": {Size: -1.5, Leverage: 5, MarginMode: "cross"}},
+		Prices:       map[string]float64{"BTCUSDT": 100},
+		SavedAt:      time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := store.Save("okx:leader", want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Load("okx:leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("state changed across the file:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestRunPersistsStateEveryPoll(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	store := newMemoryStateStore()
+	p := newTestOKXProvider(fake, Config{StateStore: store})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx, make(chan Signal, 8)) }()
+	<-p.Ready()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if s, err := store.Load(p.stateKey()); err == nil && s.LastFillTime == 1700000000000 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the state saved after the first poll, before shutdown")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCatchUpEmitsFlipMissedWhileDown(t *testing.T) {
+	// we went down holding the leader's BTC long; the leader flipped short meanwhile
+	store := newMemoryStateStore()
+	store.states[stateKey("bybit", "leader")] = ProviderState{
+		Positions: map[string]PositionMeta{"BTCUSDT": {Size: 1, Leverage: 5, MarginMode: "cross"}},
+		Prices:    map[string]float64{"BTCUSDT": 100},
+	}
+	fake := newBybitFake()
+	fake.set("list", bybitPositions(`{"symbol":"BTCUSDT","side":"Sell","sizeX":"200000000","leverageE2":"500","entryPrice":"95"}`))
+
+	p := newTestBybitProvider(fake, Config{StateStore: store, CatchUp: true})
+	p.loadState()
+	out := make(chan Signal, 8)
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected the flip caught up as a close and an open, got %d signals", len(out))
+	}
+	if sig := <-out; sig.Action != ActionCloseLong || sig.LeaderPosBefore != 1 || sig.Price != 95 {
+		t.Fatalf("expected the long closed at the current price, got %+v", sig)
+	}
+	if sig := <-out; sig.Action != ActionOpenShort || sig.LeaderPosAfter != -2 || sig.Price != 95 {
+		t.Fatalf("expected the short opened, got %+v", sig)
+	}
+
+	// without CatchUp the current book is adopted silently
+	p = newTestBybitProvider(fake, Config{StateStore: store})
+	p.loadState()
+	if err := p.fetchAndEmit(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 || p.positions()["BTCUSDT"].Size != -2 {
+		t.Fatalf("expected a silent re-seed, got %d signals and %+v", len(out), p.positions())
+	}
+}
@@ -0,0 +1,93 @@
+package copytrading
+
+import (
+	"math"
+	"time"
+)
+
+// defaultHistoryLookback is how far back Config.ImportHistory reaches by default.
+const defaultHistoryLookback = 7 * 24 * time.Hour
+
+// LeaderStats summarizes a leader's closed round trips.
+type LeaderStats struct {
+	ClosedTrades   int
+	Wins           int
+	WinRate        float64 // Wins / ClosedTrades
+	RealizedPnLUSD float64
+	AvgHoldTime    time.Duration
+}
+
+// StatsReporter is implemented by providers that track the leader's realized results.
+type StatsReporter interface {
+	Stats() LeaderStats
+}
+
+// statsFill is a venue-neutral fill used for statistics.
+type statsFill struct {
+	TID           int64
+	Time          time.Time
+	StartPosition float64 // signed position before the fill
+	Delta         float64 // signed size change
+	ClosedPnL     float64
+}
+
+// statsTracker accumulates round trips per symbol. A trade closes when the position
+// returns to flat or flips; partial closes contribute their PnL to that trade.
+type statsTracker struct {
+	cursor   int64 // last fill id folded in, so imported history isn't counted twice
+	imported bool
+
+	closed    int
+	wins      int
+	pnl       float64
+	holdTotal time.Duration
+	openedAt  map[string]time.Time
+	tradePnL  map[string]float64
+}
+
+func newStatsTracker() *statsTracker {
+	return &statsTracker{openedAt: make(map[string]time.Time), tradePnL: make(map[string]float64)}
+}
+
+func (s *statsTracker) record(symbol string, fill statsFill) {
+	if fill.TID != 0 && fill.TID <= s.cursor {
+		return
+	}
+	if fill.TID > s.cursor {
+		s.cursor = fill.TID
+	}
+
+	before := fill.StartPosition
+	after := before + fill.Delta
+	if math.Abs(after) < 1e-12 {
+		after = 0
+	}
+
+	s.pnl += fill.ClosedPnL
+	s.tradePnL[symbol] += fill.ClosedPnL
+
+	flipped := before != 0 && after != 0 && (before > 0) != (after > 0)
+	if before != 0 && (after == 0 || flipped) {
+		s.closed++
+		if s.tradePnL[symbol] > 0 {
+			s.wins++
+		}
+		if opened, ok := s.openedAt[symbol]; ok {
+			s.holdTotal += fill.Time.Sub(opened)
+		}
+		delete(s.openedAt, symbol)
+		delete(s.tradePnL, symbol)
+	}
+	if after != 0 && (before == 0 || flipped) {
+		s.openedAt[symbol] = fill.Time
+	}
+}
+
+func (s *statsTracker) snapshot() LeaderStats {
+	stats := LeaderStats{ClosedTrades: s.closed, Wins: s.wins, RealizedPnLUSD: s.pnl}
+	if s.closed > 0 {
+		stats.WinRate = float64(s.wins) / float64(s.closed)
+		stats.AvgHoldTime = s.holdTotal / time.Duration(s.closed)
+	}
+	return stats
+}
@@ -0,0 +1,49 @@
+package copytrading
+
+import (
+	"context"
+	"sync"
+)
+
+// stopSignal backs Stopper. Run checks it between polls, so a stop never interrupts
+// a poll half way through.
+type stopSignal struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+func newStopSignal() *stopSignal {
+	return &stopSignal{ch: make(chan struct{})}
+}
+
+// stop closes the signal; later calls are no-ops.
+func (s *stopSignal) stop() {
+	s.once.Do(func() { close(s.ch) })
+}
+
+func (s *stopSignal) done() <-chan struct{} {
+	return s.ch
+}
+
+func (s *stopSignal) stopped() bool {
+	select {
+	case <-s.ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// context returns ctx also cancelled by stop, for streaming transports, which have no
+// poll boundary to stop at and finish the message in hand on cancellation.
+func (s *stopSignal) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-s.ch:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,880 @@
+package copytrading
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// positionTracker keeps the mirrored view of a leader's book and turns fresh
+// snapshots into signals. It is shared by every provider so they emit the same
+// signal shapes.
+type positionTracker struct {
+	sampleInterval time.Duration
+	dailyAt        time.Duration // ModeDaily rebalance offset from 00:00 UTC
+	daily          bool
+	promptCloses   bool
+	emitTargets    bool
+	coalesceFlips  bool
+	replicate      bool // ModeReplicate
+	vwap           bool
+	reentryWindow  time.Duration
+	rebalanceMin   float64 // ModeNet minimum relative change
+	blend          *BlendedPriceOracle
+	seedFromEntry  bool
+	volatility     *volatilityGuard
+	maxLatency     time.Duration
+	dropLateOpens  bool
+	confirmSides   bool // hold snapshot moves that contradict this poll's fills
+	maxOpens       int  // MaxOpensPerCycle
+	catchUp        bool // emit the delta missed while offline after a restore
+	allowedLev     func(symbol string) (int, bool)
+	now            func() time.Time
+	marketPrice    func(symbol string) (float64, error) // market data fallback
+	logger         Logger
+
+	initialized   bool
+	restored      bool                    // the book came from a StateStore and has not been polled yet
+	ready         chan struct{}           // closed on the first successful update
+	lastPositions map[string]PositionMeta // last mirrored book
+	lastPrices    map[string]float64      // last seen fill price per symbol
+	lastLeverage  map[string]int          // last leverage seen on an open position per symbol
+	lastMargin    map[string]string       // last margin mode seen on an open position per symbol
+	marketPriced  map[string]bool         // lastPrices entries that came from market data
+	lastSampleAt  time.Time
+	lastEquity    float64              // leader equity on the last poll
+	closedAt      map[string]time.Time // when the leader last went flat per symbol
+	heldConflicts map[string]bool      // symbols held for confirmation on the last poll
+	openQueue     []string             // opens deferred by maxOpens, oldest first
+
+	cycleNotional map[string]float64 // VWAP accumulators for the current poll's fills
+	cycleVolume   map[string]float64
+	cycleFillAt   map[string]time.Time  // oldest new fill per symbol in the current poll
+	cycleLiq      map[string]bool       // symbols with a liquidation fill in the current poll
+	cycleSide     map[string]int        // +1 buys, -1 sells, 0 both ways in the current poll
+	cycleClose    map[string]closeFills // closing fills per symbol in the current poll
+}
+
+// closeFills accumulates a poll's closing fills on one symbol.
+type closeFills struct {
+	notional float64
+	volume   float64
+	pnl      float64
+}
+
+func newPositionTracker(cfg Config) *positionTracker {
+	if cfg.Mode == ModeNet && cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = defaultNetSampleInterval
+	}
+	return &positionTracker{
+		sampleInterval: cfg.SampleInterval,
+		daily:          cfg.Mode == ModeDaily,
+		dailyAt:        cfg.RebalanceTimeOfDay,
+		promptCloses:   cfg.SampleClosesImmediately,
+		emitTargets:    cfg.EmitTargets,
+		coalesceFlips:  cfg.CoalesceFlips,
+		replicate:      cfg.Mode == ModeReplicate,
+		vwap:           cfg.PriceVWAP,
+		reentryWindow:  cfg.ReentryWindow,
+		rebalanceMin:   rebalanceThreshold(cfg),
+		blend:          cfg.BlendedPrice,
+		seedFromEntry:  cfg.SeedPricesFromEntry,
+		volatility:     newVolatilityGuard(cfg),
+		maxLatency:     cfg.MaxFillLatency,
+		dropLateOpens:  cfg.SuppressLateOpens,
+		confirmSides:   cfg.ConfirmDirectionConflicts,
+		maxOpens:       cfg.MaxOpensPerCycle,
+		catchUp:        cfg.CatchUp,
+		allowedLev:     cfg.AllowedLeverage,
+		now:            clockOf(cfg.Clock).Now,
+		marketPrice:    freshPrice(func(symbol string) (float64, time.Time, error) { return marketQuote(symbol) }, cfg.MaxPriceAge, clockOf(cfg.Clock).Now),
+		logger:         loggerOf(cfg.Logger),
+		ready:          make(chan struct{}),
+		lastPositions:  make(map[string]PositionMeta),
+		lastPrices:     make(map[string]float64),
+		lastLeverage:   make(map[string]int),
+		lastMargin:     make(map[string]string),
+		marketPriced:   make(map[string]bool),
+		closedAt:       make(map[string]time.Time),
+		cycleNotional:  make(map[string]float64),
+		cycleVolume:    make(map[string]float64),
+		cycleFillAt:    make(map[string]time.Time),
+		cycleLiq:       make(map[string]bool),
+		cycleSide:      make(map[string]int),
+		cycleClose:     make(map[string]closeFills),
+	}
+}
+
+// recordPrice remembers the latest leader fill price for a symbol.
+func (t *positionTracker) recordPrice(symbol string, price float64) {
+	if symbol == "" || price <= 0 {
+		return
+	}
+	t.lastPrices[symbol] = price
+	delete(t.marketPriced, symbol)
+	t.volatility.observe(symbol, price, t.now())
+}
+
+// recordFill remembers a new fill's price and exchange time. With VWAP pricing, fills
+// within one poll are averaged by size; a fill without a usable size falls back to
+// its own price.
+func (t *positionTracker) recordFill(symbol string, price, size float64, at time.Time) {
+	if symbol != "" && !at.IsZero() {
+		if first, ok := t.cycleFillAt[symbol]; !ok || at.Before(first) {
+			t.cycleFillAt[symbol] = at
+		}
+	}
+	size = math.Abs(size)
+	if !t.vwap || size <= 0 || price <= 0 || symbol == "" {
+		t.recordPrice(symbol, price)
+		return
+	}
+	t.cycleNotional[symbol] += price * size
+	t.cycleVolume[symbol] += size
+	t.lastPrices[symbol] = t.cycleNotional[symbol] / t.cycleVolume[symbol]
+	delete(t.marketPriced, symbol)
+	t.volatility.observe(symbol, price, t.now())
+}
+
+// position returns the mirrored position for a symbol, if any.
+func (t *positionTracker) position(symbol string) (PositionMeta, bool) {
+	meta, ok := t.lastPositions[symbol]
+	return meta, ok
+}
+
+// update diffs the fresh leader snapshot against the mirrored book and returns the
+// signals to emit. The first call only seeds the snapshot.
+func (t *positionTracker) update(curr map[string]PositionMeta, equity float64) []Signal {
+	now := t.now()
+	t.markReady()
+	defer t.resetCycle()
+	t.rememberTerms(curr)
+	t.lastEquity = equity
+	if !t.initialized {
+		t.seedPrices(curr)
+		t.lastPositions = copyPositions(curr)
+		t.lastSampleAt = now
+		t.initialized = true
+		return nil
+	}
+	if t.restored {
+		t.restored = false
+		if !t.catchUp {
+			// the leader's current book becomes the new baseline
+			t.seedPrices(curr)
+			t.lastPositions = copyPositions(curr)
+			return nil
+		}
+		t.catchUpPrices(curr)
+	}
+
+	target := curr
+	if t.daily || t.sampleInterval > 0 {
+		if !t.sampleDue(now) {
+			if !t.promptCloses {
+				return nil
+			}
+			target = closesOnly(t.lastPositions, curr)
+		} else {
+			t.lastSampleAt = now
+		}
+	}
+
+	if t.rebalanceMin > 0 {
+		target = aboveThreshold(t.lastPositions, target, t.rebalanceMin)
+	}
+	if t.confirmSides {
+		target = t.holdConflicts(t.lastPositions, target)
+	}
+	if t.maxOpens > 0 {
+		target = t.throttleOpens(t.lastPositions, target)
+	}
+
+	prices, sources := t.lastPrices, map[string]string(nil)
+	if t.blend != nil {
+		prices, sources = t.blendedPrices(target)
+	} else {
+		t.resolvePrices(target)
+	}
+	prev := t.lastPositions
+	signals := diffPositionsAt(prev, target, prices, equity, now)
+	t.lastPositions = nextSnapshot(prev, target, prices)
+	for i := range signals {
+		signals[i].PriceSource = sources[signals[i].Symbol]
+		signals[i].DetectedAt = now
+		filledAt, filled := t.cycleFillAt[signals[i].Symbol]
+		if filled {
+			signals[i].Timestamp = filledAt
+		}
+		signals[i].DedupKey = signalKey(signals[i], prev, target, filledAt, filled)
+		if isEntry(signals[i].Action) {
+			signals[i].LeaderLiqPrice = target[signals[i].Symbol].LiqPrice
+		}
+		// a position reported flat may carry no terms of its own
+		if signals[i].LeaderLeverage <= 0 {
+			signals[i].LeaderLeverage = t.lastLeverage[signals[i].Symbol]
+		}
+		if signals[i].MarginMode == "" {
+			signals[i].MarginMode = t.lastMargin[signals[i].Symbol]
+		}
+	}
+	t.annotateCloses(signals)
+	t.tagReentries(signals, now)
+	t.annotateEffectiveLeverage(signals, equity)
+	t.annotateBookNotional(signals)
+	t.annotateLeverageLimits(signals)
+	signals = t.volatility.filter(signals, now)
+	signals = t.applyLatency(signals, now)
+	t.annotateConfidence(signals, now)
+	t.annotateUrgency(signals)
+	if t.replicate {
+		if len(signals) == 0 {
+			return nil
+		}
+		signals = t.replicaSignals(targetSignals(signals), equity, now)
+	} else if t.emitTargets {
+		signals = targetSignals(signals)
+	}
+	annotateMargin(signals)
+	if t.coalesceFlips && !t.replicate && !t.emitTargets {
+		signals = coalesceFlips(signals)
+	}
+	return signals
+}
+
+// closeAll closes every mirrored position at its last known price and empties the
+// mirrored book, for Config.CloseAllOnStop. The closes stand for the follower's own
+// exit rather than a leader change, so they always carry full confidence.
+func (t *positionTracker) closeAll() []Signal {
+	if !t.initialized {
+		return nil
+	}
+	now := t.now()
+	symbols := make([]string, 0, len(t.lastPositions))
+	for sym, meta := range t.lastPositions {
+		if meta.Size != 0 {
+			symbols = append(symbols, sym)
+		}
+	}
+	sort.Strings(symbols)
+
+	var signals []Signal
+	for _, sym := range symbols {
+		old := t.lastPositions[sym]
+		flat := PositionMeta{Leverage: old.Leverage, MarginMode: old.MarginMode}
+		signals = append(signals, transitionSignals(sym, old.Size, flat, t.lastPrices[sym], t.lastEquity, now)...)
+	}
+	for i := range signals {
+		sig := &signals[i]
+		sig.DetectedAt = now
+		sig.DedupKey = signalKey(*sig, t.lastPositions, nil, time.Time{}, false)
+		if sig.LeaderLeverage <= 0 {
+			sig.LeaderLeverage = t.lastLeverage[sig.Symbol]
+		}
+		if sig.MarginMode == "" {
+			sig.MarginMode = t.lastMargin[sig.Symbol]
+		}
+		sig.Confidence = 1
+		sig.Urgency = urgencyFor(sig.Action)
+	}
+	annotateMargin(signals)
+	t.lastPositions = map[string]PositionMeta{}
+	return signals
+}
+
+// snapshot copies the mirrored book, pricing each position at its last known price
+// and filling in the last known leverage where the venue reported none.
+func (t *positionTracker) snapshot() map[string]PositionMeta {
+	book := copyPositions(t.lastPositions)
+	for sym, meta := range book {
+		meta.Price = t.lastPrices[sym]
+		if meta.Leverage <= 0 {
+			meta.Leverage = t.lastLeverage[sym]
+		}
+		book[sym] = meta
+	}
+	return book
+}
+
+// rememberTerms records the leverage and margin mode of every open position, so a
+// close reported without them still carries the terms the position was held on.
+func (t *positionTracker) rememberTerms(curr map[string]PositionMeta) {
+	for sym, meta := range curr {
+		if meta.Size == 0 {
+			continue
+		}
+		if meta.Leverage > 0 {
+			t.lastLeverage[sym] = meta.Leverage
+		}
+		if meta.MarginMode != "" {
+			t.lastMargin[sym] = meta.MarginMode
+		}
+	}
+}
+
+// annotateMargin sets each signal's MarginUSD from its notional and leverage.
+func annotateMargin(signals []Signal) {
+	for i := range signals {
+		signals[i].MarginUSD = signals[i].NotionalUSD / float64(max(signals[i].LeaderLeverage, 1))
+	}
+}
+
+// replicaSignals expands the targets of this poll's changes into the whole mirrored
+// book, so a reconciler can solve for it without remembering earlier signals.
+func (t *positionTracker) replicaSignals(changed []Signal, equity float64, now time.Time) []Signal {
+	bySymbol := make(map[string]Signal, len(t.lastPositions)+len(changed))
+	for _, sig := range changed {
+		bySymbol[sig.Symbol] = sig
+	}
+	for sym, meta := range t.lastPositions {
+		if _, ok := bySymbol[sym]; ok || meta.Size == 0 {
+			continue
+		}
+		bySymbol[sym] = Signal{
+			Symbol:          sym,
+			Action:          ActionSetPosition,
+			Price:           t.lastPrices[sym],
+			LeaderEquity:    equity,
+			LeaderLeverage:  meta.Leverage,
+			MarginMode:      meta.MarginMode,
+			Timestamp:       now,
+			DetectedAt:      now,
+			LeaderPosBefore: meta.Size,
+			LeaderPosAfter:  meta.Size,
+			TargetSize:      meta.Size,
+		}
+	}
+
+	symbols := make([]string, 0, len(bySymbol))
+	for sym := range bySymbol {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	out := make([]Signal, 0, len(symbols))
+	for _, sym := range symbols {
+		out = append(out, bySymbol[sym])
+	}
+	return out
+}
+
+// seedPrices prices every position held at init that has no recent fill, so the first
+// change after init does not stall on the market data fallback.
+func (t *positionTracker) seedPrices(curr map[string]PositionMeta) {
+	for sym, meta := range curr {
+		if meta.Size == 0 || t.lastPrices[sym] > 0 {
+			continue
+		}
+		if t.seedFromEntry && meta.EntryPrice > 0 {
+			t.lastPrices[sym] = meta.EntryPrice
+			continue
+		}
+		if price, err := t.marketPrice(sym); err == nil && price > 0 {
+			t.setMarketPrice(sym, price)
+			continue
+		}
+		t.recordPrice(sym, meta.EntryPrice)
+	}
+}
+
+// recordFillSide notes the direction of a new fill for the direction consistency check.
+func (t *positionTracker) recordFillSide(symbol string, buy bool) {
+	if symbol == "" {
+		return
+	}
+	side := 1
+	if !buy {
+		side = -1
+	}
+	if prev, ok := t.cycleSide[symbol]; ok && prev != side {
+		side = 0
+	}
+	t.cycleSide[symbol] = side
+}
+
+// holdConflicts keeps the mirrored size for one poll on symbols whose snapshot moved
+// against the direction of this poll's fills, which is usually the venue serving a
+// stale or half-applied position. The next poll emits whatever the snapshot then
+// shows; a conflict seen on two polls in a row is taken at face value.
+func (t *positionTracker) holdConflicts(prev, curr map[string]PositionMeta) map[string]PositionMeta {
+	held := make(map[string]bool)
+	target := curr
+	for sym, side := range t.cycleSide {
+		delta := curr[sym].Size - prev[sym].Size
+		if side == 0 || delta == 0 || (delta > 0) == (side > 0) || t.heldConflicts[sym] {
+			continue
+		}
+		if len(held) == 0 {
+			target = copyPositions(curr)
+		}
+		held[sym] = true
+		if old, ok := prev[sym]; ok {
+			target[sym] = old
+		} else {
+			delete(target, sym)
+		}
+		loggerOf(t.logger).Infof("⏳ %s position moved %+g against this poll's fills, holding for confirmation", sym, delta)
+	}
+	t.heldConflicts = held
+	return target
+}
+
+// recordCloseFill notes a fill that reduced the leader's position, with the PnL the
+// venue reports for it.
+func (t *positionTracker) recordCloseFill(symbol string, price, size, pnl float64) {
+	if symbol == "" || price <= 0 {
+		return
+	}
+	size = math.Abs(size)
+	c := t.cycleClose[symbol]
+	c.notional += price * size
+	c.volume += size
+	c.pnl += pnl
+	t.cycleClose[symbol] = c
+}
+
+// annotateCloses prices close and reduce signals at this poll's closing fills rather
+// than the symbol's last price, which may come from an opening fill of a flip, and
+// attaches the leader's realized PnL.
+func (t *positionTracker) annotateCloses(signals []Signal) {
+	for i := range signals {
+		sig := &signals[i]
+		switch sig.Action {
+		case ActionCloseLong, ActionCloseShort, ActionReduceLong, ActionReduceShort:
+		default:
+			continue
+		}
+		c, ok := t.cycleClose[sig.Symbol]
+		if !ok {
+			continue
+		}
+		if c.volume > 0 {
+			sig.Price = c.notional / c.volume
+			sig.NotionalUSD = math.Abs(sig.DeltaSize) * sig.Price
+		}
+		sig.RealizedPnLUSD = c.pnl
+	}
+}
+
+// throttleOpens lets at most maxOpens symbols open (from flat or by flipping) per
+// poll. The rest stay flat in the mirrored book, so they come up again on the next
+// poll, and are admitted first-deferred first, new ones in symbol order. Closes and
+// the closing leg of a deferred flip pass immediately.
+func (t *positionTracker) throttleOpens(prev, curr map[string]PositionMeta) map[string]PositionMeta {
+	opening := func(sym string) bool {
+		after, before := curr[sym].Size, prev[sym].Size
+		return after != 0 && (before == 0 || (before > 0) != (after > 0))
+	}
+
+	var candidates []string
+	queued := make(map[string]bool, len(t.openQueue))
+	for _, sym := range t.openQueue {
+		if opening(sym) {
+			candidates = append(candidates, sym)
+			queued[sym] = true
+		}
+	}
+	var fresh []string
+	for sym := range curr {
+		if !queued[sym] && opening(sym) {
+			fresh = append(fresh, sym)
+		}
+	}
+	sort.Strings(fresh)
+	candidates = append(candidates, fresh...)
+
+	t.openQueue = nil
+	if len(candidates) <= t.maxOpens {
+		return curr
+	}
+	target := copyPositions(curr)
+	for _, sym := range candidates[t.maxOpens:] {
+		if prev[sym].Size != 0 {
+			target[sym] = PositionMeta{MarginMode: curr[sym].MarginMode, Leverage: curr[sym].Leverage}
+		} else {
+			delete(target, sym)
+		}
+		t.openQueue = append(t.openQueue, sym)
+	}
+	loggerOf(t.logger).Infof("🧺 %d new opens this poll, deferring %v", len(candidates), t.openQueue)
+	return target
+}
+
+// recordLiquidation notes that the leader was liquidated on symbol this poll.
+func (t *positionTracker) recordLiquidation(symbol string) {
+	if symbol != "" {
+		t.cycleLiq[symbol] = true
+	}
+}
+
+// annotateUrgency sets Signal.Urgency, raising both legs of a flip to high, and marks
+// closes on symbols liquidated this poll.
+func (t *positionTracker) annotateUrgency(signals []Signal) {
+	closed := make(map[string]bool)
+	for _, sig := range signals {
+		if sig.Action == ActionCloseLong || sig.Action == ActionCloseShort {
+			closed[sig.Symbol] = true
+		}
+	}
+	for i := range signals {
+		sig := &signals[i]
+		sig.Urgency = urgencyFor(sig.Action)
+		switch sig.Action {
+		case ActionOpenLong, ActionOpenShort:
+			if closed[sig.Symbol] {
+				sig.Urgency = UrgencyHigh // open leg of a flip
+			}
+		case ActionCloseLong, ActionCloseShort, ActionReduceLong, ActionReduceShort:
+			if t.cycleLiq[sig.Symbol] {
+				sig.Liquidation = true
+				sig.Urgency = UrgencyHigh
+			}
+		}
+	}
+}
+
+// setMarketPrice records a market data fallback price.
+func (t *positionTracker) setMarketPrice(symbol string, price float64) {
+	t.lastPrices[symbol] = price
+	t.marketPriced[symbol] = true
+}
+
+// Confidence penalties, multiplied together.
+const (
+	confidenceNoFill      = 0.7 // the diff was not corroborated by a fill this poll
+	confidenceMarketPrice = 0.6 // priced from market data instead of a leader fill
+	confidenceFillHalfAge = 5 * time.Minute
+)
+
+// annotateConfidence scores each signal from 1 (backed by a fresh fill at a known
+// price) down: a diff without a fill this poll, a market data fallback price and an
+// old fill each lower it.
+func (t *positionTracker) annotateConfidence(signals []Signal, now time.Time) {
+	for i := range signals {
+		sig := &signals[i]
+		confidence := 1.0
+		if filledAt, ok := t.cycleFillAt[sig.Symbol]; ok {
+			// halves every confidenceFillHalfAge, but never below the no-fill penalty
+			age := now.Sub(filledAt)
+			if age > 0 {
+				confidence = math.Max(confidenceNoFill, math.Pow(0.5, float64(age)/float64(confidenceFillHalfAge)))
+			}
+		} else {
+			confidence *= confidenceNoFill
+		}
+		if t.marketPriced[sig.Symbol] || sig.PriceSource == "market" {
+			confidence *= confidenceMarketPrice
+		}
+		sig.Confidence = confidence
+	}
+}
+
+// applyLatency annotates signals whose leader fill is older than maxLatency and, if
+// configured, drops the late opens and adds.
+func (t *positionTracker) applyLatency(signals []Signal, now time.Time) []Signal {
+	if t.maxLatency <= 0 || len(signals) == 0 {
+		return signals
+	}
+	kept := signals[:0]
+	for _, sig := range signals {
+		filledAt, ok := t.cycleFillAt[sig.Symbol]
+		if lag := now.Sub(filledAt); ok && lag > t.maxLatency {
+			sig.StaleByDuration = lag
+			if t.dropLateOpens && isEntry(sig.Action) {
+				loggerOf(t.logger).Infof("🐢 %s %s is %s behind the leader's fill, suppressed", sig.Symbol, sig.Action, lag)
+				continue
+			}
+		}
+		kept = append(kept, sig)
+	}
+	return kept
+}
+
+// resetCycle starts a fresh VWAP window for the next poll.
+func (t *positionTracker) resetCycle() {
+	if len(t.cycleFillAt) > 0 {
+		t.cycleFillAt = make(map[string]time.Time)
+	}
+	if len(t.cycleLiq) > 0 {
+		t.cycleLiq = make(map[string]bool)
+	}
+	if len(t.cycleSide) > 0 {
+		t.cycleSide = make(map[string]int)
+	}
+	if len(t.cycleClose) > 0 {
+		t.cycleClose = make(map[string]closeFills)
+	}
+	if len(t.cycleVolume) == 0 {
+		return
+	}
+	t.cycleNotional = make(map[string]float64)
+	t.cycleVolume = make(map[string]float64)
+}
+
+// sampleDue reports whether a sampled tracker may emit the accumulated net change.
+func (t *positionTracker) sampleDue(now time.Time) bool {
+	if t.daily {
+		return t.lastSampleAt.Before(lastDailyBoundary(now, t.dailyAt))
+	}
+	return now.Sub(t.lastSampleAt) >= t.sampleInterval
+}
+
+// lastDailyBoundary returns the most recent rebalance time at or before now.
+func lastDailyBoundary(now time.Time, offset time.Duration) time.Time {
+	now = now.UTC()
+	boundary := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(offset)
+	if boundary.After(now) {
+		boundary = boundary.AddDate(0, 0, -1)
+	}
+	return boundary
+}
+
+// markReady closes the ready channel once. Callers hold the provider lock.
+func (t *positionTracker) markReady() {
+	select {
+	case <-t.ready:
+	default:
+		close(t.ready)
+	}
+}
+
+// annotateEffectiveLeverage fills Signal.EffectiveLeverage from the mirrored book.
+// Cross positions are valued at the last known price (market data as a fallback);
+// a position that cannot be priced is left out of the pooled notional.
+func (t *positionTracker) annotateEffectiveLeverage(signals []Signal, equity float64) {
+	var crossLeverage float64
+	crossResolved := false
+	for i := range signals {
+		sig := &signals[i]
+		if !isCrossMargin(sig.MarginMode) {
+			sig.EffectiveLeverage = float64(sig.LeaderLeverage)
+			continue
+		}
+		if equity <= 0 {
+			continue
+		}
+		if !crossResolved {
+			crossLeverage = t.bookNotional(true) / equity
+			crossResolved = true
+		}
+		sig.EffectiveLeverage = crossLeverage
+	}
+}
+
+// annotateBookNotional stamps every signal with the gross notional of the book.
+func (t *positionTracker) annotateBookNotional(signals []Signal) {
+	if len(signals) == 0 {
+		return
+	}
+	total := t.bookNotional(false)
+	for i := range signals {
+		signals[i].LeaderBookNotionalUSD = total
+	}
+}
+
+// bookNotional sums the USD notional of the positions in the book, optionally only
+// the cross-margin ones.
+func (t *positionTracker) bookNotional(crossOnly bool) float64 {
+	var total float64
+	for sym, meta := range t.lastPositions {
+		if meta.Size == 0 || (crossOnly && !isCrossMargin(meta.MarginMode)) {
+			continue
+		}
+		price := t.lastPrices[sym]
+		if price <= 0 {
+			p, err := t.marketPrice(sym)
+			if err != nil || p <= 0 {
+				continue
+			}
+			price = p
+			t.setMarketPrice(sym, p)
+		}
+		total += math.Abs(meta.Size) * price
+	}
+	return total
+}
+
+// isEntry reports whether an action increases exposure.
+func isEntry(action SignalAction) bool {
+	switch action {
+	case ActionOpenLong, ActionOpenShort, ActionAddLong, ActionAddShort:
+		return true
+	}
+	return false
+}
+
+// annotateLeverageLimits flags opens and adds whose leader leverage the follower cannot
+// honor, so the executor knows the adjusted leverage before submitting.
+func (t *positionTracker) annotateLeverageLimits(signals []Signal) {
+	if t.allowedLev == nil {
+		return
+	}
+	for i := range signals {
+		sig := &signals[i]
+		if !isEntry(sig.Action) {
+			continue
+		}
+		max, ok := t.allowedLev(sig.Symbol)
+		if !ok || max <= 0 || sig.LeaderLeverage <= max {
+			continue
+		}
+		sig.LeverageAdjusted = max
+		sig.LeverageWarning = fmt.Sprintf("leader leverage %dx exceeds follower max %dx on %s", sig.LeaderLeverage, max, sig.Symbol)
+	}
+}
+
+func isCrossMargin(mode string) bool {
+	return strings.EqualFold(mode, "cross")
+}
+
+// tagReentries marks opens on recently closed symbols and records new closes.
+// Closes are recorded after tagging so the open leg of a flip is not a re-entry.
+func (t *positionTracker) tagReentries(signals []Signal, now time.Time) {
+	if t.reentryWindow <= 0 {
+		return
+	}
+	for i := range signals {
+		sig := &signals[i]
+		if sig.Action != ActionOpenLong && sig.Action != ActionOpenShort {
+			continue
+		}
+		if closed, ok := t.closedAt[sig.Symbol]; ok && now.Sub(closed) <= t.reentryWindow {
+			sig.IsReentry = true
+		}
+	}
+	for _, sig := range signals {
+		if sig.Action == ActionCloseLong || sig.Action == ActionCloseShort {
+			t.closedAt[sig.Symbol] = now
+		}
+	}
+	for sym, closed := range t.closedAt {
+		if now.Sub(closed) > t.reentryWindow {
+			delete(t.closedAt, sym)
+		}
+	}
+}
+
+// resolvePrices falls back to market data for changed symbols without a fill price.
+func (t *positionTracker) resolvePrices(target map[string]PositionMeta) {
+	for sym := range changedSymbols(t.lastPositions, target) {
+		if t.lastPrices[sym] > 0 {
+			continue
+		}
+		if price, err := t.marketPrice(sym); err == nil && price > 0 {
+			t.setMarketPrice(sym, price)
+		}
+	}
+}
+
+// catchUpPrices reprices the symbols that moved while the provider was offline. The
+// restored prices are as old as the state, so unless this poll brought a fill they
+// give way to market data, then to the position's entry price.
+func (t *positionTracker) catchUpPrices(curr map[string]PositionMeta) {
+	for sym := range changedSymbols(t.lastPositions, curr) {
+		if _, filled := t.cycleFillAt[sym]; filled {
+			continue
+		}
+		if price, err := t.marketPrice(sym); err == nil && price > 0 {
+			t.setMarketPrice(sym, price)
+		} else if entry := curr[sym].EntryPrice; entry > 0 {
+			t.recordPrice(sym, entry)
+		}
+	}
+}
+
+// blendedPrices prices the changed symbols with the blended oracle. The last fill
+// prices are left untouched so the fill source stays a pure fill price.
+func (t *positionTracker) blendedPrices(target map[string]PositionMeta) (map[string]float64, map[string]string) {
+	prices := make(map[string]float64, len(t.lastPrices))
+	for sym, price := range t.lastPrices {
+		prices[sym] = price
+	}
+	sources := make(map[string]string)
+	for sym := range changedSymbols(t.lastPositions, target) {
+		if price, source := t.blend.blend(sym, t.lastPrices[sym]); price > 0 {
+			prices[sym] = price
+			sources[sym] = source
+		}
+	}
+	return prices, sources
+}
+
+// changedSymbols lists every symbol whose size differs between two snapshots.
+func changedSymbols(prev, curr map[string]PositionMeta) map[string]struct{} {
+	changed := make(map[string]struct{})
+	for sym, meta := range curr {
+		if prev[sym].Size != meta.Size {
+			changed[sym] = struct{}{}
+		}
+	}
+	for sym, meta := range prev {
+		if _, ok := curr[sym]; !ok && meta.Size != 0 {
+			changed[sym] = struct{}{}
+		}
+	}
+	return changed
+}
+
+func rebalanceThreshold(cfg Config) float64 {
+	if cfg.Mode != ModeNet || cfg.RebalanceThreshold <= 0 {
+		return 0
+	}
+	return cfg.RebalanceThreshold
+}
+
+// aboveThreshold keeps the mirrored size for symbols whose relative change is below
+// min, so small grid legs accumulate until they amount to a meaningful rebalance.
+func aboveThreshold(prev, curr map[string]PositionMeta, min float64) map[string]PositionMeta {
+	target := make(map[string]PositionMeta, len(curr))
+	for sym, meta := range curr {
+		old, ok := prev[sym]
+		if !ok || old.Size == 0 || meta.Size == 0 || (old.Size > 0) != (meta.Size > 0) {
+			target[sym] = meta
+			continue
+		}
+		if math.Abs(meta.Size-old.Size)/math.Abs(old.Size) < min {
+			target[sym] = old
+			continue
+		}
+		target[sym] = meta
+	}
+	return target
+}
+
+// closesOnly keeps the mirrored book as-is except for symbols the leader fully closed.
+func closesOnly(prev, curr map[string]PositionMeta) map[string]PositionMeta {
+	target := make(map[string]PositionMeta, len(prev))
+	for sym, meta := range prev {
+		if next, ok := curr[sym]; !ok || next.Size == 0 {
+			continue
+		}
+		target[sym] = meta
+	}
+	return target
+}
+
+// nextSnapshot advances the mirrored book to curr. A changed symbol that could not be
+// priced keeps its previous size so the change is retried on the next poll.
+func nextSnapshot(prev, curr map[string]PositionMeta, prices map[string]float64) map[string]PositionMeta {
+	next := make(map[string]PositionMeta, len(curr))
+	for sym, meta := range curr {
+		if old, ok := prev[sym]; ok && old.Size != meta.Size && prices[sym] <= 0 {
+			next[sym] = old
+			continue
+		}
+		if _, ok := prev[sym]; !ok && meta.Size != 0 && prices[sym] <= 0 {
+			continue
+		}
+		next[sym] = meta
+	}
+	return next
+}
+
+func copyPositions(src map[string]PositionMeta) map[string]PositionMeta {
+	dst := make(map[string]PositionMeta, len(src))
+	for sym, meta := range src {
+		dst[sym] = meta
+	}
+	return dst
+}
@@ -0,0 +1,658 @@
+package copytrading
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestTracker(cfg Config) (*positionTracker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cfg.Clock = clock
+	tr := newPositionTracker(cfg)
+	tr.recordPrice("BTCUSDT", 100)
+	tr.recordPrice("ETHUSDT", 10)
+	return tr, clock
+}
+
+func book(sizes map[string]float64) map[string]PositionMeta {
+	out := make(map[string]PositionMeta, len(sizes))
+	for sym, size := range sizes {
+		out[sym] = PositionMeta{Size: size, Leverage: 5, MarginMode: "cross"}
+	}
+	return out
+}
+
+func TestTrackerSampledEmissionIgnoresNoise(t *testing.T) {
+	noisy := []float64{1, 1.2, 0.9, 1.1, 1.5, 1.4, 1.6, 1.3, 1.7, 1.5, 1.6, 1.2, 2.0}
+
+	perPoll, perPollClock := newTestTracker(Config{})
+	sampled, sampledClock := newTestTracker(Config{SampleInterval: time.Minute})
+
+	var perPollSignals, sampledSignals []Signal
+	for _, size := range noisy {
+		snapshot := book(map[string]float64{"BTCUSDT": size})
+		perPollSignals = append(perPollSignals, perPoll.update(snapshot, 1000)...)
+		sampledSignals = append(sampledSignals, sampled.update(snapshot, 1000)...)
+		perPollClock.Advance(10 * time.Second)
+		sampledClock.Advance(10 * time.Second)
+	}
+
+	if len(perPollSignals) != len(noisy)-1 {
+		t.Fatalf("expected a signal on every change, got %d", len(perPollSignals))
+	}
+	if len(sampledSignals) != 2 {
+		t.Fatalf("expected one net signal per minute, got %d: %+v", len(sampledSignals), sampledSignals)
+	}
+
+	// first sample at t=60s sees 1.6, second at t=120s sees 2.0
+	first, second := sampledSignals[0], sampledSignals[1]
+	if first.Action != ActionAddLong || math.Abs(first.DeltaSize-0.6) > 1e-9 {
+		t.Fatalf("unexpected first sample: %+v", first)
+	}
+	if math.Abs(second.LeaderPosBefore-1.6) > 1e-9 || math.Abs(second.LeaderPosAfter-2.0) > 1e-9 {
+		t.Fatalf("unexpected second sample: %+v", second)
+	}
+}
+
+func TestTrackerSampledClosesFirePromptly(t *testing.T) {
+	for _, prompt := range []bool{false, true} {
+		tr, clock := newTestTracker(Config{SampleInterval: time.Minute, SampleClosesImmediately: prompt})
+		tr.update(book(map[string]float64{"BTCUSDT": 1, "ETHUSDT": -3}), 1000)
+
+		clock.Advance(10 * time.Second)
+		signals := tr.update(book(map[string]float64{"BTCUSDT": 1.4}), 1000)
+
+		if !prompt {
+			if len(signals) != 0 {
+				t.Fatalf("expected no emission inside the window, got %+v", signals)
+			}
+			continue
+		}
+		if len(signals) != 1 || signals[0].Symbol != "ETHUSDT" || signals[0].Action != ActionCloseShort {
+			t.Fatalf("expected prompt ETH close, got %+v", signals)
+		}
+
+		// the BTC add is still deferred to the sample boundary
+		clock.Advance(time.Minute)
+		signals = tr.update(book(map[string]float64{"BTCUSDT": 1.4}), 1000)
+		if len(signals) != 1 || signals[0].Symbol != "BTCUSDT" || signals[0].Action != ActionAddLong {
+			t.Fatalf("expected deferred BTC add, got %+v", signals)
+		}
+	}
+}
+
+func TestTrackerTagsReentryWithinWindow(t *testing.T) {
+	tr, clock := newTestTracker(Config{ReentryWindow: 10 * time.Minute})
+	tr.update(book(map[string]float64{"BTCUSDT": 1}), 1000)
+
+	clock.Advance(time.Minute)
+	closed := tr.update(book(nil), 1000)
+	if len(closed) != 1 || closed[0].Action != ActionCloseLong {
+		t.Fatalf("expected close, got %+v", closed)
+	}
+
+	clock.Advance(5 * time.Minute)
+	signals := tr.update(book(map[string]float64{"BTCUSDT": 1, "ETHUSDT": 2}), 1000)
+	if len(signals) != 2 {
+		t.Fatalf("expected two opens, got %+v", signals)
+	}
+	for _, sig := range signals {
+		switch sig.Symbol {
+		case "BTCUSDT":
+			if !sig.IsReentry {
+				t.Fatalf("BTC reopened within window must be a re-entry: %+v", sig)
+			}
+		case "ETHUSDT":
+			if sig.IsReentry {
+				t.Fatalf("fresh ETH open must not be a re-entry: %+v", sig)
+			}
+		}
+	}
+
+	// close again and reopen outside the window
+	clock.Advance(time.Minute)
+	tr.update(book(map[string]float64{"ETHUSDT": 2}), 1000)
+	clock.Advance(11 * time.Minute)
+	signals = tr.update(book(map[string]float64{"ETHUSDT": 2, "BTCUSDT": -1}), 1000)
+	if len(signals) != 1 || signals[0].Action != ActionOpenShort || signals[0].IsReentry {
+		t.Fatalf("open outside the window must not be a re-entry: %+v", signals)
+	}
+}
+
+func TestTrackerFlipIsNotReentry(t *testing.T) {
+	tr, clock := newTestTracker(Config{ReentryWindow: time.Hour})
+	tr.update(book(map[string]float64{"BTCUSDT": 1}), 1000)
+
+	clock.Advance(time.Minute)
+	signals := tr.update(book(map[string]float64{"BTCUSDT": -1}), 1000)
+	if len(signals) != 2 || signals[1].Action != ActionOpenShort || signals[1].IsReentry {
+		t.Fatalf("flip open leg must not be a re-entry: %+v", signals)
+	}
+}
+
+func TestTrackerNetModeCollapsesGridLegs(t *testing.T) {
+	// a grid leader oscillating around a slowly rising net long
+	var grid []float64
+	for i := 0; i < 60; i++ {
+		leg := 0.02
+		if i%2 == 1 {
+			leg = -0.02
+		}
+		grid = append(grid, 1+float64(i)*0.005+leg)
+	}
+	grid = append(grid, 0) // and finally flattening out
+
+	run := func(cfg Config) []Signal {
+		tr, clock := newTestTracker(cfg)
+		tr.update(book(map[string]float64{"BTCUSDT": 1}), 1000)
+		var emitted []Signal
+		for _, size := range grid {
+			clock.Advance(10 * time.Second)
+			snapshot := book(map[string]float64{"BTCUSDT": size})
+			if size == 0 {
+				snapshot = book(nil)
+			}
+			emitted = append(emitted, tr.update(snapshot, 1000)...)
+		}
+		return emitted
+	}
+
+	trade := run(Config{})
+	net := run(Config{Mode: ModeNet, RebalanceThreshold: 0.1, SampleClosesImmediately: true})
+
+	if len(trade) != len(grid) {
+		t.Fatalf("trade mode should mirror every leg, got %d", len(trade))
+	}
+	if len(net) == 0 || len(net) > 4 {
+		t.Fatalf("net mode should collapse the grid into a few rebalances, got %d", len(net))
+	}
+	last := net[len(net)-1]
+	if last.Action != ActionCloseLong || last.LeaderPosAfter != 0 {
+		t.Fatalf("net mode must still mirror the final close, got %+v", last)
+	}
+	var netSize float64 = 1
+	for _, sig := range net[:len(net)-1] {
+		netSize += sig.DeltaSize
+		if math.Abs(sig.DeltaSize)/math.Abs(sig.LeaderPosBefore) < 0.1 {
+			t.Fatalf("rebalance below threshold emitted: %+v", sig)
+		}
+	}
+	if netSize < 1.1 {
+		t.Fatalf("net mode should follow the rising trend, mirrored %.3f", netSize)
+	}
+}
+
+func TestTrackerEffectiveLeverageUnderCrossMargin(t *testing.T) {
+	tr, clock := newTestTracker(Config{})
+	tr.recordPrice("SOLUSDT", 50)
+	tr.update(map[string]PositionMeta{
+		"BTCUSDT": {Size: 1, Leverage: 20, MarginMode: "cross"},
+		"ETHUSDT": {Size: -30, Leverage: 20, MarginMode: "cross"},
+		"SOLUSDT": {Size: 4, Leverage: 3, MarginMode: "isolated"},
+	}, 1000)
+
+	clock.Advance(time.Minute)
+	signals := tr.update(map[string]PositionMeta{
+		"BTCUSDT": {Size: 2, Leverage: 20, MarginMode: "cross"},
+		"ETHUSDT": {Size: -30, Leverage: 20, MarginMode: "cross"},
+		"SOLUSDT": {Size: 6, Leverage: 3, MarginMode: "isolated"},
+	}, 1000)
+	if len(signals) != 2 {
+		t.Fatalf("expected two adds, got %+v", signals)
+	}
+	for _, sig := range signals {
+		switch sig.Symbol {
+		case "BTCUSDT":
+			// pooled: BTC 2*100 + ETH 30*10 = 500 notional on 1000 equity, not the nominal 20x
+			if math.Abs(sig.EffectiveLeverage-0.5) > 1e-9 {
+				t.Fatalf("expected pooled effective leverage 0.5, got %+v", sig)
+			}
+		case "SOLUSDT":
+			if sig.EffectiveLeverage != 3 {
+				t.Fatalf("isolated positions keep per-symbol leverage, got %+v", sig)
+			}
+		}
+	}
+}
+
+func TestTrackerDailyModeEmitsOneNetDiffAtBoundary(t *testing.T) {
+	tr, clock := newTestTracker(Config{Mode: ModeDaily, RebalanceTimeOfDay: 0})
+	tr.update(book(map[string]float64{"BTCUSDT": 1, "ETHUSDT": -5}), 1000)
+
+	// a day of activity: BTC churns and ends at 3, ETH flips to long 2
+	var emitted []Signal
+	for hour := 1; hour < 24; hour++ {
+		clock.Advance(time.Hour)
+		btc := 1 + float64(hour%4)
+		if hour == 23 {
+			btc = 3
+		}
+		eth := -5.0
+		if hour > 12 {
+			eth = 2
+		}
+		emitted = append(emitted, tr.update(book(map[string]float64{"BTCUSDT": btc, "ETHUSDT": eth}), 1000)...)
+	}
+	if len(emitted) != 0 {
+		t.Fatalf("no signal should be emitted before the rebalance time, got %+v", emitted)
+	}
+
+	clock.Advance(70 * time.Minute) // 2025-01-02 00:10 UTC, past the boundary
+	signals := tr.update(book(map[string]float64{"BTCUSDT": 3, "ETHUSDT": 2}), 1000)
+
+	var btc, eth []Signal
+	for _, sig := range signals {
+		switch sig.Symbol {
+		case "BTCUSDT":
+			btc = append(btc, sig)
+		case "ETHUSDT":
+			eth = append(eth, sig)
+		}
+	}
+	if len(btc) != 1 || btc[0].Action != ActionAddLong || btc[0].DeltaSize != 2 {
+		t.Fatalf("expected a single BTC net add of 2, got %+v", btc)
+	}
+	// a flip is still two legs, but only for the net change across the day
+	if len(eth) != 2 || eth[0].Action != ActionCloseShort || eth[1].Action != ActionOpenLong || eth[1].LeaderPosAfter != 2 {
+		t.Fatalf("expected a single ETH net flip, got %+v", eth)
+	}
+
+	clock.Advance(time.Hour)
+	if again := tr.update(book(map[string]float64{"BTCUSDT": 4, "ETHUSDT": 2}), 1000); len(again) != 0 {
+		t.Fatalf("expected nothing until the next day's boundary, got %+v", again)
+	}
+}
+
+func TestTrackerEmitTargetsCollapsesFlip(t *testing.T) {
+	tr, clock := newTestTracker(Config{EmitTargets: true})
+	tr.update(book(map[string]float64{"BTCUSDT": 2, "ETHUSDT": 1}), 1000)
+
+	clock.Advance(time.Minute)
+	signals := tr.update(book(map[string]float64{"BTCUSDT": -3, "ETHUSDT": 1.5}), 1000)
+	if len(signals) != 2 {
+		t.Fatalf("expected one target per symbol, got %+v", signals)
+	}
+	for _, sig := range signals {
+		if sig.Action != ActionSetPosition {
+			t.Fatalf("expected set_position, got %+v", sig)
+		}
+		switch sig.Symbol {
+		case "BTCUSDT":
+			if sig.TargetSize != -3 || sig.LeaderPosBefore != 2 || sig.DeltaSize != -5 || sig.Price != 100 {
+				t.Fatalf("expected one flip target of -3 from 2, got %+v", sig)
+			}
+			if sig.NotionalUSD != 500 {
+				t.Fatalf("expected notional of the whole move, got %+v", sig)
+			}
+		case "ETHUSDT":
+			if sig.TargetSize != 1.5 || sig.DeltaSize != 0.5 {
+				t.Fatalf("expected plain changes as targets too, got %+v", sig)
+			}
+		}
+	}
+}
+
+func TestTrackerAnnotatesUnhonorableLeverage(t *testing.T) {
+	allowed := map[string]int{"BTCUSDT": 3, "ETHUSDT": 10}
+	tr, _ := newTestTracker(Config{AllowedLeverage: func(symbol string) (int, bool) {
+		max, ok := allowed[symbol]
+		return max, ok
+	}})
+	tr.update(book(nil), 1000)
+
+	signals := tr.update(book(map[string]float64{"BTCUSDT": 1, "ETHUSDT": 1}), 1000)
+	if len(signals) != 2 {
+		t.Fatalf("expected two opens, got %+v", signals)
+	}
+	for _, sig := range signals {
+		switch sig.Symbol {
+		case "BTCUSDT":
+			if sig.LeverageAdjusted != 3 || sig.LeverageWarning == "" {
+				t.Fatalf("5x on a 3x symbol must be annotated: %+v", sig)
+			}
+		case "ETHUSDT":
+			if sig.LeverageAdjusted != 0 || sig.LeverageWarning != "" {
+				t.Fatalf("honorable leverage must not be annotated: %+v", sig)
+			}
+		}
+	}
+}
+
+func TestTrackerSeedsHeldSymbolPricesAtInit(t *testing.T) {
+	held := map[string]PositionMeta{"SOLUSDT": {Size: 10, Leverage: 5, MarginMode: "cross", EntryPrice: 140}}
+
+	stubMarketPrice(t, map[string]float64{"SOLUSDT": 150})
+	tr, _ := newTestTracker(Config{})
+	tr.update(held, 1000)
+
+	// market data goes away after init: the seeded price must carry the first change
+	marketQuote = oracleQuotes(fixedOracle(nil))
+	signals := tr.update(map[string]PositionMeta{"SOLUSDT": {Size: 12, Leverage: 5, MarginMode: "cross"}}, 1000)
+	if len(signals) != 1 || signals[0].Action != ActionAddLong || signals[0].Price != 150 {
+		t.Fatalf("expected add priced from the init seed, got %+v", signals)
+	}
+
+	fromEntry, _ := newTestTracker(Config{SeedPricesFromEntry: true})
+	fromEntry.update(held, 1000)
+	if fromEntry.lastPrices["SOLUSDT"] != 140 {
+		t.Fatalf("expected entry price seed, got %v", fromEntry.lastPrices["SOLUSDT"])
+	}
+}
+
+func TestTrackerVolatilityGuardSuppressesEntriesOnly(t *testing.T) {
+	tr, clock := newTestTracker(Config{VolatilityThreshold: 0.05})
+	tr.update(book(map[string]float64{"ETHUSDT": 1}), 1000)
+
+	for _, price := range []float64{100, 112, 94, 108} {
+		clock.Advance(10 * time.Second)
+		tr.recordPrice("BTCUSDT", price)
+		tr.recordPrice("ETHUSDT", price/10)
+	}
+	signals := tr.update(book(map[string]float64{"BTCUSDT": 1}), 1000)
+	if len(signals) != 1 || signals[0].Symbol != "ETHUSDT" || signals[0].Action != ActionCloseLong {
+		t.Fatalf("expected only the ETH close to pass, got %+v", signals)
+	}
+
+	// once the window has calmed down, entries pass again
+	clock.Advance(10 * time.Minute)
+	tr.recordPrice("BTCUSDT", 101)
+	signals = tr.update(book(map[string]float64{"BTCUSDT": 2}), 1000)
+	if len(signals) != 1 || signals[0].Action != ActionAddLong {
+		t.Fatalf("expected add after volatility subsided, got %+v", signals)
+	}
+}
+
+func TestTrackerMarksAndSuppressesLateOpens(t *testing.T) {
+	tr, clock := newTestTracker(Config{MaxFillLatency: 5 * time.Second, SuppressLateOpens: true})
+	tr.update(book(map[string]float64{"ETHUSDT": 1}), 1000)
+
+	// both fills happened 30s before the poll that sees them
+	filledAt := clock.Now()
+	clock.Advance(30 * time.Second)
+	tr.recordFill("BTCUSDT", 100, 1, filledAt)
+	tr.recordFill("ETHUSDT", 10, 1, filledAt)
+	signals := tr.update(book(map[string]float64{"BTCUSDT": 1}), 1000)
+	if len(signals) != 1 || signals[0].Action != ActionCloseLong || signals[0].StaleByDuration != 30*time.Second {
+		t.Fatalf("expected only the stale close to pass, got %+v", signals)
+	}
+
+	// a fresh fill is neither annotated nor suppressed
+	tr.recordFill("BTCUSDT", 100, 1, clock.Now().Add(-time.Second))
+	signals = tr.update(book(map[string]float64{"BTCUSDT": 2}), 1000)
+	if len(signals) != 1 || signals[0].Action != ActionAddLong || signals[0].StaleByDuration != 0 {
+		t.Fatalf("expected fresh add, got %+v", signals)
+	}
+}
+
+func TestTrackerConfidenceReflectsPriceBacking(t *testing.T) {
+	stubMarketPrice(t, map[string]float64{"SOLUSDT": 150})
+	tr, clock := newTestTracker(Config{})
+	tr.update(book(nil), 1000)
+
+	// BTC is backed by a fresh fill this poll; SOL only by the position diff and market data
+	tr.recordFill("BTCUSDT", 100, 1, clock.Now())
+	signals := tr.update(book(map[string]float64{"BTCUSDT": 1, "SOLUSDT": 1}), 1000)
+	if len(signals) != 2 {
+		t.Fatalf("expected two opens, got %+v", signals)
+	}
+	confidence := map[string]float64{}
+	for _, sig := range signals {
+		confidence[sig.Symbol] = sig.Confidence
+	}
+	if confidence["BTCUSDT"] != 1 {
+		t.Fatalf("expected full confidence for a fresh fill, got %v", confidence["BTCUSDT"])
+	}
+	if c := confidence["SOLUSDT"]; c <= 0 || c >= 0.5 {
+		t.Fatalf("expected low confidence for a fallback-priced diff, got %v", c)
+	}
+}
+
+func TestTrackerUrgency(t *testing.T) {
+	tr, _ := newTestTracker(Config{})
+	tr.update(book(map[string]float64{"BTCUSDT": 1, "ETHUSDT": 2}), 1000)
+
+	urgency := func(signals []Signal) map[SignalAction]Urgency {
+		got := make(map[SignalAction]Urgency)
+		for _, sig := range signals {
+			got[sig.Action] = sig.Urgency
+		}
+		return got
+	}
+
+	// BTC flips; ETH adds
+	got := urgency(tr.update(book(map[string]float64{"BTCUSDT": -1, "ETHUSDT": 3}), 1000))
+	want := map[SignalAction]Urgency{ActionCloseLong: UrgencyHigh, ActionOpenShort: UrgencyHigh, ActionAddLong: UrgencyLow}
+	for action, u := range want {
+		if got[action] != u {
+			t.Fatalf("%s: expected %s, got %s (%v)", action, u, got[action], got)
+		}
+	}
+
+	got = urgency(tr.update(book(map[string]float64{"BTCUSDT": -1, "ETHUSDT": 1, "SOLUSDT": 1}), 1000))
+	if got[ActionReduceLong] != UrgencyLow {
+		t.Fatalf("reduce: expected low, got %v", got)
+	}
+}
+
+func TestTrackerLiquidationClosesAreHigh(t *testing.T) {
+	tr, clock := newTestTracker(Config{})
+	tr.update(book(map[string]float64{"BTCUSDT": 1, "ETHUSDT": 1}), 1000)
+
+	tr.recordFill("BTCUSDT", 90, 1, clock.Now())
+	tr.recordLiquidation("BTCUSDT")
+	signals := tr.update(book(map[string]float64{"ETHUSDT": 2}), 1000)
+	for _, sig := range signals {
+		switch sig.Symbol {
+		case "BTCUSDT":
+			if !sig.Liquidation || sig.Urgency != UrgencyHigh {
+				t.Fatalf("expected liquidation close marked high: %+v", sig)
+			}
+		case "ETHUSDT":
+			if sig.Liquidation || sig.Urgency != UrgencyLow {
+				t.Fatalf("expected ordinary low-urgency add: %+v", sig)
+			}
+		}
+	}
+}
+
+func TestTrackerHoldsMovesContradictingFills(t *testing.T) {
+	tr, clock := newTestTracker(Config{ConfirmDirectionConflicts: true})
+	tr.update(book(map[string]float64{"BTCUSDT": 1}), 1000)
+
+	// the leader bought, but the snapshot still lags and shows a reduce
+	tr.recordFill("BTCUSDT", 100, 1, clock.Now())
+	tr.recordFillSide("BTCUSDT", true)
+	if signals := tr.update(book(map[string]float64{"BTCUSDT": 0.5}), 1000); len(signals) != 0 {
+		t.Fatalf("a move against the fills must wait for confirmation, got %+v", signals)
+	}
+
+	// the next snapshot has caught up with the buy
+	signals := tr.update(book(map[string]float64{"BTCUSDT": 2}), 1000)
+	if len(signals) != 1 || signals[0].Action != ActionAddLong || signals[0].DeltaSize != 1 {
+		t.Fatalf("expected a single add from the mirrored size, got %+v", signals)
+	}
+
+	// a snapshot agreeing with its fills is emitted right away
+	tr.recordFill("BTCUSDT", 100, 1, clock.Now())
+	tr.recordFillSide("BTCUSDT", false)
+	if signals := tr.update(book(map[string]float64{"BTCUSDT": 1}), 1000); len(signals) != 1 || signals[0].Action != ActionReduceLong {
+		t.Fatalf("expected an immediate reduce, got %+v", signals)
+	}
+}
+
+func TestTrackerSpreadsBasketOpensAcrossPolls(t *testing.T) {
+	tr, _ := newTestTracker(Config{MaxOpensPerCycle: 2})
+	for _, sym := range []string{"SOLUSDT", "XRPUSDT", "DOGEUSDT", "ADAUSDT"} {
+		tr.recordPrice(sym, 1)
+	}
+	tr.update(book(map[string]float64{}), 1000)
+
+	opened := func(signals []Signal) (opens []string, closes int) {
+		for _, sig := range signals {
+			switch sig.Action {
+			case ActionOpenLong:
+				opens = append(opens, sig.Symbol)
+			case ActionCloseLong:
+				closes++
+			}
+		}
+		sort.Strings(opens)
+		return opens, closes
+	}
+	basket := map[string]float64{"BTCUSDT": 1, "ETHUSDT": 1, "SOLUSDT": 1, "XRPUSDT": 1, "DOGEUSDT": 1}
+
+	opens, _ := opened(tr.update(book(basket), 1000))
+	if !reflect.DeepEqual(opens, []string{"BTCUSDT", "DOGEUSDT"}) {
+		t.Fatalf("poll 1: expected the first two symbols, got %v", opens)
+	}
+
+	// ADA opens after the basket and queues behind it; the BTC close is never held
+	basket["ADAUSDT"] = 1
+	delete(basket, "BTCUSDT")
+	opens, closes := opened(tr.update(book(basket), 1000))
+	if !reflect.DeepEqual(opens, []string{"ETHUSDT", "SOLUSDT"}) || closes != 1 {
+		t.Fatalf("poll 2: expected ETH and SOL plus the BTC close, got %v (%d closes)", opens, closes)
+	}
+	if !reflect.DeepEqual(tr.openQueue, []string{"XRPUSDT", "ADAUSDT"}) {
+		t.Fatalf("expected XRP queued ahead of ADA, got %v", tr.openQueue)
+	}
+
+	opens, _ = opened(tr.update(book(basket), 1000))
+	if !reflect.DeepEqual(opens, []string{"ADAUSDT", "XRPUSDT"}) {
+		t.Fatalf("poll 3: expected the queued XRP and ADA, got %v", opens)
+	}
+	if signals := tr.update(book(basket), 1000); len(signals) != 0 {
+		t.Fatalf("the queue must be drained, got %+v", signals)
+	}
+}
+
+func TestTrackerReplicateEmitsWholeBook(t *testing.T) {
+	tr, _ := newTestTracker(Config{Mode: ModeReplicate})
+	tr.recordPrice("SOLUSDT", 1)
+	tr.update(book(map[string]float64{"BTCUSDT": 1, "ETHUSDT": -2}), 1000)
+
+	targets := func(signals []Signal) map[string]float64 {
+		got := make(map[string]float64, len(signals))
+		for i, sig := range signals {
+			if sig.Action != ActionSetPosition {
+				t.Fatalf("unexpected action %s", sig.Action)
+			}
+			if i > 0 && signals[i-1].Symbol >= sig.Symbol {
+				t.Fatalf("targets must be in symbol order: %+v", signals)
+			}
+			got[sig.Symbol] = sig.TargetSize
+		}
+		return got
+	}
+
+	// one symbol changes: every position is re-stated
+	leader := map[string]float64{"BTCUSDT": 1, "ETHUSDT": -3, "SOLUSDT": 5}
+	if got := targets(tr.update(book(leader), 1000)); !reflect.DeepEqual(got, leader) {
+		t.Fatalf("expected the full book %v, got %v", leader, got)
+	}
+	if signals := tr.update(book(leader), 1000); len(signals) != 0 {
+		t.Fatalf("an unchanged book must emit nothing, got %+v", signals)
+	}
+
+	// a close is stated as a zero target next to the untouched positions
+	leader = map[string]float64{"BTCUSDT": 1, "SOLUSDT": 5}
+	want := map[string]float64{"BTCUSDT": 1, "ETHUSDT": 0, "SOLUSDT": 5}
+	if got := targets(tr.update(book(leader), 1000)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestTrackerSignalsCarryMargin(t *testing.T) {
+	tr, _ := newTestTracker(Config{})
+	tr.update(book(map[string]float64{"ETHUSDT": 1}), 1000)
+
+	// 2 BTC at 100 on 5x leverage
+	signals := tr.update(book(map[string]float64{"BTCUSDT": 2, "ETHUSDT": 1}), 1000)
+	if len(signals) != 1 || signals[0].NotionalUSD != 200 || signals[0].MarginUSD != 40 {
+		t.Fatalf("expected 40 USD of margin behind 200 USD at 5x, got %+v", signals)
+	}
+
+	// a close reported without leverage is margined at the position's last known 5x
+	signals = tr.update(map[string]PositionMeta{"BTCUSDT": {Size: 2, Leverage: 5}, "ETHUSDT": {Size: 0}}, 1000)
+	if len(signals) != 1 || signals[0].MarginUSD != 2 {
+		t.Fatalf("expected the close margined at the last known 5x, got %+v", signals)
+	}
+}
+
+func TestTrackerClosesKeepLastKnownTerms(t *testing.T) {
+	tr, _ := newTestTracker(Config{})
+	tr.update(map[string]PositionMeta{}, 1000)
+	tr.update(map[string]PositionMeta{"BTCUSDT": {Size: 1, Leverage: 10, MarginMode: "isolated"}}, 1000)
+
+	// the venue reports the flat row without its terms
+	signals := tr.update(map[string]PositionMeta{"BTCUSDT": {Size: 0}}, 1000)
+	if len(signals) != 1 || signals[0].Action != ActionCloseLong || signals[0].LeaderLeverage != 10 || signals[0].MarginMode != "isolated" {
+		t.Fatalf("expected the close at the position's 10x isolated, got %+v", signals)
+	}
+}
+
+func TestTrackerHoldsChangesOnStaleMarketPrice(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	quotedAt := clock.t.Add(-10 * time.Minute)
+	orig := marketQuote
+	marketQuote = func(string) (float64, time.Time, error) { return 3, quotedAt, nil }
+	t.Cleanup(func() { marketQuote = orig })
+
+	tr := newPositionTracker(Config{Clock: clock, MaxPriceAge: time.Minute})
+	tr.update(map[string]PositionMeta{}, 1000)
+	if signals := tr.update(book(map[string]float64{"SOLUSDT": 10}), 1000); len(signals) != 0 {
+		t.Fatalf("expected the open held on a 10 minute old quote, got %+v", signals)
+	}
+	if _, err := tr.marketPrice("SOLUSDT"); !errors.Is(err, ErrStalePrice) {
+		t.Fatalf("expected ErrStalePrice, got %v", err)
+	}
+
+	// a fresh quote on the next poll lets it through
+	quotedAt = clock.t
+	signals := tr.update(book(map[string]float64{"SOLUSDT": 10}), 1000)
+	if len(signals) != 1 || signals[0].Action != ActionOpenLong || signals[0].NotionalUSD != 30 {
+		t.Fatalf("expected the open emitted at the fresh quote, got %+v", signals)
+	}
+}
+
+func TestTrackerCoalescesFlips(t *testing.T) {
+	plain, _ := newTestTracker(Config{})
+	plain.update(book(map[string]float64{"BTCUSDT": 1, "ETHUSDT": 1}), 1000)
+	if signals := plain.update(book(map[string]float64{"BTCUSDT": -2, "ETHUSDT": 1}), 1000); len(signals) != 2 {
+		t.Fatalf("expected a flip to stay two legs by default, got %+v", signals)
+	}
+
+	tr, _ := newTestTracker(Config{CoalesceFlips: true})
+	tr.update(book(map[string]float64{"BTCUSDT": 1, "ETHUSDT": 1}), 1000)
+
+	// BTC flips from long 1 to short 2 at 100; ETH adds
+	signals := tr.update(book(map[string]float64{"BTCUSDT": -2, "ETHUSDT": 2}), 1000)
+	if len(signals) != 2 {
+		t.Fatalf("expected one reverse and one add, got %+v", signals)
+	}
+	rev := signals[0]
+	if rev.Action != ActionReverseShort || rev.CloseNotionalUSD != 100 || rev.OpenNotionalUSD != 200 || rev.NotionalUSD != 300 {
+		t.Fatalf("expected a reverse carrying both notionals, got %+v", rev)
+	}
+	if rev.LeaderPosBefore != 1 || rev.LeaderPosAfter != -2 || rev.DeltaSize != -3 || rev.MarginUSD != 40 || rev.Urgency != UrgencyHigh {
+		t.Fatalf("expected the whole move with the open leg's margin, got %+v", rev)
+	}
+	if add := signals[1]; add.Action != ActionAddLong || add.CloseNotionalUSD != 0 || add.OpenNotionalUSD != 0 {
+		t.Fatalf("expected the ETH add untouched, got %+v", add)
+	}
+
+	// a plain close is not merged
+	signals = tr.update(book(map[string]float64{"ETHUSDT": 2}), 1000)
+	if len(signals) != 1 || signals[0].Action != ActionCloseShort {
+		t.Fatalf("expected a plain close, got %+v", signals)
+	}
+}
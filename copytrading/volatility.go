@@ -0,0 +1,90 @@
+package copytrading
+
+import (
+	"math"
+	"time"
+)
+
+const defaultVolatilityWindow = 5 * time.Minute
+
+// volatilityGuard suppresses opens and adds on symbols whose recent prices are too
+// dispersed to enter at a fair price. Closes and reduces always pass.
+type volatilityGuard struct {
+	threshold float64 // max (high-low)/mean over the window
+	window    time.Duration
+	samples   map[string][]priceSample
+	logger    Logger
+}
+
+type priceSample struct {
+	price float64
+	at    time.Time
+}
+
+func newVolatilityGuard(cfg Config) *volatilityGuard {
+	if cfg.VolatilityThreshold <= 0 {
+		return nil
+	}
+	window := cfg.VolatilityWindow
+	if window <= 0 {
+		window = defaultVolatilityWindow
+	}
+	return &volatilityGuard{
+		threshold: cfg.VolatilityThreshold,
+		window:    window,
+		samples:   make(map[string][]priceSample),
+		logger:    loggerOf(cfg.Logger),
+	}
+}
+
+// observe records a price seen for symbol. A nil guard ignores it.
+func (g *volatilityGuard) observe(symbol string, price float64, at time.Time) {
+	if g == nil || symbol == "" || price <= 0 {
+		return
+	}
+	g.samples[symbol] = append(g.prune(symbol, at), priceSample{price: price, at: at})
+}
+
+// prune drops samples that fell out of the window.
+func (g *volatilityGuard) prune(symbol string, now time.Time) []priceSample {
+	samples := g.samples[symbol]
+	i := 0
+	for i < len(samples) && now.Sub(samples[i].at) > g.window {
+		i++
+	}
+	return samples[i:]
+}
+
+// dispersion returns the relative high-low range of the symbol's recent prices.
+func (g *volatilityGuard) dispersion(symbol string, now time.Time) float64 {
+	samples := g.prune(symbol, now)
+	g.samples[symbol] = samples
+	if len(samples) < 2 {
+		return 0
+	}
+	low, high, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, s := range samples {
+		low = math.Min(low, s.price)
+		high = math.Max(high, s.price)
+		sum += s.price
+	}
+	return (high - low) / (sum / float64(len(samples)))
+}
+
+// filter drops opens and adds on volatile symbols.
+func (g *volatilityGuard) filter(signals []Signal, now time.Time) []Signal {
+	if g == nil || len(signals) == 0 {
+		return signals
+	}
+	kept := signals[:0]
+	for _, sig := range signals {
+		if isEntry(sig.Action) {
+			if d := g.dispersion(sig.Symbol, now); d > g.threshold {
+				loggerOf(g.logger).Infof("🌪 %s too volatile (%.2f%% range), suppressed %s", sig.Symbol, d*100, sig.Action)
+				continue
+			}
+		}
+		kept = append(kept, sig)
+	}
+	return kept
+}
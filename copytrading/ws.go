@@ -0,0 +1,185 @@
+package copytrading
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transports a provider can be driven by.
+const (
+	TransportREST = "rest" // default: poll the venue's REST endpoints
+	TransportWS   = "ws"   // keep a websocket subscription open and refresh on pushes
+)
+
+// transportOf returns the configured transport, honoring the UseWebSocket shorthand.
+func transportOf(cfg Config) string {
+	if cfg.UseWebSocket {
+		return TransportWS
+	}
+	if cfg.Transport == "" {
+		return TransportREST
+	}
+	return cfg.Transport
+}
+
+const (
+	defaultWSPingInterval = 20 * time.Second
+	defaultWSReadTimeout  = time.Minute
+	defaultWSMinBackoff   = time.Second
+	defaultWSMaxBackoff   = 30 * time.Second
+)
+
+// wsTransport keeps one websocket subscription alive on behalf of a provider: it
+// dials, (re)sends the subscription messages after every connect, keeps the
+// connection warm with pings, reconnects with exponential backoff and hands every
+// data message to handle. Venue specifics (URLs, message shapes, app-level pings)
+// stay in the provider.
+type wsTransport struct {
+	name          string
+	url           string
+	subscriptions []interface{}
+	// pingMessage, when set, is sent as a text frame instead of a control ping, for
+	// venues with an app-level keepalive: []byte as is, anything else as JSON.
+	pingMessage  interface{}
+	pingInterval time.Duration
+	readTimeout  time.Duration
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	dialer       *websocket.Dialer
+	logger       Logger
+
+	// handle receives every text or binary message; it runs on the read goroutine.
+	handle func(msg []byte)
+	// connected, when set, runs after each successful subscribe, so the provider can
+	// resync anything it may have missed while disconnected.
+	connected func()
+}
+
+// run keeps the subscription alive until ctx is done.
+func (w *wsTransport) run(ctx context.Context) {
+	backoff := w.backoffMin()
+	for {
+		established, err := w.session(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if established {
+			// the session was established before it dropped: start over from the floor
+			backoff = w.backoffMin()
+		}
+		loggerOf(w.logger).Warnf("⚠️  %s websocket disconnected, reconnecting in %v: %v", w.name, backoff, err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff *= 2
+		if max := w.backoffMax(); backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// session dials, subscribes and reads until the connection fails or ctx is done.
+// established reports whether the subscription went through, so the caller can
+// reset its backoff.
+func (w *wsTransport) session(ctx context.Context) (established bool, err error) {
+	dialer := w.dialer
+	if dialer == nil {
+		dialer = &websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	}
+	conn, _, err := dialer.DialContext(ctx, w.url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var writeMu sync.Mutex
+	write := func(v interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		switch msg := v.(type) {
+		case nil:
+			return conn.WriteMessage(websocket.PingMessage, nil)
+		case []byte:
+			return conn.WriteMessage(websocket.TextMessage, msg)
+		}
+		return conn.WriteJSON(v)
+	}
+	for _, sub := range w.subscriptions {
+		if err := write(sub); err != nil {
+			conn.Close()
+			return false, err
+		}
+	}
+	if w.connected != nil {
+		w.connected()
+	}
+
+	readTimeout := w.readTimeout
+	if readTimeout <= 0 {
+		readTimeout = defaultWSReadTimeout
+	}
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		interval := w.pingInterval
+		if interval <= 0 {
+			interval = defaultWSPingInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// unblocks the read below
+				conn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := write(w.pingMessage); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	defer conn.Close()
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		w.handle(msg)
+	}
+}
+
+func (w *wsTransport) backoffMin() time.Duration {
+	if w.minBackoff > 0 {
+		return w.minBackoff
+	}
+	return defaultWSMinBackoff
+}
+
+func (w *wsTransport) backoffMax() time.Duration {
+	if w.maxBackoff > 0 {
+		return w.maxBackoff
+	}
+	return defaultWSMaxBackoff
+}
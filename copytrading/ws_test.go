@@ -0,0 +1,280 @@
+package copytrading
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsFake is a websocket server that records subscriptions and lets the test push
+// messages to, or drop, the current connection.
+type wsFake struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	subs        []string
+	subsPerConn int // subscription messages read before handing out a connection
+	conns       chan *websocket.Conn
+	upgrade     websocket.Upgrader
+}
+
+func newWSFake(t *testing.T) *wsFake {
+	f := &wsFake{conns: make(chan *websocket.Conn, 8), subsPerConn: 1}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := f.upgrade.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		for i := 0; i < f.subsPerConn; i++ {
+			_, sub, err := conn.ReadMessage()
+			if err != nil {
+				conn.Close()
+				return
+			}
+			f.mu.Lock()
+			f.subs = append(f.subs, strings.TrimSpace(string(sub)))
+			f.mu.Unlock()
+		}
+		f.conns <- conn
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *wsFake) url() string {
+	return "ws" + strings.TrimPrefix(f.URL, "http")
+}
+
+func (f *wsFake) next(t *testing.T) *websocket.Conn {
+	t.Helper()
+	select {
+	case conn := <-f.conns:
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a websocket connection")
+		return nil
+	}
+}
+
+func (f *wsFake) subscriptions() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.subs...)
+}
+
+func TestWSTransportReconnectsAndResubscribes(t *testing.T) {
+	server := newWSFake(t)
+	received := make(chan string, 8)
+	ws := &wsTransport{
+		name:          "test",
+		url:           server.url(),
+		subscriptions: []interface{}{map[string]string{"op": "subscribe"}},
+		minBackoff:    10 * time.Millisecond,
+		handle:        func(msg []byte) { received <- string(msg) },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ws.run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("expected %s, got %s", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+
+	first := server.next(t)
+	first.WriteMessage(websocket.TextMessage, []byte(`{"n":1}`))
+	expect(`{"n":1}`)
+	first.Close()
+
+	second := server.next(t)
+	second.WriteMessage(websocket.TextMessage, []byte(`{"n":2}`))
+	expect(`{"n":2}`)
+
+	subs := server.subscriptions()
+	if len(subs) != 2 || subs[0] != `{"op":"subscribe"}` || subs[1] != subs[0] {
+		t.Fatalf("expected the subscription resent on reconnect, got %v", subs)
+	}
+}
+
+func TestHyperliquidWebSocketMatchesPolling(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	seedFill := `{"coin":"BTC","px":"100","sz":"1","side":"B","startPosition":"0","time":1700000000000,"tid":1}`
+	openFill := `{"coin":"BTC","px":"101","sz":"1","side":"B","startPosition":"0","time":1700000060000,"tid":2}`
+	flat := `{"marginSummary":{"accountValue":"1000"},"assetPositions":[]}`
+	long := `{"marginSummary":{"accountValue":"1000"},"assetPositions":[
+		{"position":{"coin":"BTC","szi":"1","entryPx":"101","leverage":{"type":"cross","value":5}}}]}`
+
+	// the polling path over the same leader activity
+	fake := newHyperliquidFake()
+	polling := newTestHyperliquidProvider(fake, Config{Clock: clock})
+	polled := make(chan Signal, 8)
+	fake.set("userFills", `[`+seedFill+`]`)
+	fake.set("clearinghouseState", flat)
+	if err := polling.fetchAndEmit(context.Background(), polled); err != nil {
+		t.Fatal(err)
+	}
+	fake.set("userFills", `[`+seedFill+`,`+openFill+`]`)
+	fake.set("clearinghouseState", long)
+	if err := polling.fetchAndEmit(context.Background(), polled); err != nil {
+		t.Fatal(err)
+	}
+	if len(polled) != 1 {
+		t.Fatalf("expected one polled signal, got %d", len(polled))
+	}
+
+	server := newWSFake(t)
+	server.subsPerConn = 2
+	streaming := newTestHyperliquidProvider(newHyperliquidFake(), Config{Clock: clock, UseWebSocket: true})
+	streaming.wsURL = server.url()
+	stop := make(chan struct{})
+	out := make(chan Signal, 8)
+	done := make(chan error, 1)
+	go func() { done <- RunUntil(streaming, stop, out) }()
+	defer func() {
+		close(stop)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	conn := server.next(t)
+	if subs := server.subscriptions(); len(subs) != 2 ||
+		!strings.Contains(subs[0], `"type":"userFills"`) || !strings.Contains(subs[1], `"type":"webData2"`) {
+		t.Fatalf("unexpected subscriptions %v", subs)
+	}
+	push := func(channel, data string) {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"channel":"`+channel+`","data":`+data+`}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the first state seeds silently, historical fills included
+	push("userFills", `{"isSnapshot":true,"user":"`+streaming.user+`","fills":[`+seedFill+`]}`)
+	push("webData2", `{"clearinghouseState":`+flat+`}`)
+	<-streaming.Ready()
+
+	push("userFills", `{"user":"`+streaming.user+`","fills":[`+openFill+`]}`)
+	push("webData2", `{"clearinghouseState":`+long+`}`)
+	select {
+	case sig := <-out:
+		if want := <-polled; !reflect.DeepEqual(sig, want) {
+			t.Fatalf("streamed signal differs from polling:\n got %+v\nwant %+v", sig, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the streamed open")
+	}
+
+	// an unchanged state push emits nothing
+	push("webData2", `{"clearinghouseState":`+long+`}`)
+	select {
+	case sig := <-out:
+		t.Fatalf("unexpected signal %+v", sig)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOKXWebSocketAppliesPushedDiffs(t *testing.T) {
+	fake := newOKXFake()
+	fake.set("trade-records", `{"code":"0","data":[{"instId":"BTC-USDT-SWAP","avgPx":"100","fillTime":"1700000000000","ordId":"1"}]}`)
+	server := newWSFake(t)
+	p := newTestOKXProvider(fake, Config{Transport: TransportWS})
+	p.wsURL = server.url()
+
+	stop := make(chan struct{})
+	out := make(chan Signal, 8)
+	done := make(chan error, 1)
+	go func() { done <- RunUntil(p, stop, out) }()
+	defer func() {
+		close(stop)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	conn := server.next(t)
+	<-p.Ready()
+	if subs := server.subscriptions(); len(subs) != 1 ||
+		!strings.Contains(subs[0], okxWSPositionsChannel) || !strings.Contains(subs[0], okxWSFillsChannel) {
+		t.Fatalf("unexpected subscriptions %v", subs)
+	}
+	push := func(msg string) {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(action SignalAction, price float64) {
+		t.Helper()
+		select {
+		case sig := <-out:
+			if sig.Symbol != "BTCUSDT" || sig.Action != action || sig.Price != price {
+				t.Fatalf("expected %s at %v, got %+v", action, price, sig)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s", action)
+		}
+	}
+
+	push("pong")
+	push(`{"arg":{"channel":"` + okxWSFillsChannel + `"},"data":[{"instId":"BTC-USDT-SWAP","side":"buy","posSide":"long","avgPx":"101","sz":"1","fillTime":"1700000060000","ordId":"2"}]}`)
+	push(`{"arg":{"channel":"` + okxWSPositionsChannel + `"},"data":[{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"1","lever":"5"}]}`)
+	expect(ActionOpenLong, 101)
+
+	push(`{"arg":{"channel":"` + okxWSPositionsChannel + `"},"data":[{"instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"long","pos":"0","lever":"5"}]}`)
+	expect(ActionCloseLong, 101)
+
+	if n := fake.requestCount("position-current"); n != 1 {
+		t.Fatalf("expected REST only for the initial snapshot, got %d position requests", n)
+	}
+}
+
+func TestWSTransportSendsAppLevelPing(t *testing.T) {
+	server := newWSFake(t)
+	ws := &wsTransport{
+		name:          "test",
+		url:           server.url(),
+		subscriptions: []interface{}{map[string]string{"op": "subscribe"}},
+		pingMessage:   []byte("ping"),
+		pingInterval:  10 * time.Millisecond,
+		handle:        func([]byte) {},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ws.run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	conn := server.next(t)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "ping" {
+		t.Fatalf("expected a bare ping frame, got %q (%v)", msg, err)
+	}
+}
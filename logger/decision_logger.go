@@ -70,7 +70,6 @@ type DecisionAction struct {
 	FollowerEquity      float64 `json:"follower_equity,omitempty"`       // 跟随者账户净值
 	FollowerMarginUSD   float64 `json:"follower_margin_usd,omitempty"`   // 跟随者下单保证金
 	CopyRatio           float64 `json:"copy_ratio,omitempty"`            // 跟单系数 %
-	MinAmountApplied    bool    `json:"min_amount_applied,omitempty"`    // 是否触发最小额
 	MaxAmountApplied    bool    `json:"max_amount_applied,omitempty"`    // 是否触发最大额
 }
 
@@ -109,9 +109,20 @@ func Get(symbol string) (*Data, error) {
 		FundingRate:       fundingRate,
 		IntradaySeries:    intradayData,
 		LongerTermContext: longerTermData,
+		UpdatedAt:         klineUpdatedAt(klines3m[len(klines3m)-1], time.Now()),
 	}, nil
 }
 
+// klineUpdatedAt 返回K线数据的最后更新时间：进行中的K线视为刚更新，
+// 已收盘的K线取其收盘时间（推送中断时该时间会停留在过去）
+func klineUpdatedAt(k Kline, now time.Time) time.Time {
+	closed := time.UnixMilli(k.CloseTime)
+	if k.CloseTime == 0 || closed.After(now) {
+		return now
+	}
+	return closed
+}
+
 // calculateEMA 计算EMA
 func calculateEMA(klines []Kline, period int) float64 {
 	if len(klines) < period {
@@ -3,6 +3,7 @@ package market
 import (
 	"math"
 	"testing"
+	"time"
 )
 
 // generateTestKlines 生成测试用的 K线数据
@@ -500,3 +501,18 @@ func TestIsStaleData_EmptyKlines(t *testing.T) {
 		t.Error("Expected false for empty klines, got true")
 	}
 }
+
+// TestKlineUpdatedAt 测试K线数据的更新时间：进行中的K线视为刚更新，已收盘的K线取收盘时间
+func TestKlineUpdatedAt(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	open := Kline{CloseTime: now.Add(time.Minute).UnixMilli()}
+	if got := klineUpdatedAt(open, now); !got.Equal(now) {
+		t.Errorf("进行中的K线应视为刚更新, got %v", got)
+	}
+
+	closed := Kline{CloseTime: now.Add(-10 * time.Minute).UnixMilli()}
+	if got := klineUpdatedAt(closed, now); !got.Equal(now.Add(-10 * time.Minute)) {
+		t.Errorf("已收盘的K线应取收盘时间, got %v", got)
+	}
+}
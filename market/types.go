@@ -15,6 +15,8 @@ type Data struct {
 	FundingRate       float64
 	IntradaySeries    *IntradayData
 	LongerTermContext *LongerTermData
+	// UpdatedAt 最新3分钟K线的时间（进行中的K线取当前时间），用于判断数据是否新鲜
+	UpdatedAt time.Time
 }
 
 // OIData Open Interest数据
@@ -1,7 +1,6 @@
 package trader
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -91,6 +90,9 @@ type AutoTraderConfig struct {
 
 	// 系统提示词模板
 	SystemPromptTemplate string // 系统提示词模板名称（如 "default", "aggressive"）
+
+	// Clock 时钟（测试中可注入，nil 表示系统时钟；同时传给复制信号源）
+	Clock copytrading.Clock
 }
 
 // AutoTrader 自动交易器
@@ -102,6 +104,7 @@ type AutoTrader struct {
 	signalSourceType      string
 	signalSourceValue     string
 	copyTradingConfig     CopyTradingConfig
+	copyConfigMutex       sync.RWMutex // 保护 copyTradingConfig 的热更新
 	config                AutoTraderConfig
 	trader                Trader // 使用Trader接口（支持多平台）
 	mcpClient             mcp.AIClient
@@ -126,6 +129,12 @@ type AutoTrader struct {
 	lastBalanceSyncTime   time.Time          // 上次余额同步时间
 	database              interface{}        // 数据库引用（用于自动更新余额）
 	userID                string             // 用户ID
+	clock                 copytrading.Clock  // nil 表示系统时钟
+	symbolSpecs           *symbolSpecCache   // 交易规则缓存（交易所不支持时为 nil）
+	symbolSpecsOnce       sync.Once
+	copyLosses            *copyLossStreak // 领航员连续亏损熔断
+	leaderEquityEMA       float64         // 平滑后的领航员净值（EquitySmoothing）
+	copySourceChanged     chan struct{}   // 热更新改变信号源参数时通知跟单循环重新订阅
 }
 
 // NewAutoTrader 创建自动交易器
@@ -261,6 +270,11 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		systemPromptTemplate = "adaptive"
 	}
 
+	startedAt := time.Now()
+	if config.Clock != nil {
+		startedAt = config.Clock.Now()
+	}
+
 	return &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
@@ -277,8 +291,8 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		systemPromptTemplate:  systemPromptTemplate,
 		defaultCoins:          config.DefaultCoins,
 		tradingCoins:          config.TradingCoins,
-		lastResetTime:         time.Now(),
-		startTime:             time.Now(),
+		lastResetTime:         startedAt,
+		startTime:             startedAt,
 		callCount:             0,
 		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
@@ -286,9 +300,10 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		monitorWg:             sync.WaitGroup{},
 		peakPnLCache:          make(map[string]float64),
 		peakPnLCacheMutex:     sync.RWMutex{},
-		lastBalanceSyncTime:   time.Now(), // 初始化为当前时间
+		lastBalanceSyncTime:   startedAt, // 初始化为当前时间
 		database:              database,
 		userID:                userID,
+		clock:                 config.Clock,
 	}, nil
 }
 
@@ -296,7 +311,7 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 func (at *AutoTrader) Run() error {
 	at.isRunning = true
 	at.stopMonitorCh = make(chan struct{})
-	at.startTime = time.Now()
+	at.startTime = at.now()
 
 	log.Println("🚀 AI驱动自动交易系统启动")
 	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
@@ -347,49 +362,143 @@ func (at *AutoTrader) Stop() {
 	log.Println("⏹ 自动交易系统停止")
 }
 
-// runCopyTradingLoop 复制交易模式（后续将接入真实信号监听）
+// runCopyTradingLoop 复制交易模式：订阅共享信号源（同一领航员的多个跟随者共用一次轮询）
 func (at *AutoTrader) runCopyTradingLoop() error {
-	provider, err := copytrading.NewProvider(copytrading.Config{
-		Type:         at.signalSourceType,
-		Identifier:   at.signalSourceValue,
-		PollInterval: at.copyPollInterval(),
-	})
+	signalCh, unsubscribe, err := defaultCopySignalHub.subscribe(at.copySourceConfig())
 	if err != nil {
 		return fmt.Errorf("初始化复制交易信号源失败: %w", err)
 	}
-
-	signalCh := make(chan copytrading.Signal, 128)
-	errCh := make(chan error, 1)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	go func() {
-		errCh <- provider.Run(ctx.Done(), signalCh)
-	}()
+	defer func() { unsubscribe() }()
 
 	log.Printf("🛰 [%s] 已接入复制信号源: %s (%s)", at.name, at.signalSourceType, at.signalSourceValue)
 
 	for {
 		select {
-		case sig := <-signalCh:
+		case <-at.copySourceUpdates():
+			// 先接入新信号源再退出旧信号源，其他跟随者共享的旧信号源不受影响
+			source := at.copySourceConfig()
+			nextCh, nextUnsubscribe, err := defaultCopySignalHub.subscribe(source)
+			if err != nil {
+				log.Printf("⚠️  [%s] 重新订阅复制信号源失败，继续使用原信号源: %v", at.name, err)
+				continue
+			}
+			unsubscribe()
+			signalCh, unsubscribe = nextCh, nextUnsubscribe
+			log.Printf("🛰 [%s] 已切换复制信号源: %s", at.name, copySourceKey(source))
+		case sig, ok := <-signalCh:
+			if !ok {
+				log.Printf("❌ [%s] 复制信号服务异常退出", at.name)
+				return errCopySourceClosed
+			}
 			if err := at.processCopySignal(sig); err != nil {
 				log.Printf("⚠️  复制交易执行失败: %v", err)
 			}
-		case err := <-errCh:
-			if err != nil {
-				log.Printf("❌ 复制信号服务异常退出: %v", err)
-				return err
-			}
-			return nil
 		case <-at.stopMonitorCh:
-			cancel()
 			log.Printf("⏹ [%s] 复制交易模式已停止", at.name)
 			return nil
 		}
 	}
 }
 
+// now 返回当前时间（优先使用注入的时钟）
+func (at *AutoTrader) now() time.Time {
+	if at.clock == nil {
+		return time.Now()
+	}
+	return at.clock.Now()
+}
+
+func (at *AutoTrader) since(t time.Time) time.Duration {
+	return at.now().Sub(t)
+}
+
+// copyLossStreak 返回连续亏损熔断统计（首次使用时创建）
+func (at *AutoTrader) copyLossStreak() *copyLossStreak {
+	at.copyConfigMutex.Lock()
+	defer at.copyConfigMutex.Unlock()
+	if at.copyLosses == nil {
+		at.copyLosses = newCopyLossStreak()
+	}
+	return at.copyLosses
+}
+
+// copySourceUpdates 返回信号源参数变化通知（缓冲 1，首次使用时创建）
+func (at *AutoTrader) copySourceUpdates() chan struct{} {
+	at.copyConfigMutex.Lock()
+	defer at.copyConfigMutex.Unlock()
+	if at.copySourceChanged == nil {
+		at.copySourceChanged = make(chan struct{}, 1)
+	}
+	return at.copySourceChanged
+}
+
+// smoothLeaderEquity 按 EquitySmoothing 对领航员净值做指数移动平均，作为仓位计算基准
+func (at *AutoTrader) smoothLeaderEquity(equity, alpha float64) float64 {
+	at.copyConfigMutex.Lock()
+	defer at.copyConfigMutex.Unlock()
+	if alpha <= 0 || alpha >= 1 || at.leaderEquityEMA <= 0 {
+		at.leaderEquityEMA = equity
+		return equity
+	}
+	at.leaderEquityEMA = alpha*equity + (1-alpha)*at.leaderEquityEMA
+	return at.leaderEquityEMA
+}
+
+// ResetCopyLossStreak 手动解除连续亏损熔断
+func (at *AutoTrader) ResetCopyLossStreak() {
+	at.copyLossStreak().reset()
+	log.Printf("🔓 [%s] 已重置领航员连续亏损熔断", at.name)
+}
+
+// isCopyEntry 开仓/加仓动作
+func isCopyEntry(action copytrading.SignalAction) bool {
+	switch action {
+	case copytrading.ActionOpenLong, copytrading.ActionOpenShort, copytrading.ActionAddLong, copytrading.ActionAddShort:
+		return true
+	}
+	return false
+}
+
+// symbolSpec 返回币种交易规则（带缓存）；交易所不支持查询时 ok=false
+func (at *AutoTrader) symbolSpec(symbol string) (SymbolSpec, bool) {
+	at.symbolSpecsOnce.Do(func() {
+		if provider, ok := at.trader.(SymbolSpecProvider); ok {
+			at.symbolSpecs = newSymbolSpecCache(provider.GetSymbolSpec, defaultSymbolSpecTTL, at.now)
+		}
+	})
+	if at.symbolSpecs == nil {
+		return SymbolSpec{}, false
+	}
+	return at.symbolSpecs.get(symbol)
+}
+
+// refreshSymbolSpec 下单因交易规则被拒时使缓存失效，下一笔订单按最新规则计算
+func (at *AutoTrader) refreshSymbolSpec(symbol string) {
+	if at.symbolSpecs == nil {
+		return
+	}
+	at.symbolSpecs.refresh(symbol)
+	log.Printf("🔄 [%s] %s 下单因交易规则被拒，已刷新交易规则缓存", at.name, symbol)
+}
+
+// copySourceConfig 按当前跟单配置构造复制信号源配置
+func (at *AutoTrader) copySourceConfig() copytrading.Config {
+	return at.copySourceConfigFor(at.getCopyTradingConfig())
+}
+
+// copySourceConfigFor 构造复制信号源配置；FollowMode 与 RebalanceThresholdPct 在订阅时
+// 交给 provider，运行中的信号源不会感知之后的修改
+func (at *AutoTrader) copySourceConfigFor(copyCfg CopyTradingConfig) copytrading.Config {
+	return copytrading.Config{
+		Type:               at.signalSourceType,
+		Identifier:         at.signalSourceValue,
+		PollInterval:       at.copyPollInterval(),
+		Mode:               copyCfg.FollowMode,
+		RebalanceThreshold: copyCfg.RebalanceThresholdPct / 100,
+		Clock:              at.clock,
+	}
+}
+
 // copyPollInterval returns a short, provider-specific polling interval for copy trading
 // to keep it independent from the AI scan interval.
 func (at *AutoTrader) copyPollInterval() time.Duration {
@@ -403,11 +512,57 @@ func (at *AutoTrader) copyPollInterval() time.Duration {
 	}
 }
 
+// UpdateCopyTradingConfig 热更新跟单配置。仓位计算、动作开关、风控等字段对下一个信号
+// 直接生效，不重启信号源（保留领航员快照与游标）；FollowMode / RebalanceThresholdPct
+// 决定信号源本身，变更时跟单循环会重新订阅对应的信号源（新信号源重新建立领航员快照）
+func (at *AutoTrader) UpdateCopyTradingConfig(cfg CopyTradingConfig) error {
+	if err := validateCopyTradingConfig(cfg); err != nil {
+		return fmt.Errorf("跟单配置无效: %w", err)
+	}
+	cfg = normalizeCopyTradingConfig(cfg)
+
+	at.copyConfigMutex.Lock()
+	prev := at.copyTradingConfig
+	at.copyTradingConfig = cfg
+	at.config.CopyTradingConfig = cfg
+	at.copyConfigMutex.Unlock()
+
+	if copySourceKey(at.copySourceConfigFor(prev)) != copySourceKey(at.copySourceConfigFor(cfg)) {
+		select {
+		case at.copySourceUpdates() <- struct{}{}:
+		default:
+		}
+		log.Printf("🔁 [%s] 跟随模式已变更为 %s，将重新订阅信号源", at.name, cfg.FollowMode)
+	}
+	log.Printf("🔄 [%s] 跟单配置已热更新: ratio=%.2f%%, min=%.2f, max=%.2f", at.name, cfg.FollowRatio, cfg.MinAmount, cfg.MaxAmount)
+	return nil
+}
+
+// getCopyTradingConfig 返回当前生效的跟单配置副本
+func (at *AutoTrader) getCopyTradingConfig() CopyTradingConfig {
+	at.copyConfigMutex.RLock()
+	defer at.copyConfigMutex.RUnlock()
+	return at.copyTradingConfig
+}
+
 func (at *AutoTrader) processCopySignal(sig copytrading.Signal) error {
-	cfg := at.copyTradingConfig
+	cfg := at.getCopyTradingConfig()
 	if sig.LeaderEquity <= 0 || sig.NotionalUSD <= 0 {
 		return nil
 	}
+	if pnl, closed := at.copyLossStreak().observe(sig, cfg.MaxConsecutiveLosses); closed && pnl < 0 {
+		log.Printf("📉 [%s] 领航员 %s 亏损平仓: %.2f USDT", at.name, sig.Symbol, pnl)
+	}
+	sig = cfg.Transform(sig)
+	if remapped := cfg.remapAction(sig.Action); remapped != sig.Action {
+		log.Printf("🔁 [%s] 动作映射 %s: %s → %s", at.name, sig.Symbol, sig.Action, remapped)
+		sig.Action = remapped
+	}
+	sig.LeaderEquity = at.smoothLeaderEquity(sig.LeaderEquity, cfg.EquitySmoothing)
+	if isCopyEntry(sig.Action) && !at.copyLossStreak().allowOpen() {
+		log.Printf("🛑 [%s] 领航员连续亏损 %d 次，暂停跟随开仓: %s %s", at.name, cfg.MaxConsecutiveLosses, sig.Symbol, sig.Action)
+		return nil
+	}
 
 	if cfg.FollowRatio <= 0 {
 		cfg.FollowRatio = 100
@@ -437,11 +592,11 @@ func (at *AutoTrader) processCopySignal(sig copytrading.Signal) error {
 	accountSnapshot.PositionCount = len(positions)
 
 	// sizing
-	var quantity float64
+	var quantity, followerNotional float64
 	actionRecord := logger.DecisionAction{
 		Action:    string(sig.Action),
 		Symbol:    sig.Symbol,
-		Timestamp: time.Now(),
+		Timestamp: at.now(),
 	}
 	isReduce := sig.Action == copytrading.ActionCloseLong ||
 		sig.Action == copytrading.ActionCloseShort ||
@@ -451,6 +606,15 @@ func (at *AutoTrader) processCopySignal(sig copytrading.Signal) error {
 	longQty := getPositionQuantity(positions, sig.Symbol, "long")
 	shortQty := getPositionQuantity(positions, sig.Symbol, "short")
 
+	hasPosition := longQty > 0
+	if copyActionSide(sig.Action) == "short" {
+		hasPosition = shortQty > 0
+	}
+	if !cfg.ShouldFollow(sig, hasPosition) {
+		log.Printf("⏭ [%s] 跟单动作开关未开启，跳过 %s %s", at.name, sig.Symbol, sig.Action)
+		return nil
+	}
+
 	if isReduce {
 		// 按领航员变动比例作用于本地持仓
 		var leaderBefore float64
@@ -482,60 +646,91 @@ func (at *AutoTrader) processCopySignal(sig copytrading.Signal) error {
 			}
 		}
 	} else {
-		// 开/加仓按资金比例复制保证金：pct = leader_margin / leader_equity
-		leaderLeverage := math.Max(1, float64(sig.LeaderLeverage))
-		leaderMargin := sig.NotionalUSD / leaderLeverage
-		if leaderMargin <= 0 {
+		// 开/加仓按 SizeFor 计算跟随者名义价值（size_mode、最小/最大金额与总杠杆上限）
+		var skip bool
+		followerNotional, skip = cfg.SizeFor(sig, followerEquity)
+		if skip {
+			log.Printf("⏭ [%s] %s 跟单金额 %.2f 低于最小金额 %.2f 或无法计算，跳过",
+				at.name, sig.Symbol, followerNotional, cfg.MinAmount)
 			return nil
 		}
-		proportion := leaderMargin / sig.LeaderEquity
-		if proportion <= 0 {
+		quantity = followerNotional / marketData.CurrentPrice
+		if quantity <= 0 {
 			return nil
 		}
-		followerMargin := proportion * followerEquity * (cfg.FollowRatio / 100)
-		appliedMin := false
-		appliedMax := false
-		if cfg.MinAmount > 0 && followerMargin < cfg.MinAmount {
-			followerMargin = cfg.MinAmount
-			appliedMin = true
-		}
-		if cfg.MaxAmount > 0 && followerMargin > cfg.MaxAmount {
-			followerMargin = cfg.MaxAmount
-			appliedMax = true
+		if limit := cfg.maxSymbolBaseSizeFor(sig.Symbol); limit > 0 {
+			held := longQty
+			if copyActionSide(sig.Action) == "short" {
+				held = shortQty
+			}
+			price := sig.Price
+			if price <= 0 {
+				price = marketData.CurrentPrice
+			}
+			if capped, hit := capCopyBaseSize(quantity*marketData.CurrentPrice, price, held, limit); hit {
+				log.Printf("📏 [%s] %s 持仓数量上限 %.6f（已持有 %.6f），下单数量截断: %.6f → %.6f",
+					at.name, sig.Symbol, limit, held, quantity, capped)
+				if capped <= 0 {
+					return nil
+				}
+				quantity = capped
+			}
 		}
-		quantity = followerMargin / marketData.CurrentPrice
-		if quantity <= 0 {
-			return nil
+		if spec, ok := at.symbolSpec(sig.Symbol); ok {
+			adjusted, clamped := applyCopySymbolSpec(quantity, marketData.CurrentPrice, spec, cfg)
+			if adjusted <= 0 {
+				log.Printf("⏭ [%s] %s 订单金额 %.2f 低于交易所最小名义价值 %.2f，跳过",
+					at.name, sig.Symbol, quantity*marketData.CurrentPrice, spec.MinNotional)
+				return nil
+			}
+			if clamped {
+				log.Printf("📏 [%s] %s 订单数量按交易所最小名义价值 %.2f 抬高: %.6f → %.6f",
+					at.name, sig.Symbol, spec.MinNotional, quantity, adjusted)
+			}
+			quantity = adjusted
 		}
 		// enrich action record with sizing info
 		actionRecord.LeaderEquity = sig.LeaderEquity
 		actionRecord.LeaderNotionalUSD = sig.NotionalUSD
-		actionRecord.LeaderMarginUSD = leaderMargin
+		actionRecord.LeaderMarginUSD = sig.NotionalUSD / math.Max(1, float64(sig.LeaderLeverage))
 		if sig.Price > 0 {
 			actionRecord.LeaderPrice = sig.Price
 		} else if sig.DeltaSize != 0 {
 			actionRecord.LeaderPrice = sig.NotionalUSD / math.Abs(sig.DeltaSize)
 		}
 		actionRecord.FollowerEquity = followerEquity
-		actionRecord.FollowerMarginUSD = followerMargin
-		actionRecord.CopyRatio = cfg.FollowRatio
-		actionRecord.MinAmountApplied = appliedMin
-		actionRecord.MaxAmountApplied = appliedMax
+		actionRecord.CopyRatio = cfg.followRatioFor(sig.Symbol)
+		actionRecord.MaxAmountApplied = cfg.MaxAmount > 0 && followerNotional >= cfg.MaxAmount
 	}
 
 	execLog := []string{
-		fmt.Sprintf("信号源 %s(%s) -> %s %s, leaderEq=%.2f, notional=%.2f, followerEq=%.2f, ratio=%.2f%%, qty=%.6f",
-			at.signalSourceValue, at.signalSourceType, sig.Symbol, sig.Action, sig.LeaderEquity, sig.NotionalUSD, followerEquity, cfg.FollowRatio, quantity),
+		fmt.Sprintf("信号源 %s(%s) -> %s %s, leaderEq=%.2f, notional=%.2f, followerEq=%.2f, ratio=%.2f%%, mode=%s, qty=%.6f",
+			at.signalSourceValue, at.signalSourceType, sig.Symbol, sig.Action, sig.LeaderEquity, sig.NotionalUSD, followerEquity, cfg.followRatioFor(sig.Symbol), cfg.SizeMode, quantity),
 	}
 	leverage := at.defaultLeverageForSymbol(sig.Symbol)
 	if cfg.SyncLeverage && sig.LeaderLeverage > 0 {
 		leverage = sig.LeaderLeverage
+		// 跟随者无法承受领航员杠杆时，使用信号源预先标注的可用杠杆
+		if sig.LeverageAdjusted > 0 {
+			leverage = sig.LeverageAdjusted
+			execLog = append(execLog, fmt.Sprintf("⚠️ 杠杆已调整为 %dx: %s", leverage, sig.LeverageWarning))
+		}
+	}
+	if capped := cfg.EffectiveLeverage(leverage); capped != leverage {
+		execLog = append(execLog, fmt.Sprintf("⚠️ 杠杆 %dx 超过上限，按 max_leverage 使用 %dx", leverage, capped))
+		leverage = capped
 	}
 	actionRecord.Quantity = quantity
 	actionRecord.Leverage = leverage
+	if followerNotional > 0 {
+		actionRecord.FollowerMarginUSD = followerNotional / math.Max(1, float64(leverage))
+	}
 
-	err = at.executeCopyTrade(sig, quantity, cfg, positions, leverage)
+	err = at.executeCopyTrade(sig, quantity, marketData.CurrentPrice, cfg, positions, leverage)
 	if err != nil {
+		if isSymbolSpecRejection(err) {
+			at.refreshSymbolSpec(sig.Symbol)
+		}
 		actionRecord.Error = err.Error()
 		actionRecord.Success = false
 		execLog = append(execLog, fmt.Sprintf("❌ 执行失败: %v", err))
@@ -551,23 +746,11 @@ func (at *AutoTrader) processCopySignal(sig copytrading.Signal) error {
 	return nil
 }
 
-func (at *AutoTrader) executeCopyTrade(sig copytrading.Signal, quantity float64, cfg CopyTradingConfig, positions []map[string]interface{}, leverage int) error {
+func (at *AutoTrader) executeCopyTrade(sig copytrading.Signal, quantity, price float64, cfg CopyTradingConfig, positions []map[string]interface{}, leverage int) error {
 	longQty := getPositionQuantity(positions, sig.Symbol, "long")
 	shortQty := getPositionQuantity(positions, sig.Symbol, "short")
 
-	if cfg.SyncMarginMode && sig.MarginMode != "" {
-		isCross := strings.EqualFold(sig.MarginMode, "cross")
-		if err := at.trader.SetMarginMode(sig.Symbol, isCross); err != nil {
-			log.Printf("⚠️  设置保证金模式失败: %v", err)
-		}
-	}
-
-	// leverage/margin sync is applied before sizing execution
-	if cfg.SyncLeverage && sig.LeaderLeverage > 0 {
-		if err := at.trader.SetLeverage(sig.Symbol, leverage); err != nil {
-			log.Printf("⚠️  设置杠杆失败: %v", err)
-		}
-	}
+	at.copyPreTradeActions(sig, cfg, leverage)
 
 	var err error
 
@@ -575,41 +758,37 @@ func (at *AutoTrader) executeCopyTrade(sig copytrading.Signal, quantity float64,
 	case copytrading.ActionOpenLong:
 		fallthrough
 	case copytrading.ActionAddLong:
-		hasPosition := longQty > 0
-		if hasPosition && !cfg.FollowAdd {
-			return nil
-		}
-		if !hasPosition && !cfg.FollowOpen {
-			return nil
-		}
-		_, err = at.trader.OpenLong(sig.Symbol, quantity, leverage)
+		err = placeCopyOrderSlices(sig.Symbol, quantity, price, cfg, func(qty float64) error {
+			_, err := at.trader.OpenLong(sig.Symbol, qty, leverage)
+			return err
+		})
 	case copytrading.ActionOpenShort:
 		fallthrough
 	case copytrading.ActionAddShort:
-		hasPosition := shortQty > 0
-		if hasPosition && !cfg.FollowAdd {
-			return nil
-		}
-		if !hasPosition && !cfg.FollowOpen {
-			return nil
-		}
-		_, err = at.trader.OpenShort(sig.Symbol, quantity, leverage)
+		err = placeCopyOrderSlices(sig.Symbol, quantity, price, cfg, func(qty float64) error {
+			_, err := at.trader.OpenShort(sig.Symbol, qty, leverage)
+			return err
+		})
 	case copytrading.ActionCloseLong:
 		fallthrough
 	case copytrading.ActionReduceLong:
-		if !cfg.FollowReduce || longQty <= 0 {
+		if longQty <= 0 {
 			return nil
 		}
-		qty := math.Min(longQty, quantity)
-		_, err = at.trader.CloseLong(sig.Symbol, qty)
+		err = placeCopyOrderSlices(sig.Symbol, math.Min(longQty, quantity), price, cfg, func(qty float64) error {
+			_, err := at.trader.CloseLong(sig.Symbol, qty)
+			return err
+		})
 	case copytrading.ActionCloseShort:
 		fallthrough
 	case copytrading.ActionReduceShort:
-		if !cfg.FollowReduce || shortQty <= 0 {
+		if shortQty <= 0 {
 			return nil
 		}
-		qty := math.Min(shortQty, quantity)
-		_, err = at.trader.CloseShort(sig.Symbol, qty)
+		err = placeCopyOrderSlices(sig.Symbol, math.Min(shortQty, quantity), price, cfg, func(qty float64) error {
+			_, err := at.trader.CloseShort(sig.Symbol, qty)
+			return err
+		})
 	default:
 		return nil
 	}
@@ -617,17 +796,44 @@ func (at *AutoTrader) executeCopyTrade(sig copytrading.Signal, quantity float64,
 	if err != nil {
 		return err
 	}
+	if stop, ok := copyLiqStopPrice(sig, cfg.LiqStopBufferPct); ok {
+		side, held := "LONG", longQty
+		if copyActionSide(sig.Action) == "short" {
+			side, held = "SHORT", shortQty
+		}
+		if err := at.trader.SetStopLoss(sig.Symbol, side, held+quantity, stop); err != nil {
+			log.Printf("⚠️ [%s] %s 设置强平保护止损失败: %v", at.name, sig.Symbol, err)
+		} else {
+			log.Printf("🛡 [%s] %s 领航员强平价 %.4f，止损设于 %.4f", at.name, sig.Symbol, sig.LeaderLiqPrice, stop)
+		}
+	}
 
 	log.Printf("📡 [%s] 已复制 %s %s, 数量=%.4f", at.name, sig.Symbol, sig.Action, quantity)
 	return nil
 }
 
+// copyPreTradeActions 下单前同步保证金模式与杠杆（失败只记录日志，不阻断下单）
+func (at *AutoTrader) copyPreTradeActions(sig copytrading.Signal, cfg CopyTradingConfig, leverage int) {
+	if isCross, ok := cfg.marginModeFor(sig.Symbol, sig.MarginMode); ok {
+		if err := at.trader.SetMarginMode(sig.Symbol, isCross); err != nil {
+			log.Printf("⚠️  设置保证金模式失败: %v", err)
+		}
+	}
+
+	// leverage/margin sync is applied before sizing execution
+	if cfg.SyncLeverage && sig.LeaderLeverage > 0 {
+		if err := at.trader.SetLeverage(sig.Symbol, leverage); err != nil {
+			log.Printf("⚠️  设置杠杆失败: %v", err)
+		}
+	}
+}
+
 // runCycle 运行一个交易周期（使用AI全权决策）
 func (at *AutoTrader) runCycle() error {
 	at.callCount++
 
 	log.Print("\n" + strings.Repeat("=", 70) + "\n")
-	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
+	log.Printf("⏰ %s - AI决策周期 #%d", at.now().Format("2006-01-02 15:04:05"), at.callCount)
 	log.Println(strings.Repeat("=", 70))
 
 	// 创建决策记录
@@ -637,8 +843,8 @@ func (at *AutoTrader) runCycle() error {
 	}
 
 	// 1. 检查是否需要停止交易
-	if time.Now().Before(at.stopUntil) {
-		remaining := at.stopUntil.Sub(time.Now())
+	if at.now().Before(at.stopUntil) {
+		remaining := at.stopUntil.Sub(at.now())
 		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
@@ -647,9 +853,9 @@ func (at *AutoTrader) runCycle() error {
 	}
 
 	// 2. 重置日盈亏（每天重置）
-	if time.Since(at.lastResetTime) > 24*time.Hour {
+	if at.since(at.lastResetTime) > 24*time.Hour {
 		at.dailyPnL = 0
-		at.lastResetTime = time.Now()
+		at.lastResetTime = at.now()
 		log.Println("📅 日盈亏已重置")
 	}
 
@@ -786,7 +992,7 @@ func (at *AutoTrader) runCycle() error {
 			Quantity:  0,
 			Leverage:  d.Leverage,
 			Price:     0,
-			Timestamp: time.Now(),
+			Timestamp: at.now(),
 			Success:   false,
 		}
 
@@ -884,7 +1090,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		currentPositionKeys[posKey] = true
 		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
 			// 新持仓，记录当前时间
-			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+			at.positionFirstSeenTime[posKey] = at.now().UnixMilli()
 		}
 		updateTime := at.positionFirstSeenTime[posKey]
 
@@ -945,8 +1151,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 	// 6. 构建上下文
 	ctx := &decision.Context{
-		CurrentTime:     time.Now().Format("2006-01-02 15:04:05"),
-		RuntimeMinutes:  int(time.Since(at.startTime).Minutes()),
+		CurrentTime:     at.now().Format("2006-01-02 15:04:05"),
+		RuntimeMinutes:  int(at.since(at.startTime).Minutes()),
 		CallCount:       at.callCount,
 		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
 		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
@@ -1060,7 +1266,7 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_long"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionFirstSeenTime[posKey] = at.now().UnixMilli()
 
 	// 设置止损止盈
 	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
@@ -1140,7 +1346,7 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_short"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionFirstSeenTime[posKey] = at.now().UnixMilli()
 
 	// 设置止损止盈
 	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
@@ -1552,14 +1758,14 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		aiProvider = "Qwen"
 	}
 
-	return map[string]interface{}{
+	status := map[string]interface{}{
 		"trader_id":          at.id,
 		"trader_name":        at.name,
 		"ai_model":           at.aiModel,
 		"exchange":           at.exchange,
 		"is_running":         at.isRunning,
 		"start_time":         at.startTime.Format(time.RFC3339),
-		"runtime_minutes":    int(time.Since(at.startTime).Minutes()),
+		"runtime_minutes":    int(at.since(at.startTime).Minutes()),
 		"call_count":         at.callCount,
 		"initial_balance":    at.initialBalance,
 		"scan_interval":      at.config.ScanInterval.String(),
@@ -1569,6 +1775,14 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"signal_source_type": at.signalSourceType,
 		"signal_source_value": at.signalSourceValue,
 	}
+	if at.signalSourceType != "ai" {
+		if cursor, initialized, ok := defaultCopySignalHub.cursor(at.copySourceConfig()); ok {
+			status["copy_cursor"] = cursor
+			status["copy_initialized"] = initialized
+		}
+		status["copy_loss_breaker_tripped"] = !at.copyLossStreak().allowOpen()
+	}
+	return status
 }
 
 // GetAccountInfo 获取账户信息（用于API）
@@ -1964,7 +2178,7 @@ func (at *AutoTrader) getCopyAccountSnapshot() (logger.AccountSnapshot, error) {
 
 func (at *AutoTrader) logCopyDecision(snapshot logger.AccountSnapshot, action logger.DecisionAction, execLog []string, success bool) {
 	record := &logger.DecisionRecord{
-		Timestamp:     time.Now(),
+		Timestamp:     at.now(),
 		AccountState:  snapshot,
 		Decisions:     []logger.DecisionAction{action},
 		ExecutionLog:  execLog,
@@ -822,6 +822,41 @@ func (t *FuturesTrader) CheckMinNotional(symbol string, quantity float64) error
 	return nil
 }
 
+// GetSymbolSpec 获取交易对的下单规则（最小名义价值、数量步进、价格步进）
+func (t *FuturesTrader) GetSymbolSpec(symbol string) (SymbolSpec, error) {
+	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return SymbolSpec{}, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+
+	for _, s := range exchangeInfo.Symbols {
+		if s.Symbol != symbol {
+			continue
+		}
+		spec := SymbolSpec{MinNotional: t.GetMinNotional(symbol)}
+		for _, filter := range s.Filters {
+			switch filter["filterType"] {
+			case "LOT_SIZE":
+				if v, ok := filter["stepSize"].(string); ok {
+					spec.StepSize, _ = strconv.ParseFloat(v, 64)
+				}
+			case "PRICE_FILTER":
+				if v, ok := filter["tickSize"].(string); ok {
+					spec.TickSize, _ = strconv.ParseFloat(v, 64)
+				}
+			case "MIN_NOTIONAL":
+				if v, ok := filter["notional"].(string); ok {
+					if n, err := strconv.ParseFloat(v, 64); err == nil && n > 0 {
+						spec.MinNotional = n
+					}
+				}
+			}
+		}
+		return spec, nil
+	}
+	return SymbolSpec{}, fmt.Errorf("未找到交易对 %s", symbol)
+}
+
 // GetSymbolPrecision 获取交易对的数量精度
 func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
 	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
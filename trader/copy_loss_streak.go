@@ -0,0 +1,92 @@
+package trader
+
+import (
+	"math"
+	"sync"
+
+	"nofx/copytrading"
+)
+
+// copyLossStreak 统计领航员连续亏损平仓次数（按信号价格估算已实现盈亏），
+// 达到 MaxConsecutiveLosses 后停止跟随新开/加仓（平仓照常跟随），
+// 直到出现盈利平仓或手动重置
+type copyLossStreak struct {
+	mu          sync.Mutex
+	positions   map[string]*leaderLot // 领航员持仓均价（按币种）
+	consecutive int
+	tripped     bool
+}
+
+type leaderLot struct {
+	size     float64 // 带符号数量
+	avgPrice float64
+	realized float64 // 本轮持仓已实现盈亏（含减仓）
+}
+
+func newCopyLossStreak() *copyLossStreak {
+	return &copyLossStreak{positions: make(map[string]*leaderLot)}
+}
+
+// observe 记录领航员信号；返回平仓时本轮持仓的已实现盈亏（closed=true）
+func (s *copyLossStreak) observe(sig copytrading.Signal, maxLosses int) (pnl float64, closed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sig.Price <= 0 || sig.DeltaSize == 0 {
+		return 0, false
+	}
+	lot := s.positions[sig.Symbol]
+	switch sig.Action {
+	case copytrading.ActionOpenLong, copytrading.ActionOpenShort, copytrading.ActionAddLong, copytrading.ActionAddShort:
+		if lot == nil || lot.size == 0 {
+			lot = &leaderLot{}
+			s.positions[sig.Symbol] = lot
+		}
+		newSize := lot.size + sig.DeltaSize
+		lot.avgPrice = (math.Abs(lot.size)*lot.avgPrice + math.Abs(sig.DeltaSize)*sig.Price) / math.Abs(newSize)
+		lot.size = newSize
+		return 0, false
+	}
+
+	// 减/平仓：未见过开仓（如启动前已持仓）时无法计算盈亏
+	if lot == nil || lot.size == 0 {
+		return 0, false
+	}
+	closedQty := math.Min(math.Abs(sig.DeltaSize), math.Abs(lot.size))
+	dir := 1.0
+	if lot.size < 0 {
+		dir = -1
+	}
+	lot.realized += (sig.Price - lot.avgPrice) * closedQty * dir
+	lot.size -= closedQty * dir
+	if sig.Action != copytrading.ActionCloseLong && sig.Action != copytrading.ActionCloseShort && math.Abs(lot.size) > 1e-12 {
+		return 0, false
+	}
+
+	pnl = lot.realized
+	delete(s.positions, sig.Symbol)
+	if pnl < 0 {
+		s.consecutive++
+		if maxLosses > 0 && s.consecutive >= maxLosses {
+			s.tripped = true
+		}
+	} else {
+		s.consecutive = 0
+		s.tripped = false
+	}
+	return pnl, true
+}
+
+// allowOpen 熔断后禁止新开/加仓
+func (s *copyLossStreak) allowOpen() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.tripped
+}
+
+func (s *copyLossStreak) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.consecutive = 0
+	s.tripped = false
+}
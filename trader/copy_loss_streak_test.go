@@ -0,0 +1,81 @@
+package trader
+
+import (
+	"testing"
+
+	"nofx/copytrading"
+)
+
+func leaderRoundTrip(s *copyLossStreak, symbol string, entry, exit float64, maxLosses int) (float64, bool) {
+	s.observe(copytrading.Signal{Symbol: symbol, Action: copytrading.ActionOpenLong, Price: entry, DeltaSize: 2, LeaderPosAfter: 2}, maxLosses)
+	s.observe(copytrading.Signal{Symbol: symbol, Action: copytrading.ActionReduceLong, Price: exit, DeltaSize: -1, LeaderPosBefore: 2, LeaderPosAfter: 1}, maxLosses)
+	return s.observe(copytrading.Signal{Symbol: symbol, Action: copytrading.ActionCloseLong, Price: exit, DeltaSize: -1, LeaderPosBefore: 1}, maxLosses)
+}
+
+func TestCopyLossStreak_TripsAfterConsecutiveLosses(t *testing.T) {
+	s := newCopyLossStreak()
+
+	if pnl, closed := leaderRoundTrip(s, "BTCUSDT", 100, 90, 3); !closed || pnl != -20 {
+		t.Fatalf("expected realized -20 on close, got %v closed=%v", pnl, closed)
+	}
+	leaderRoundTrip(s, "ETHUSDT", 10, 9, 3)
+	if !s.allowOpen() {
+		t.Fatal("two losses must not trip a limit of three")
+	}
+	leaderRoundTrip(s, "SOLUSDT", 50, 40, 3)
+	if s.allowOpen() {
+		t.Fatal("expected the switch to trip on the third consecutive loss")
+	}
+
+	// 熔断期间平仓照常统计，盈利平仓解除熔断
+	leaderRoundTrip(s, "BTCUSDT", 100, 110, 3)
+	if !s.allowOpen() {
+		t.Fatal("a winning close must reset the switch")
+	}
+
+	leaderRoundTrip(s, "BTCUSDT", 100, 90, 1)
+	if s.allowOpen() {
+		t.Fatal("expected trip at a limit of one")
+	}
+	s.reset()
+	if !s.allowOpen() {
+		t.Fatal("manual reset must re-enable opens")
+	}
+}
+
+func TestProcessCopySignal_SkipsOpensWhileTripped(t *testing.T) {
+	cfg := DefaultCopyTradingConfig()
+	cfg.MaxConsecutiveLosses = 1
+	at := &AutoTrader{name: "copy", copyTradingConfig: cfg}
+	leaderRoundTrip(at.copyLossStreak(), "BTCUSDT", 100, 90, 1)
+
+	// 熔断时开仓信号在访问交易所之前即被跳过（trader 为 nil 也不会触发）
+	open := copytrading.Signal{Symbol: "ETHUSDT", Action: copytrading.ActionOpenLong, Price: 10, NotionalUSD: 100, LeaderEquity: 1000, DeltaSize: 10}
+	if err := at.processCopySignal(open); err != nil {
+		t.Fatalf("expected open skipped silently, got %v", err)
+	}
+}
+
+func TestResetCopyLossStreak_ClearsTrippedBreaker(t *testing.T) {
+	cfg := DefaultCopyTradingConfig()
+	cfg.MaxConsecutiveLosses = 1
+	at, rec := newCopyTestTrader(t, cfg, 10, nil)
+	at.signalSourceType = "okx_wallet"
+	at.signalSourceValue = "leader"
+	leaderRoundTrip(at.copyLossStreak(), "BTCUSDT", 100, 90, 1)
+	if tripped, _ := at.GetStatus()["copy_loss_breaker_tripped"].(bool); !tripped {
+		t.Fatal("expected status to report the tripped breaker")
+	}
+
+	at.ResetCopyLossStreak()
+	if tripped, _ := at.GetStatus()["copy_loss_breaker_tripped"].(bool); tripped {
+		t.Fatal("expected status to report the breaker cleared after reset")
+	}
+	open := copytrading.Signal{Symbol: "ETHUSDT", Action: copytrading.ActionOpenLong, Price: 10, NotionalUSD: 100, LeaderEquity: 10000, DeltaSize: 10}
+	if err := at.processCopySignal(open); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.orders) != 1 || rec.orders[0].action != "open_long" {
+		t.Fatalf("expected opens followed again after reset, got %+v", rec.orders)
+	}
+}
@@ -0,0 +1,131 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"nofx/copytrading"
+)
+
+// copySignalHub 在多个跟单交易员之间共享同一信号源，避免对同一领航员重复轮询
+type copySignalHub struct {
+	mu      sync.Mutex
+	sources map[string]*sharedCopySource
+	// newProvider 可在测试中替换
+	newProvider func(cfg copytrading.Config) (copytrading.Provider, error)
+}
+
+type sharedCopySource struct {
+	provider copytrading.Provider
+	fanout   *copytrading.FanOut
+	cancel   context.CancelFunc
+	refs     int
+}
+
+var defaultCopySignalHub = &copySignalHub{
+	sources:     make(map[string]*sharedCopySource),
+	newProvider: copytrading.NewProvider,
+}
+
+// copySourceKey 同一领航员、同一跟随模式（及调仓阈值）共享一个信号源
+func copySourceKey(cfg copytrading.Config) string {
+	key := strings.ToLower(cfg.Type) + ":" + strings.ToLower(strings.TrimSpace(cfg.Identifier))
+	if cfg.Mode == copytrading.ModeNet {
+		key += fmt.Sprintf(":net:%g", cfg.RebalanceThreshold)
+	}
+	return key
+}
+
+// subscribe 订阅信号源；同一领航员只会启动一个 provider。返回的函数用于取消订阅，
+// 最后一个订阅者退出时停止 provider。
+func (h *copySignalHub) subscribe(cfg copytrading.Config) (<-chan copytrading.Signal, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := copySourceKey(cfg)
+	src, ok := h.sources[key]
+	if !ok {
+		provider, err := h.newProvider(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		src = &sharedCopySource{
+			provider: provider,
+			fanout:   copytrading.NewFanOut(128),
+			cancel:   cancel,
+		}
+		in := make(chan copytrading.Signal, 128)
+		go func() {
+			if err := provider.Run(ctx, in); err != nil {
+				log.Printf("❌ 复制信号源 %s 异常退出: %v", key, err)
+			}
+			close(in)
+			h.mu.Lock()
+			if h.sources[key] == src {
+				delete(h.sources, key)
+			}
+			h.mu.Unlock()
+		}()
+		go src.fanout.Run(in)
+		h.sources[key] = src
+		log.Printf("🛰 启动共享复制信号源: %s", key)
+	}
+
+	src.refs++
+	ch, unsubscribe := src.fanout.Subscribe()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			unsubscribe()
+			h.release(key, src)
+		})
+	}
+	return ch, release, nil
+}
+
+func (h *copySignalHub) release(key string, src *sharedCopySource) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	src.refs--
+	if src.refs > 0 {
+		return
+	}
+	src.cancel()
+	if h.sources[key] == src {
+		delete(h.sources, key)
+	}
+	log.Printf("⏹ 共享复制信号源已停止: %s", key)
+}
+
+// subscribers 返回某信号源当前的订阅数（用于状态展示/测试）
+func (h *copySignalHub) subscribers(cfg copytrading.Config) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if src, ok := h.sources[copySourceKey(cfg)]; ok {
+		return src.refs
+	}
+	return 0
+}
+
+// cursor 返回信号源的游标与初始化状态（用于排查漏单）
+func (h *copySignalHub) cursor(cfg copytrading.Config) (cursor int64, initialized bool, ok bool) {
+	h.mu.Lock()
+	src, exists := h.sources[copySourceKey(cfg)]
+	h.mu.Unlock()
+	if !exists {
+		return 0, false, false
+	}
+	reporter, isReporter := src.provider.(copytrading.CursorReporter)
+	if !isReporter {
+		return 0, false, false
+	}
+	return reporter.Cursor(), reporter.Initialized(), true
+}
+
+var errCopySourceClosed = fmt.Errorf("复制信号源已关闭")
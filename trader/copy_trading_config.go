@@ -2,7 +2,11 @@ package trader
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"strings"
+
+	"nofx/copytrading"
 )
 
 // CopyTradingConfig 描述前端配置的定比跟单参数
@@ -11,12 +15,61 @@ type CopyTradingConfig struct {
 	FollowAdd      bool    `json:"follow_add"`
 	FollowReduce   bool    `json:"follow_reduce"`
 	FollowRatio    float64 `json:"follow_ratio"`
-	MinAmount      float64 `json:"min_amount"`
-	MaxAmount      float64 `json:"max_amount"`
+	MinAmount      float64 `json:"min_amount"` // 跟随者名义价值低于该值时跳过本次开/加仓（旧版为保证金下限）
+	MaxAmount      float64 `json:"max_amount"` // 跟随者名义价值上限（旧版为保证金上限）
 	SyncLeverage   bool    `json:"sync_leverage"`
 	SyncMarginMode bool    `json:"sync_margin_mode"`
+	// SymbolRatios 按币种覆盖跟单比例（百分比，键为 BTCUSDT 形式），未配置的币种使用 FollowRatio
+	SymbolRatios map[string]float64 `json:"symbol_ratios,omitempty"`
+	// SizeMode ratio（按领航员名义价值定比）/ equity（再按跟随者与领航员净值之比缩放）。
+	// DefaultCopyTradingConfig 为 ratio；数据库中没有 size_mode 的配置（该字段引入前保存的
+	// 旧配置）由 ParseCopyTradingConfig 按 equity 解析，延续旧版按净值比例跟单
+	SizeMode string `json:"size_mode,omitempty"`
+	// MaxLeverage 跟随者实际使用杠杆的上限（0 表示不限制），无论是否同步领航员杠杆都生效
+	MaxLeverage int `json:"max_leverage,omitempty"`
+	// Reverse 反向跟单：开/加/减/平多与空互换，持有与领航员相反的仓位
+	Reverse bool `json:"reverse,omitempty"`
+	// MaxOrderNotional 交易所单笔订单名义价值上限（按币种，"*" 表示所有币种），
+	// 与 MaxAmount（风控上限）不同，超出时按 OrderLimitMode 拆单或截断
+	MaxOrderNotional map[string]float64 `json:"max_order_notional,omitempty"`
+	OrderLimitMode   string             `json:"order_limit_mode,omitempty"` // split（默认）/ clamp
+	// MaxSymbolBaseSize 单币种同向持仓数量上限（单位为币，"*" 表示所有币种），
+	// 开/加仓后持仓超出时截断本次下单数量，是 MaxAmount 等金额上限的数量维度补充
+	MaxSymbolBaseSize map[string]float64 `json:"max_symbol_base_size,omitempty"`
+	// FollowMode trade（默认，逐笔跟随）/ net（只跟随净仓位，适合网格/DCA 类领航员）
+	FollowMode string `json:"follow_mode,omitempty"`
+	// RebalanceThresholdPct net 模式下净仓位相对变化低于该百分比时不调仓
+	RebalanceThresholdPct float64 `json:"rebalance_threshold_pct,omitempty"`
+	// ActionRemap 在计算仓位前替换信号动作，如 reduce_long→close_long（只能在同一方向内映射）
+	ActionRemap map[copytrading.SignalAction]copytrading.SignalAction `json:"action_remap,omitempty"`
+	// MarginModeOverrides 按币种强制保证金模式（cross/isolated，"*" 表示所有币种），
+	// 优先于领航员模式与 SyncMarginMode
+	MarginModeOverrides map[string]string `json:"margin_mode_overrides,omitempty"`
+	// MaxTotalLeverage 跟单总敞口上限（相对跟随者净值的倍数，0 表示不限制）；
+	// 领航员整本仓位按比例映射后超出时，所有币种按同一系数等比缩小
+	MaxTotalLeverage float64 `json:"max_total_leverage,omitempty"`
+	// MaxConsecutiveLosses 领航员连续亏损平仓达到该次数后停止跟随新开/加仓
+	// （平仓照常跟随），出现盈利平仓或手动重置后恢复；0 表示不启用
+	MaxConsecutiveLosses int `json:"max_consecutive_losses,omitempty"`
+	// EquitySmoothing 领航员净值的指数移动平均系数（0~1，新值权重，越小越平滑；
+	// 0 表示不平滑），避免未实现盈亏波动导致相邻订单规模忽大忽小
+	EquitySmoothing float64 `json:"equity_smoothing,omitempty"`
+	// LiqStopBufferPct 开/加仓后在领航员强平价内侧设置止损：止损价位于强平价与
+	// 领航员成交价之间，距强平价为两者距离的该百分比（0~100，0 表示不设置）
+	LiqStopBufferPct float64 `json:"liq_stop_buffer_pct,omitempty"`
 }
 
+const (
+	OrderLimitSplit = "split"
+	OrderLimitClamp = "clamp"
+
+	FollowModeTrade = copytrading.ModeTrade
+	FollowModeNet   = copytrading.ModeNet
+
+	SizeModeRatio  = "ratio"
+	SizeModeEquity = "equity"
+)
+
 // DefaultCopyTradingConfig 返回默认参数
 func DefaultCopyTradingConfig() CopyTradingConfig {
 	return CopyTradingConfig{
@@ -31,18 +84,101 @@ func DefaultCopyTradingConfig() CopyTradingConfig {
 	}
 }
 
-// ParseCopyTradingConfig 解析数据库中的JSON，无法解析时返回默认值
+// ParseCopyTradingConfig 解析数据库中的JSON，无法解析时返回默认值；
+// 未保存 size_mode 的旧配置按 equity 模式解析
 func ParseCopyTradingConfig(raw string) CopyTradingConfig {
 	cfg := DefaultCopyTradingConfig()
 	if strings.TrimSpace(raw) == "" {
 		return cfg
 	}
+	cfg.SizeMode = SizeModeEquity
 	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
-		return cfg
+		return DefaultCopyTradingConfig()
 	}
 	return normalizeCopyTradingConfig(cfg)
 }
 
+// validateCopyTradingConfig 校验外部传入的配置（热更新前调用，拒绝明显错误的参数）
+func validateCopyTradingConfig(cfg CopyTradingConfig) error {
+	if cfg.FollowRatio < 0 {
+		return fmt.Errorf("follow_ratio 不能为负数: %.2f", cfg.FollowRatio)
+	}
+	if cfg.MinAmount < 0 || cfg.MaxAmount < 0 {
+		return fmt.Errorf("min_amount/max_amount 不能为负数")
+	}
+	for symbol, ratio := range cfg.SymbolRatios {
+		if ratio < 0 {
+			return fmt.Errorf("symbol_ratios[%s] 不能为负数: %.2f", symbol, ratio)
+		}
+	}
+	if cfg.MinAmount > 0 && cfg.MaxAmount > 0 && cfg.MinAmount > cfg.MaxAmount {
+		return fmt.Errorf("min_amount(%.2f) 不能大于 max_amount(%.2f)", cfg.MinAmount, cfg.MaxAmount)
+	}
+	if !cfg.FollowOpen && !cfg.FollowAdd && !cfg.FollowReduce {
+		return fmt.Errorf("至少需要开启一种跟单动作")
+	}
+	for symbol, limit := range cfg.MaxOrderNotional {
+		if limit < 0 {
+			return fmt.Errorf("max_order_notional[%s] 不能为负数", symbol)
+		}
+	}
+	for symbol, limit := range cfg.MaxSymbolBaseSize {
+		if limit < 0 {
+			return fmt.Errorf("max_symbol_base_size[%s] 不能为负数", symbol)
+		}
+	}
+	switch strings.ToLower(cfg.OrderLimitMode) {
+	case "", OrderLimitSplit, OrderLimitClamp:
+	default:
+		return fmt.Errorf("未知的 order_limit_mode: %s", cfg.OrderLimitMode)
+	}
+	switch strings.ToLower(cfg.FollowMode) {
+	case "", FollowModeTrade, FollowModeNet:
+	default:
+		return fmt.Errorf("未知的 follow_mode: %s", cfg.FollowMode)
+	}
+	switch strings.ToLower(cfg.SizeMode) {
+	case "", SizeModeRatio, SizeModeEquity:
+	default:
+		return fmt.Errorf("未知的 size_mode: %s", cfg.SizeMode)
+	}
+	for symbol, mode := range cfg.MarginModeOverrides {
+		switch strings.ToLower(strings.TrimSpace(mode)) {
+		case "cross", "isolated":
+		default:
+			return fmt.Errorf("margin_mode_overrides[%s] 只能是 cross 或 isolated: %s", symbol, mode)
+		}
+	}
+	for from, to := range cfg.ActionRemap {
+		fromSide, toSide := copyActionSide(from), copyActionSide(to)
+		if fromSide == "" || toSide == "" {
+			return fmt.Errorf("action_remap 包含未知动作: %s→%s", from, to)
+		}
+		if fromSide != toSide {
+			return fmt.Errorf("action_remap 不能跨方向映射: %s→%s", from, to)
+		}
+	}
+	if cfg.EquitySmoothing < 0 || cfg.EquitySmoothing > 1 {
+		return fmt.Errorf("equity_smoothing 需在 0~1 之间: %.2f", cfg.EquitySmoothing)
+	}
+	if cfg.MaxLeverage < 0 {
+		return fmt.Errorf("max_leverage 不能为负数: %d", cfg.MaxLeverage)
+	}
+	if cfg.MaxConsecutiveLosses < 0 {
+		return fmt.Errorf("max_consecutive_losses 不能为负数: %d", cfg.MaxConsecutiveLosses)
+	}
+	if cfg.LiqStopBufferPct < 0 || cfg.LiqStopBufferPct >= 100 {
+		return fmt.Errorf("liq_stop_buffer_pct 需在 0~100 之间: %.2f", cfg.LiqStopBufferPct)
+	}
+	if cfg.MaxTotalLeverage < 0 {
+		return fmt.Errorf("max_total_leverage 不能为负数: %.2f", cfg.MaxTotalLeverage)
+	}
+	if cfg.RebalanceThresholdPct < 0 || cfg.RebalanceThresholdPct >= 100 {
+		return fmt.Errorf("rebalance_threshold_pct 需在 0~100 之间: %.2f", cfg.RebalanceThresholdPct)
+	}
+	return nil
+}
+
 func normalizeCopyTradingConfig(cfg CopyTradingConfig) CopyTradingConfig {
 	defaultCfg := DefaultCopyTradingConfig()
 	if cfg.FollowRatio <= 0 {
@@ -54,6 +190,71 @@ func normalizeCopyTradingConfig(cfg CopyTradingConfig) CopyTradingConfig {
 	if cfg.MinAmount < 0 {
 		cfg.MinAmount = 0
 	}
+	if cfg.MaxTotalLeverage < 0 {
+		cfg.MaxTotalLeverage = 0
+	}
+	if cfg.MaxLeverage < 0 {
+		cfg.MaxLeverage = 0
+	}
+	if cfg.EquitySmoothing < 0 || cfg.EquitySmoothing > 1 {
+		cfg.EquitySmoothing = 0
+	}
+	if cfg.LiqStopBufferPct < 0 || cfg.LiqStopBufferPct >= 100 {
+		cfg.LiqStopBufferPct = 0
+	}
+	if len(cfg.MaxOrderNotional) > 0 {
+		limits := make(map[string]float64, len(cfg.MaxOrderNotional))
+		for symbol, limit := range cfg.MaxOrderNotional {
+			if limit > 0 {
+				limits[strings.ToUpper(strings.TrimSpace(symbol))] = limit
+			}
+		}
+		cfg.MaxOrderNotional = limits
+	}
+	if len(cfg.SymbolRatios) > 0 {
+		ratios := make(map[string]float64, len(cfg.SymbolRatios))
+		for symbol, ratio := range cfg.SymbolRatios {
+			if ratio > 0 {
+				ratios[strings.ToUpper(strings.TrimSpace(symbol))] = ratio
+			}
+		}
+		cfg.SymbolRatios = ratios
+	}
+	if len(cfg.MaxSymbolBaseSize) > 0 {
+		limits := make(map[string]float64, len(cfg.MaxSymbolBaseSize))
+		for symbol, limit := range cfg.MaxSymbolBaseSize {
+			if limit > 0 {
+				limits[strings.ToUpper(strings.TrimSpace(symbol))] = limit
+			}
+		}
+		cfg.MaxSymbolBaseSize = limits
+	}
+	if len(cfg.MarginModeOverrides) > 0 {
+		overrides := make(map[string]string, len(cfg.MarginModeOverrides))
+		for symbol, mode := range cfg.MarginModeOverrides {
+			mode = strings.ToLower(strings.TrimSpace(mode))
+			if mode == "cross" || mode == "isolated" {
+				overrides[strings.ToUpper(strings.TrimSpace(symbol))] = mode
+			}
+		}
+		cfg.MarginModeOverrides = overrides
+	}
+	cfg.OrderLimitMode = strings.ToLower(cfg.OrderLimitMode)
+	if cfg.OrderLimitMode != OrderLimitClamp {
+		cfg.OrderLimitMode = OrderLimitSplit
+	}
+	cfg.FollowMode = strings.ToLower(cfg.FollowMode)
+	if cfg.FollowMode != FollowModeNet {
+		cfg.FollowMode = FollowModeTrade
+		cfg.RebalanceThresholdPct = 0
+	}
+	if cfg.RebalanceThresholdPct < 0 || cfg.RebalanceThresholdPct >= 100 {
+		cfg.RebalanceThresholdPct = 0
+	}
+	cfg.SizeMode = strings.ToLower(cfg.SizeMode)
+	if cfg.SizeMode != SizeModeEquity {
+		cfg.SizeMode = SizeModeRatio
+	}
 	if !cfg.FollowOpen && !cfg.FollowAdd && !cfg.FollowReduce {
 		cfg.FollowOpen = defaultCfg.FollowOpen
 		cfg.FollowAdd = defaultCfg.FollowAdd
@@ -61,3 +262,248 @@ func normalizeCopyTradingConfig(cfg CopyTradingConfig) CopyTradingConfig {
 	}
 	return cfg
 }
+
+// copyBudgetScale 返回统一缩放系数：领航员整本仓位按 SizeFor 的规则映射后，跟随者的
+// 总杠杆不超过 MaxTotalLeverage（ratio 模式相对跟随者净值，equity 模式等价于领航员整本杠杆
+// × 跟单比例）。系数只取决于领航员整本仓位，因此对所有币种一致；未配置或无法判断时返回 1
+func (c CopyTradingConfig) copyBudgetScale(leaderBookNotional, leaderEquity, followerEquity float64) float64 {
+	base := followerEquity
+	if strings.EqualFold(c.SizeMode, SizeModeEquity) {
+		base = leaderEquity
+	}
+	if c.MaxTotalLeverage <= 0 || leaderBookNotional <= 0 || base <= 0 {
+		return 1
+	}
+	ratio := c.FollowRatio / 100
+	if ratio <= 0 {
+		ratio = 1
+	}
+	mirrored := leaderBookNotional / base * ratio
+	if mirrored <= c.MaxTotalLeverage {
+		return 1
+	}
+	return c.MaxTotalLeverage / mirrored
+}
+
+// SizeFor 按定比跟单计算跟随者本次下单的名义价值（USD）：领航员成交名义价值 ×
+// 跟单比例（followRatioFor），equity 模式下再乘以 followerEquity/LeaderEquity，
+// 使资金为领航员 1/10 的跟随者自动只跟 1/10 仓位；随后按 MaxTotalLeverage 统一缩放。
+// 超过 MaxAmount 时截断到上限；低于 MinAmount 时返回 skip=true，表示金额过小不值得跟随
+// （不会被抬高到 MinAmount）。为 0 的上下限不生效。跟随者净值不可用（<=0）、信号没有
+// 名义价值或 equity 模式下领航员净值未知时同样跳过。实盘开/加仓只经由该方法计算金额
+func (c CopyTradingConfig) SizeFor(sig copytrading.Signal, followerEquity float64) (usd float64, skip bool) {
+	if followerEquity <= 0 || sig.NotionalUSD <= 0 {
+		return 0, true
+	}
+	usd = math.Abs(sig.NotionalUSD) * c.followRatioFor(sig.Symbol) / 100
+	if strings.EqualFold(c.SizeMode, SizeModeEquity) {
+		if sig.LeaderEquity <= 0 {
+			return 0, true
+		}
+		usd *= followerEquity / sig.LeaderEquity
+	}
+	usd *= c.copyBudgetScale(sig.LeaderBookNotionalUSD, sig.LeaderEquity, followerEquity)
+	if c.MaxAmount > 0 && usd > c.MaxAmount {
+		usd = c.MaxAmount
+	}
+	if c.MinAmount > 0 && usd < c.MinAmount {
+		return usd, true
+	}
+	return usd, usd <= 0
+}
+
+// copyLiqStopPrice 根据领航员强平价推导跟随者止损价：多头止损位于强平价上方、
+// 空头位于下方，距强平价为其到领航员成交价距离的 bufferPct%。
+// 信号不是开/加仓、缺少强平价或强平价不在成交价亏损一侧时返回 ok=false
+func copyLiqStopPrice(sig copytrading.Signal, bufferPct float64) (stop float64, ok bool) {
+	if bufferPct <= 0 || bufferPct >= 100 || sig.LeaderLiqPrice <= 0 || sig.Price <= 0 || !isCopyEntry(sig.Action) {
+		return 0, false
+	}
+	switch copyActionSide(sig.Action) {
+	case "long":
+		if sig.LeaderLiqPrice >= sig.Price {
+			return 0, false
+		}
+	case "short":
+		if sig.LeaderLiqPrice <= sig.Price {
+			return 0, false
+		}
+	}
+	return sig.LeaderLiqPrice + (sig.Price-sig.LeaderLiqPrice)*bufferPct/100, true
+}
+
+// copyActionSide 返回动作所属方向（long/short），未知动作返回空字符串
+func copyActionSide(action copytrading.SignalAction) string {
+	switch action {
+	case copytrading.ActionOpenLong, copytrading.ActionAddLong, copytrading.ActionReduceLong, copytrading.ActionCloseLong:
+		return "long"
+	case copytrading.ActionOpenShort, copytrading.ActionAddShort, copytrading.ActionReduceShort, copytrading.ActionCloseShort:
+		return "short"
+	}
+	return ""
+}
+
+// EffectiveLeverage 返回跟随者实际使用的杠杆：配置了 MaxLeverage 时取其与 leaderLev
+// 的较小值，否则原样返回 leaderLev
+func (c CopyTradingConfig) EffectiveLeverage(leaderLev int) int {
+	if c.MaxLeverage > 0 && leaderLev > c.MaxLeverage {
+		return c.MaxLeverage
+	}
+	return leaderLev
+}
+
+// ShouldFollow 按 FollowOpen / FollowAdd / FollowReduce 判断是否跟随该信号：
+// 开/加仓以跟随者自身持仓区分（hasPosition 为跟随者在该方向已有仓位）——没有仓位时看
+// FollowOpen，已有仓位时看 FollowAdd；减仓与平仓都看 FollowReduce，
+// 即开启 FollowReduce 时领航员的完全平仓总会被跟随。
+// set_position 目标仓位由调仓逻辑自行比较，始终放行；未知动作不跟随
+func (c CopyTradingConfig) ShouldFollow(sig copytrading.Signal, hasPosition bool) bool {
+	switch sig.Action {
+	case copytrading.ActionOpenLong, copytrading.ActionOpenShort, copytrading.ActionAddLong, copytrading.ActionAddShort:
+		if hasPosition {
+			return c.FollowAdd
+		}
+		return c.FollowOpen
+	case copytrading.ActionReduceLong, copytrading.ActionReduceShort, copytrading.ActionCloseLong, copytrading.ActionCloseShort:
+		return c.FollowReduce
+	case copytrading.ActionSetPosition:
+		return true
+	}
+	return false
+}
+
+// reversedActions 反向跟单时的动作映射
+var reversedActions = map[copytrading.SignalAction]copytrading.SignalAction{
+	copytrading.ActionOpenLong:    copytrading.ActionOpenShort,
+	copytrading.ActionOpenShort:   copytrading.ActionOpenLong,
+	copytrading.ActionAddLong:     copytrading.ActionAddShort,
+	copytrading.ActionAddShort:    copytrading.ActionAddLong,
+	copytrading.ActionReduceLong:  copytrading.ActionReduceShort,
+	copytrading.ActionReduceShort: copytrading.ActionReduceLong,
+	copytrading.ActionCloseLong:   copytrading.ActionCloseShort,
+	copytrading.ActionCloseShort:  copytrading.ActionCloseLong,
+}
+
+// Transform 在 Reverse 开启时把信号翻转为反方向：动作多空互换，名义价值不变，
+// DeltaSize/LeaderPosBefore/LeaderPosAfter/TargetSize 取反。领航员反手产生的
+// 平多+开空会依次翻转为平空+开多，与跟随者持有的反向仓位一致。
+// 领航员强平价对反向仓位没有意义，会被清除；未开启时原样返回
+func (c CopyTradingConfig) Transform(sig copytrading.Signal) copytrading.Signal {
+	if !c.Reverse {
+		return sig
+	}
+	if action, ok := reversedActions[sig.Action]; ok {
+		sig.Action = action
+	}
+	sig.DeltaSize = -sig.DeltaSize
+	sig.LeaderPosBefore = -sig.LeaderPosBefore
+	sig.LeaderPosAfter = -sig.LeaderPosAfter
+	sig.TargetSize = -sig.TargetSize
+	sig.LeaderLiqPrice = 0
+	return sig
+}
+
+// remapAction 按 ActionRemap 替换动作，未配置时原样返回
+func (c CopyTradingConfig) remapAction(action copytrading.SignalAction) copytrading.SignalAction {
+	if to, ok := c.ActionRemap[action]; ok && copyActionSide(to) == copyActionSide(action) {
+		return to
+	}
+	return action
+}
+
+// marginModeFor 返回开仓前需设置的保证金模式：币种覆盖 > 同步领航员模式；ok=false 表示不设置
+func (c CopyTradingConfig) marginModeFor(symbol, leaderMode string) (isCross bool, ok bool) {
+	mode, overridden := c.MarginModeOverrides[strings.ToUpper(symbol)]
+	if !overridden {
+		mode, overridden = c.MarginModeOverrides["*"]
+	}
+	if !overridden {
+		if !c.SyncMarginMode || leaderMode == "" {
+			return false, false
+		}
+		mode = leaderMode
+	}
+	return strings.EqualFold(mode, "cross"), true
+}
+
+// followRatioFor 返回某币种的跟单比例（百分比）：SymbolRatios 优先，否则为 FollowRatio
+func (c CopyTradingConfig) followRatioFor(symbol string) float64 {
+	if ratio, ok := c.SymbolRatios[strings.ToUpper(symbol)]; ok && ratio > 0 {
+		return ratio
+	}
+	return c.FollowRatio
+}
+
+// maxOrderNotionalFor 返回某币种的单笔订单名义价值上限，0 表示不限制
+func (c CopyTradingConfig) maxOrderNotionalFor(symbol string) float64 {
+	if limit, ok := c.MaxOrderNotional[strings.ToUpper(symbol)]; ok {
+		return limit
+	}
+	return c.MaxOrderNotional["*"]
+}
+
+// maxSymbolBaseSizeFor 返回某币种的同向持仓数量上限（币），0 表示不限制
+func (c CopyTradingConfig) maxSymbolBaseSizeFor(symbol string) float64 {
+	if limit, ok := c.MaxSymbolBaseSize[strings.ToUpper(symbol)]; ok {
+		return limit
+	}
+	return c.MaxSymbolBaseSize["*"]
+}
+
+// capCopyBaseSize 按信号价格将开/加仓名义价值折算为币数，并截断到加上已有同向持仓
+// held 后不超过 limit；capped 表示上限生效（qty=0 表示已满仓，不再下单）
+func capCopyBaseSize(notional, price, held, limit float64) (qty float64, capped bool) {
+	if price <= 0 {
+		return 0, false
+	}
+	qty = notional / price
+	if limit <= 0 {
+		return qty, false
+	}
+	room := limit - held
+	if room <= 0 {
+		return 0, true
+	}
+	if qty > room {
+		return room, true
+	}
+	return qty, false
+}
+
+// splitOrderQuantity 按单笔名义价值上限拆分订单数量；clamp 模式只保留一笔上限订单
+func splitOrderQuantity(quantity, price, maxNotional float64, mode string) []float64 {
+	if quantity <= 0 {
+		return nil
+	}
+	if maxNotional <= 0 || price <= 0 || quantity*price <= maxNotional {
+		return []float64{quantity}
+	}
+	maxQty := maxNotional / price
+	if mode == OrderLimitClamp {
+		return []float64{maxQty}
+	}
+	var slices []float64
+	remaining := quantity
+	for remaining > maxQty {
+		slices = append(slices, maxQty)
+		remaining -= maxQty
+	}
+	if remaining > maxQty*1e-9 {
+		slices = append(slices, remaining)
+	}
+	return slices
+}
+
+// placeCopyOrderSlices 依次下单所有子订单，任一失败立即返回
+func placeCopyOrderSlices(symbol string, quantity, price float64, cfg CopyTradingConfig, place func(qty float64) error) error {
+	slices := splitOrderQuantity(quantity, price, cfg.maxOrderNotionalFor(symbol), cfg.OrderLimitMode)
+	for i, qty := range slices {
+		if err := place(qty); err != nil {
+			if len(slices) > 1 {
+				return fmt.Errorf("第 %d/%d 笔子订单失败: %w", i+1, len(slices), err)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,835 @@
+package trader
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nofx/copytrading"
+	"nofx/logger"
+	"nofx/market"
+
+	"github.com/agiledragon/gomonkey/v2"
+)
+
+func TestUpdateCopyTradingConfig_RatioAppliesToSubsequentSignals(t *testing.T) {
+	at := &AutoTrader{name: "copy", copyTradingConfig: DefaultCopyTradingConfig()}
+	sig := copytrading.Signal{
+		Symbol:         "BTCUSDT",
+		Action:         copytrading.ActionOpenLong,
+		NotionalUSD:    1000,
+		LeaderEquity:   1000,
+		LeaderLeverage: 10,
+	}
+
+	usd, _ := at.getCopyTradingConfig().SizeFor(sig, 500)
+	if math.Abs(usd-1000) > 1e-9 {
+		t.Fatalf("expected 1000 at 100%%, got %.4f", usd)
+	}
+
+	updated := DefaultCopyTradingConfig()
+	updated.FollowRatio = 50
+	if err := at.UpdateCopyTradingConfig(updated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	usd, _ = at.getCopyTradingConfig().SizeFor(sig, 500)
+	if math.Abs(usd-500) > 1e-9 {
+		t.Fatalf("expected 500 at 50%%, got %.4f", usd)
+	}
+}
+
+func TestUpdateCopyTradingConfig_RejectsInvalid(t *testing.T) {
+	at := &AutoTrader{name: "copy", copyTradingConfig: DefaultCopyTradingConfig()}
+
+	bad := DefaultCopyTradingConfig()
+	bad.MinAmount = 100
+	bad.MaxAmount = 10
+	if err := at.UpdateCopyTradingConfig(bad); err == nil {
+		t.Fatal("expected min>max to be rejected")
+	}
+
+	bad = DefaultCopyTradingConfig()
+	bad.FollowRatio = -1
+	if err := at.UpdateCopyTradingConfig(bad); err == nil {
+		t.Fatal("expected negative ratio to be rejected")
+	}
+
+	if got := at.getCopyTradingConfig(); got.FollowRatio != 100 || got.MinAmount != 0 {
+		t.Fatalf("config must be unchanged after rejected update, got %+v", got)
+	}
+}
+
+// scriptedCopyProvider 等待 start 后依次发送预设信号，然后等待停止
+type scriptedCopyProvider struct {
+	start   <-chan struct{}
+	signals []copytrading.Signal
+	runs    *int32
+}
+
+func (p *scriptedCopyProvider) Run(ctx context.Context, out chan<- copytrading.Signal) error {
+	atomic.AddInt32(p.runs, 1)
+	<-p.start
+	for _, sig := range p.signals {
+		select {
+		case out <- sig:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func TestCopySignalHub_SharedSourceWithPerFollowerRatios(t *testing.T) {
+	var runs int32
+	start := make(chan struct{})
+	sig := copytrading.Signal{
+		Symbol:         "BTCUSDT",
+		Action:         copytrading.ActionOpenLong,
+		NotionalUSD:    1000,
+		LeaderEquity:   1000,
+		LeaderLeverage: 10,
+	}
+	hub := &copySignalHub{
+		sources: make(map[string]*sharedCopySource),
+		newProvider: func(cfg copytrading.Config) (copytrading.Provider, error) {
+			return &scriptedCopyProvider{start: start, signals: []copytrading.Signal{sig}, runs: &runs}, nil
+		},
+	}
+
+	srcCfg := copytrading.Config{Type: "okx", Identifier: "leader"}
+	chA, releaseA, err := hub.subscribe(srcCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chB, releaseB, err := hub.subscribe(srcCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hub.subscribers(srcCfg) != 2 {
+		t.Fatalf("expected 2 subscribers, got %d", hub.subscribers(srcCfg))
+	}
+	close(start)
+
+	cfgA := DefaultCopyTradingConfig()
+	cfgB := DefaultCopyTradingConfig()
+	cfgB.FollowRatio = 30
+
+	usdA, _ := cfgA.SizeFor(<-chA, 500)
+	usdB, _ := cfgB.SizeFor(<-chB, 500)
+	if math.Abs(usdA-1000) > 1e-9 || math.Abs(usdB-300) > 1e-9 {
+		t.Fatalf("unexpected follower sizes: A=%.4f B=%.4f", usdA, usdB)
+	}
+	if atomic.LoadInt32(&runs) != 1 {
+		t.Fatalf("expected a single shared provider, got %d", runs)
+	}
+
+	releaseA()
+	releaseB()
+	if hub.subscribers(srcCfg) != 0 {
+		t.Fatal("source must stop after the last subscriber leaves")
+	}
+}
+
+func TestSplitOrderQuantity(t *testing.T) {
+	// 50000 USD 订单，价格 100，交易所单笔上限 12000 USD
+	slices := splitOrderQuantity(500, 100, 12000, OrderLimitSplit)
+	if len(slices) != 5 {
+		t.Fatalf("expected 5 slices, got %v", slices)
+	}
+	var total float64
+	for _, qty := range slices {
+		if qty*100 > 12000+1e-9 {
+			t.Fatalf("slice %.4f exceeds the exchange cap", qty)
+		}
+		total += qty
+	}
+	if math.Abs(total-500) > 1e-9 {
+		t.Fatalf("slices must sum to the original quantity, got %.6f", total)
+	}
+
+	if got := splitOrderQuantity(500, 100, 12000, OrderLimitClamp); len(got) != 1 || math.Abs(got[0]-120) > 1e-9 {
+		t.Fatalf("clamp mode must keep a single capped order, got %v", got)
+	}
+	if got := splitOrderQuantity(5, 100, 12000, OrderLimitSplit); len(got) != 1 || got[0] != 5 {
+		t.Fatalf("orders under the cap must pass through, got %v", got)
+	}
+	if got := splitOrderQuantity(5, 100, 0, OrderLimitSplit); len(got) != 1 || got[0] != 5 {
+		t.Fatalf("no cap must pass through, got %v", got)
+	}
+}
+
+func TestPlaceCopyOrderSlices_UsesSymbolLimit(t *testing.T) {
+	cfg := ParseCopyTradingConfig(`{"follow_ratio":100,"follow_open":true,"max_order_notional":{"btcusdt":1000,"*":500}}`)
+
+	var placed []float64
+	place := func(qty float64) error {
+		placed = append(placed, qty)
+		return nil
+	}
+	if err := placeCopyOrderSlices("BTCUSDT", 25, 100, cfg, place); err != nil {
+		t.Fatal(err)
+	}
+	if len(placed) != 3 || math.Abs(placed[0]+placed[1]+placed[2]-25) > 1e-9 {
+		t.Fatalf("expected 3 BTC slices summing to 25, got %v", placed)
+	}
+
+	placed = nil
+	if err := placeCopyOrderSlices("ETHUSDT", 12, 100, cfg, place); err != nil {
+		t.Fatal(err)
+	}
+	if len(placed) != 3 {
+		t.Fatalf("expected wildcard limit to split ETH into 3 slices, got %v", placed)
+	}
+}
+
+func TestCopySourceConfigNetMode(t *testing.T) {
+	at := &AutoTrader{signalSourceType: "hyperliquid_wallet", signalSourceValue: "0xabc"}
+	at.copyTradingConfig = normalizeCopyTradingConfig(CopyTradingConfig{
+		FollowOpen: true, FollowMode: "NET", RebalanceThresholdPct: 10,
+	})
+
+	src := at.copySourceConfig()
+	if src.Mode != copytrading.ModeNet || src.RebalanceThreshold != 0.1 {
+		t.Fatalf("unexpected source config: %+v", src)
+	}
+
+	trade := src
+	trade.Mode = copytrading.ModeTrade
+	trade.RebalanceThreshold = 0
+	if copySourceKey(src) == copySourceKey(trade) {
+		t.Fatalf("net and trade followers must not share a provider")
+	}
+
+	if err := validateCopyTradingConfig(CopyTradingConfig{FollowOpen: true, FollowMode: "grid"}); err == nil {
+		t.Fatalf("expected unknown follow_mode to be rejected")
+	}
+}
+
+type stepClock struct{ t time.Time }
+
+func (c *stepClock) Now() time.Time { return c.t }
+
+func TestAutoTraderUsesInjectedClock(t *testing.T) {
+	clock := &stepClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	at := &AutoTrader{signalSourceType: "ai", clock: clock, startTime: clock.Now()}
+
+	clock.t = clock.t.Add(90 * time.Minute)
+	if got := at.GetStatus()["runtime_minutes"]; got != 90 {
+		t.Fatalf("expected runtime driven by the injected clock, got %v", got)
+	}
+
+	at.signalSourceType = "okx_wallet"
+	if at.copySourceConfig().Clock != clock {
+		t.Fatalf("expected the clock to be passed to the copy signal source")
+	}
+}
+
+func TestActionRemap(t *testing.T) {
+	cfg := DefaultCopyTradingConfig()
+	cfg.ActionRemap = map[copytrading.SignalAction]copytrading.SignalAction{
+		copytrading.ActionReduceLong:  copytrading.ActionCloseLong,
+		copytrading.ActionReduceShort: copytrading.ActionCloseShort,
+	}
+	if err := validateCopyTradingConfig(cfg); err != nil {
+		t.Fatalf("reduce→close remap must be valid: %v", err)
+	}
+	if got := cfg.remapAction(copytrading.ActionReduceLong); got != copytrading.ActionCloseLong {
+		t.Fatalf("expected reduce_long→close_long, got %s", got)
+	}
+	// unmapped actions pass through unchanged
+	if got := cfg.remapAction(copytrading.ActionOpenLong); got != copytrading.ActionOpenLong {
+		t.Fatalf("expected identity passthrough, got %s", got)
+	}
+	if got := DefaultCopyTradingConfig().remapAction(copytrading.ActionReduceShort); got != copytrading.ActionReduceShort {
+		t.Fatalf("expected identity passthrough without remap, got %s", got)
+	}
+
+	cfg.ActionRemap = map[copytrading.SignalAction]copytrading.SignalAction{
+		copytrading.ActionReduceLong: copytrading.ActionCloseShort,
+	}
+	if err := validateCopyTradingConfig(cfg); err == nil {
+		t.Fatal("expected cross-direction remap to be rejected")
+	}
+	cfg.ActionRemap = map[copytrading.SignalAction]copytrading.SignalAction{"trim_long": copytrading.ActionCloseLong}
+	if err := validateCopyTradingConfig(cfg); err == nil {
+		t.Fatal("expected unknown action to be rejected")
+	}
+}
+
+// marginModeRecorder 记录 SetMarginMode 调用
+type marginModeRecorder struct {
+	MockTrader
+	modes map[string]bool
+}
+
+func (m *marginModeRecorder) SetMarginMode(symbol string, isCrossMargin bool) error {
+	m.modes[symbol] = isCrossMargin
+	return nil
+}
+
+func TestCopyPreTradeActions_MarginModeOverrides(t *testing.T) {
+	overrides := map[string]string{"BTCUSDT": "Cross", "*": "isolated"}
+	for _, tc := range []struct {
+		name  string
+		sync  bool
+		sig   copytrading.Signal
+		cross bool
+		set   bool
+	}{
+		{"major forced cross over leader isolated", true, copytrading.Signal{Symbol: "BTCUSDT", MarginMode: "isolated"}, true, true},
+		{"alt forced isolated over leader cross", true, copytrading.Signal{Symbol: "DOGEUSDT", MarginMode: "cross"}, false, true},
+		{"override applies with sync off", false, copytrading.Signal{Symbol: "BTCUSDT", MarginMode: "isolated"}, true, true},
+		{"wildcard applies with sync off", false, copytrading.Signal{Symbol: "DOGEUSDT"}, false, true},
+	} {
+		cfg := DefaultCopyTradingConfig()
+		cfg.SyncMarginMode = tc.sync
+		cfg.MarginModeOverrides = overrides
+		if err := validateCopyTradingConfig(cfg); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		cfg = normalizeCopyTradingConfig(cfg)
+
+		rec := &marginModeRecorder{modes: make(map[string]bool)}
+		at := &AutoTrader{name: "copy", trader: rec}
+		at.copyPreTradeActions(tc.sig, cfg, 5)
+
+		cross, set := rec.modes[tc.sig.Symbol]
+		if set != tc.set || cross != tc.cross {
+			t.Fatalf("%s: expected set=%v cross=%v, got set=%v cross=%v", tc.name, tc.set, tc.cross, set, cross)
+		}
+	}
+
+	// without overrides the global sync flag still decides
+	cfg := DefaultCopyTradingConfig()
+	cfg.SyncMarginMode = false
+	rec := &marginModeRecorder{modes: make(map[string]bool)}
+	(&AutoTrader{trader: rec}).copyPreTradeActions(copytrading.Signal{Symbol: "ETHUSDT", MarginMode: "cross"}, cfg, 5)
+	if len(rec.modes) != 0 {
+		t.Fatalf("sync off without override must not touch margin mode, got %v", rec.modes)
+	}
+
+	cfg.MarginModeOverrides = map[string]string{"ETHUSDT": "portfolio"}
+	if err := validateCopyTradingConfig(cfg); err == nil {
+		t.Fatal("expected unknown margin mode to be rejected")
+	}
+}
+
+func TestMaxSymbolBaseSize(t *testing.T) {
+	cfg := ParseCopyTradingConfig(`{"follow_ratio":100,"max_symbol_base_size":{"btcusdt":5,"*":100}}`)
+	limit := cfg.maxSymbolBaseSizeFor("BTCUSDT")
+	if limit != 5 || cfg.maxSymbolBaseSizeFor("ETHUSDT") != 100 {
+		t.Fatalf("unexpected limits: %v", cfg.MaxSymbolBaseSize)
+	}
+
+	// 小额开仓：0.5 BTC 远低于上限，不截断
+	if qty, capped := capCopyBaseSize(50000, 100000, 0, limit); capped || math.Abs(qty-0.5) > 1e-9 {
+		t.Fatalf("small open must pass through, got %.6f capped=%v", qty, capped)
+	}
+	// 加仓：已持有 4 BTC，本次 3 BTC → 只能再加 1 BTC
+	if qty, capped := capCopyBaseSize(300000, 100000, 4, limit); !capped || math.Abs(qty-1) > 1e-9 {
+		t.Fatalf("add must be clamped to the remaining 1 BTC, got %.6f capped=%v", qty, capped)
+	}
+	// 已达上限：不再下单
+	if qty, capped := capCopyBaseSize(100000, 100000, 5, limit); !capped || qty != 0 {
+		t.Fatalf("a full position must block the add, got %.6f capped=%v", qty, capped)
+	}
+
+	if err := validateCopyTradingConfig(CopyTradingConfig{FollowOpen: true, MaxSymbolBaseSize: map[string]float64{"BTCUSDT": -1}}); err == nil {
+		t.Fatal("negative base size caps must be rejected")
+	}
+}
+
+func TestMaxTotalLeverage_ScalesAllSymbolsUniformly(t *testing.T) {
+	cfg := DefaultCopyTradingConfig()
+	cfg.SizeMode = SizeModeEquity
+	cfg.MaxTotalLeverage = 3
+	uncapped := cfg
+	uncapped.MaxTotalLeverage = 0
+
+	// 领航员整本 10x（BTC 6000 + ETH 4000，净值 1000），上限 3x → 统一缩放 0.3
+	book := 10000.0
+	signals := []copytrading.Signal{
+		{Symbol: "BTCUSDT", Action: copytrading.ActionOpenLong, NotionalUSD: 6000, LeaderEquity: 1000, LeaderLeverage: 10, LeaderBookNotionalUSD: book},
+		{Symbol: "ETHUSDT", Action: copytrading.ActionOpenShort, NotionalUSD: 4000, LeaderEquity: 1000, LeaderLeverage: 5, LeaderBookNotionalUSD: book},
+	}
+	var followerNotional float64
+	for _, sig := range signals {
+		scaled, _ := cfg.SizeFor(sig, 500)
+		unscaled, _ := uncapped.SizeFor(sig, 500)
+		if math.Abs(scaled/unscaled-0.3) > 1e-9 {
+			t.Fatalf("%s: expected scale 0.3, got %.4f", sig.Symbol, scaled/unscaled)
+		}
+		followerNotional += scaled
+	}
+	if math.Abs(followerNotional-3*500) > 1e-6 {
+		t.Fatalf("expected aggregate notional capped at 3x equity, got %.2f", followerNotional)
+	}
+
+	// 领航员整本未超过上限时不缩放
+	if scale := cfg.copyBudgetScale(2000, 1000, 500); scale != 1 {
+		t.Fatalf("expected no scaling under the cap, got %.4f", scale)
+	}
+
+	// ratio 模式按跟随者净值计算总杠杆：整本 10000 映射到净值 500 的跟随者为 20x
+	cfg.SizeMode = SizeModeRatio
+	if scale := cfg.copyBudgetScale(book, 1000, 500); math.Abs(scale-0.15) > 1e-9 {
+		t.Fatalf("expected ratio mode scaled against follower equity, got %.4f", scale)
+	}
+}
+
+func TestSmoothLeaderEquity_DampensSpikes(t *testing.T) {
+	at := &AutoTrader{name: "copy", copyTradingConfig: DefaultCopyTradingConfig()}
+	equities := []float64{1000, 1500, 700, 1400, 1000}
+
+	var smoothed []float64
+	for _, eq := range equities {
+		smoothed = append(smoothed, at.smoothLeaderEquity(eq, 0.2))
+	}
+	if smoothed[0] != 1000 {
+		t.Fatalf("first observation seeds the average, got %.2f", smoothed[0])
+	}
+	for i := 1; i < len(equities); i++ {
+		raw := math.Abs(equities[i] - equities[i-1])
+		step := math.Abs(smoothed[i] - smoothed[i-1])
+		if step >= raw*0.5 {
+			t.Fatalf("step %d: smoothed basis moved %.2f for a raw swing of %.2f", i, step, raw)
+		}
+	}
+	if math.Abs(smoothed[1]-1100) > 1e-9 {
+		t.Fatalf("expected 0.2*1500+0.8*1000=1100, got %.2f", smoothed[1])
+	}
+
+	// 关闭平滑时直接使用原始净值
+	if eq := at.smoothLeaderEquity(1234, 0); eq != 1234 {
+		t.Fatalf("expected raw equity without smoothing, got %.2f", eq)
+	}
+}
+
+func TestCopyLiqStopPrice(t *testing.T) {
+	long := copytrading.Signal{Symbol: "BTCUSDT", Action: copytrading.ActionOpenLong, Price: 100, LeaderLiqPrice: 80}
+	if stop, ok := copyLiqStopPrice(long, 25); !ok || math.Abs(stop-85) > 1e-9 {
+		t.Fatalf("long: expected stop 85 (25%% inside liq 80), got %.4f ok=%v", stop, ok)
+	}
+	short := copytrading.Signal{Symbol: "BTCUSDT", Action: copytrading.ActionAddShort, Price: 100, LeaderLiqPrice: 110}
+	if stop, ok := copyLiqStopPrice(short, 50); !ok || math.Abs(stop-105) > 1e-9 {
+		t.Fatalf("short: expected stop 105, got %.4f ok=%v", stop, ok)
+	}
+
+	for name, sig := range map[string]copytrading.Signal{
+		"no liq price":          {Action: copytrading.ActionOpenLong, Price: 100},
+		"liq on the wrong side": {Action: copytrading.ActionOpenLong, Price: 100, LeaderLiqPrice: 120},
+		"close":                 {Action: copytrading.ActionCloseLong, Price: 100, LeaderLiqPrice: 80},
+	} {
+		if _, ok := copyLiqStopPrice(sig, 25); ok {
+			t.Fatalf("%s: expected no stop", name)
+		}
+	}
+	if _, ok := copyLiqStopPrice(long, 0); ok {
+		t.Fatal("a zero buffer disables the stop")
+	}
+}
+
+func TestSizeFor(t *testing.T) {
+	sig := copytrading.Signal{Symbol: "BTCUSDT", Action: copytrading.ActionOpenLong, NotionalUSD: 1000}
+	for _, tc := range []struct {
+		name     string
+		ratio    float64
+		min, max float64
+		equity   float64
+		usd      float64
+		skip     bool
+	}{
+		{"full ratio", 100, 0, 0, 500, 1000, false},
+		{"half ratio", 50, 0, 0, 500, 500, false},
+		{"clamped to max", 50, 0, 300, 500, 300, false},
+		{"at min", 10, 100, 0, 500, 100, false},
+		{"below min skips", 5, 100, 0, 500, 50, true},
+		{"max below min still skips", 100, 200, 150, 500, 150, true},
+		{"no follower equity skips", 100, 0, 0, 0, 0, true},
+	} {
+		cfg := DefaultCopyTradingConfig()
+		cfg.FollowRatio, cfg.MinAmount, cfg.MaxAmount = tc.ratio, tc.min, tc.max
+		usd, skip := cfg.SizeFor(sig, tc.equity)
+		if math.Abs(usd-tc.usd) > 1e-9 || skip != tc.skip {
+			t.Errorf("%s: got usd=%.2f skip=%v, want usd=%.2f skip=%v", tc.name, usd, skip, tc.usd, tc.skip)
+		}
+	}
+}
+
+func TestShouldFollow(t *testing.T) {
+	cfg := CopyTradingConfig{FollowOpen: true, FollowAdd: false, FollowReduce: true}
+	for _, tc := range []struct {
+		action      copytrading.SignalAction
+		hasPosition bool
+		want        bool
+	}{
+		{copytrading.ActionOpenLong, false, true},
+		{copytrading.ActionOpenShort, false, true},
+		{copytrading.ActionAddLong, true, false},
+		{copytrading.ActionAddShort, true, false},
+		// 领航员开仓但跟随者已有仓位：对跟随者是加仓
+		{copytrading.ActionOpenLong, true, false},
+		// 领航员加仓但跟随者没有仓位：对跟随者是开仓
+		{copytrading.ActionAddShort, false, true},
+		{copytrading.ActionReduceLong, true, true},
+		{copytrading.ActionReduceShort, true, true},
+		{copytrading.ActionCloseLong, true, true},
+		{copytrading.ActionCloseShort, true, true},
+		{copytrading.ActionSetPosition, false, true},
+		{"trim_long", true, false},
+	} {
+		if got := cfg.ShouldFollow(copytrading.Signal{Action: tc.action}, tc.hasPosition); got != tc.want {
+			t.Errorf("%s (hasPosition=%v): got %v, want %v", tc.action, tc.hasPosition, got, tc.want)
+		}
+	}
+
+	cfg = CopyTradingConfig{FollowOpen: false, FollowAdd: true, FollowReduce: false}
+	for _, tc := range []struct {
+		action      copytrading.SignalAction
+		hasPosition bool
+		want        bool
+	}{
+		{copytrading.ActionOpenLong, false, false},
+		{copytrading.ActionAddShort, true, true},
+		{copytrading.ActionReduceLong, true, false},
+		{copytrading.ActionCloseShort, true, false},
+	} {
+		if got := cfg.ShouldFollow(copytrading.Signal{Action: tc.action}, tc.hasPosition); got != tc.want {
+			t.Errorf("%s with reduce off: got %v, want %v", tc.action, got, tc.want)
+		}
+	}
+}
+
+func TestEffectiveLeverage(t *testing.T) {
+	cfg := DefaultCopyTradingConfig()
+	if got := cfg.EffectiveLeverage(50); got != 50 {
+		t.Fatalf("uncapped config must mirror the leader, got %dx", got)
+	}
+	cfg.MaxLeverage = 10
+	if got := cfg.EffectiveLeverage(50); got != 10 {
+		t.Fatalf("expected 50x capped at 10x, got %dx", got)
+	}
+	if got := cfg.EffectiveLeverage(5); got != 5 {
+		t.Fatalf("leverage under the cap must pass through, got %dx", got)
+	}
+
+	if err := validateCopyTradingConfig(CopyTradingConfig{FollowOpen: true, MaxLeverage: -1}); err == nil {
+		t.Fatal("expected negative max_leverage to be rejected")
+	}
+	if got := ParseCopyTradingConfig(`{"follow_open":true,"max_leverage":-3}`); got.MaxLeverage != 0 {
+		t.Fatalf("expected negative max_leverage normalized to 0, got %d", got.MaxLeverage)
+	}
+}
+
+func TestTransformReverse(t *testing.T) {
+	cfg := DefaultCopyTradingConfig()
+	cfg.Reverse = true
+	for from, to := range map[copytrading.SignalAction]copytrading.SignalAction{
+		copytrading.ActionOpenLong:    copytrading.ActionOpenShort,
+		copytrading.ActionOpenShort:   copytrading.ActionOpenLong,
+		copytrading.ActionAddLong:     copytrading.ActionAddShort,
+		copytrading.ActionAddShort:    copytrading.ActionAddLong,
+		copytrading.ActionReduceLong:  copytrading.ActionReduceShort,
+		copytrading.ActionReduceShort: copytrading.ActionReduceLong,
+		copytrading.ActionCloseLong:   copytrading.ActionCloseShort,
+		copytrading.ActionCloseShort:  copytrading.ActionCloseLong,
+	} {
+		sig := cfg.Transform(copytrading.Signal{Symbol: "BTCUSDT", Action: from, NotionalUSD: 500})
+		if sig.Action != to || sig.NotionalUSD != 500 {
+			t.Errorf("%s: expected %s with notional kept, got %s %.2f", from, to, sig.Action, sig.NotionalUSD)
+		}
+	}
+
+	// 领航员反手（平多 1 → 开空 2）翻转为平空 1 → 开多 2，与跟随者的空头持仓衔接
+	flip := []copytrading.Signal{
+		{Symbol: "BTCUSDT", Action: copytrading.ActionCloseLong, DeltaSize: -1, LeaderPosBefore: 1, LeaderPosAfter: 0},
+		{Symbol: "BTCUSDT", Action: copytrading.ActionOpenShort, DeltaSize: -2, LeaderPosBefore: 0, LeaderPosAfter: -2, LeaderLiqPrice: 120},
+	}
+	closeSig, openSig := cfg.Transform(flip[0]), cfg.Transform(flip[1])
+	if closeSig.Action != copytrading.ActionCloseShort || closeSig.LeaderPosBefore != -1 || closeSig.DeltaSize != 1 {
+		t.Fatalf("expected the close reversed to a short close, got %+v", closeSig)
+	}
+	if openSig.Action != copytrading.ActionOpenLong || openSig.LeaderPosAfter != 2 || openSig.DeltaSize != 2 || openSig.LeaderLiqPrice != 0 {
+		t.Fatalf("expected the open reversed to a long open without the leader's liq price, got %+v", openSig)
+	}
+	if closeSig.LeaderPosAfter != openSig.LeaderPosBefore {
+		t.Fatal("the reversed flip must stay a continuous position sequence")
+	}
+
+	if sig := DefaultCopyTradingConfig().Transform(flip[0]); sig != flip[0] {
+		t.Fatalf("expected no change without Reverse, got %+v", sig)
+	}
+}
+
+func TestSymbolRatios(t *testing.T) {
+	cfg := ParseCopyTradingConfig(`{"follow_open":true,"follow_ratio":100,"size_mode":"ratio","symbol_ratios":{"ethusdt":30," solusdt ":0,"DOGEUSDT":-5}}`)
+	if len(cfg.SymbolRatios) != 1 || cfg.SymbolRatios["ETHUSDT"] != 30 {
+		t.Fatalf("expected keys uppercased and non-positive ratios dropped, got %v", cfg.SymbolRatios)
+	}
+
+	for _, tc := range []struct {
+		symbol string
+		usd    float64
+	}{
+		{"BTCUSDT", 1000}, // 全局 100%
+		{"ETHUSDT", 300},  // 币种覆盖 30%
+		{"ethusdt", 300},
+		{"SOLUSDT", 1000}, // 无效覆盖已丢弃，回退全局
+	} {
+		usd, skip := cfg.SizeFor(copytrading.Signal{Symbol: tc.symbol, NotionalUSD: 1000}, 500)
+		if skip || math.Abs(usd-tc.usd) > 1e-9 {
+			t.Errorf("%s: expected %.2f, got %.2f skip=%v", tc.symbol, tc.usd, usd, skip)
+		}
+	}
+
+	if err := validateCopyTradingConfig(CopyTradingConfig{FollowOpen: true, SymbolRatios: map[string]float64{"BTCUSDT": -1}}); err == nil {
+		t.Fatal("expected a negative symbol ratio to be rejected")
+	}
+}
+
+func TestSizeForEquityMode(t *testing.T) {
+	// 领航员净值 10000，跟随者 1000（1/10）
+	sig := copytrading.Signal{Symbol: "BTCUSDT", Action: copytrading.ActionOpenLong, NotionalUSD: 5000, LeaderEquity: 10000}
+
+	ratio := ParseCopyTradingConfig(`{"follow_open":true,"follow_ratio":50,"size_mode":"ratio"}`)
+	equity := ParseCopyTradingConfig(`{"follow_open":true,"follow_ratio":50,"size_mode":"EQUITY"}`)
+	if ratio.SizeMode != SizeModeRatio || equity.SizeMode != SizeModeEquity {
+		t.Fatalf("unexpected size modes: %q %q", ratio.SizeMode, equity.SizeMode)
+	}
+
+	ratioUSD, _ := ratio.SizeFor(sig, 1000)
+	equityUSD, skip := equity.SizeFor(sig, 1000)
+	if math.Abs(ratioUSD-2500) > 1e-9 {
+		t.Fatalf("ratio mode ignores account sizes, expected 2500, got %.2f", ratioUSD)
+	}
+	if skip || math.Abs(equityUSD-250) > 1e-9 {
+		t.Fatalf("equity mode must scale by 1000/10000, expected 250, got %.2f skip=%v", equityUSD, skip)
+	}
+
+	sig.LeaderEquity = 0
+	if _, skip := equity.SizeFor(sig, 1000); !skip {
+		t.Fatal("equity mode must skip without the leader's equity")
+	}
+	if err := validateCopyTradingConfig(CopyTradingConfig{FollowOpen: true, SizeMode: "kelly"}); err == nil {
+		t.Fatal("expected an unknown size_mode to be rejected")
+	}
+}
+
+// copyOrder 记录跟单下单（动作与数量）
+type copyOrder struct {
+	action   string
+	quantity float64
+}
+
+// recordingCopyTrader 在 MockTrader 基础上记录所有开/平仓订单
+type recordingCopyTrader struct {
+	*MockTrader
+	orders []copyOrder
+}
+
+func (r *recordingCopyTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	r.orders = append(r.orders, copyOrder{"open_long", quantity})
+	return r.MockTrader.OpenLong(symbol, quantity, leverage)
+}
+
+func (r *recordingCopyTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	r.orders = append(r.orders, copyOrder{"open_short", quantity})
+	return r.MockTrader.OpenShort(symbol, quantity, leverage)
+}
+
+func (r *recordingCopyTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	r.orders = append(r.orders, copyOrder{"close_long", quantity})
+	return r.MockTrader.CloseLong(symbol, quantity)
+}
+
+func (r *recordingCopyTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	r.orders = append(r.orders, copyOrder{"close_short", quantity})
+	return r.MockTrader.CloseShort(symbol, quantity)
+}
+
+// newCopyTestTrader 构造跟单用 AutoTrader：跟随者净值 10000，行情价格固定为 price
+func newCopyTestTrader(t *testing.T, cfg CopyTradingConfig, price float64, positions []map[string]interface{}) (*AutoTrader, *recordingCopyTrader) {
+	t.Helper()
+	patches := gomonkey.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: price}, nil
+	})
+	t.Cleanup(patches.Reset)
+
+	rec := &recordingCopyTrader{MockTrader: &MockTrader{
+		balance:   map[string]interface{}{"totalWalletBalance": 10000.0, "availableBalance": 10000.0, "totalUnrealizedProfit": 0.0},
+		positions: positions,
+	}}
+	at := &AutoTrader{
+		name:              "copy",
+		trader:            rec,
+		decisionLogger:    logger.NewDecisionLogger(t.TempDir()),
+		copyTradingConfig: normalizeCopyTradingConfig(cfg),
+		config:            AutoTraderConfig{BTCETHLeverage: 10, AltcoinLeverage: 5},
+	}
+	return at, rec
+}
+
+func TestProcessCopySignal_SizesOpensThroughSizeFor(t *testing.T) {
+	cfg := DefaultCopyTradingConfig()
+	cfg.FollowRatio = 50
+	cfg.MinAmount = 100
+	at, rec := newCopyTestTrader(t, cfg, 100, nil)
+
+	// 领航员开仓 1000 USDT，50% → 跟随 500 USDT，即 5 个币
+	open := copytrading.Signal{Symbol: "SOLUSDT", Action: copytrading.ActionOpenLong, Price: 100, NotionalUSD: 1000, LeaderEquity: 1000, DeltaSize: 10}
+	if err := at.processCopySignal(open); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.orders) != 1 || rec.orders[0].action != "open_long" || math.Abs(rec.orders[0].quantity-5) > 1e-9 {
+		t.Fatalf("expected one 5-coin long open, got %+v", rec.orders)
+	}
+
+	// 50 USDT 低于最小金额：与 SizeFor 一致直接跳过，而不是抬高到 MinAmount
+	small := open
+	small.NotionalUSD = 100
+	if err := at.processCopySignal(small); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.orders) != 1 {
+		t.Fatalf("expected the below-minimum open skipped, got %+v", rec.orders)
+	}
+}
+
+func TestProcessCopySignal_EquitySizeMode(t *testing.T) {
+	// 领航员净值 100000，跟随者 10000（1/10）：同一信号在两种模式下的实盘下单数量
+	open := copytrading.Signal{Symbol: "SOLUSDT", Action: copytrading.ActionOpenLong, Price: 100, NotionalUSD: 5000, LeaderEquity: 100000, DeltaSize: 50}
+	for mode, want := range map[string]float64{SizeModeRatio: 50, SizeModeEquity: 5} {
+		cfg := DefaultCopyTradingConfig()
+		cfg.SizeMode = mode
+		at, rec := newCopyTestTrader(t, cfg, 100, nil)
+		if err := at.processCopySignal(open); err != nil {
+			t.Fatal(err)
+		}
+		if len(rec.orders) != 1 || math.Abs(rec.orders[0].quantity-want) > 1e-9 {
+			t.Fatalf("%s mode: expected %.2f coins, got %+v", mode, want, rec.orders)
+		}
+	}
+}
+
+func TestParseCopyTradingConfig_LegacyConfigUsesEquityMode(t *testing.T) {
+	// size_mode 引入前保存的配置没有该字段：按 equity 解析，延续旧版按净值比例跟单
+	legacy := ParseCopyTradingConfig(`{"follow_open":true,"follow_ratio":100,"min_amount":10,"max_amount":500}`)
+	if legacy.SizeMode != SizeModeEquity {
+		t.Fatalf("expected legacy config parsed as equity mode, got %q", legacy.SizeMode)
+	}
+	if got := ParseCopyTradingConfig(`{"follow_open":true,"size_mode":"ratio"}`); got.SizeMode != SizeModeRatio {
+		t.Fatalf("expected an explicit ratio mode kept, got %q", got.SizeMode)
+	}
+	if got := ParseCopyTradingConfig(`{not json`); got.SizeMode == SizeModeEquity {
+		t.Fatalf("expected the default config for unparsable JSON, got %q", got.SizeMode)
+	}
+
+	// 领航员净值 100000、跟随者 10000：旧配置按净值比例只跟 1/10
+	at, rec := newCopyTestTrader(t, legacy, 100, nil)
+	open := copytrading.Signal{Symbol: "SOLUSDT", Action: copytrading.ActionOpenLong, Price: 100, NotionalUSD: 5000, LeaderEquity: 100000, DeltaSize: 50}
+	if err := at.processCopySignal(open); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.orders) != 1 || math.Abs(rec.orders[0].quantity-5) > 1e-9 {
+		t.Fatalf("expected a 5-coin equity-proportional open, got %+v", rec.orders)
+	}
+}
+
+func TestProcessCopySignal_HonorsFollowSwitches(t *testing.T) {
+	cfg := DefaultCopyTradingConfig()
+	cfg.FollowAdd = false
+	cfg.FollowReduce = false
+	held := []map[string]interface{}{{"symbol": "SOLUSDT", "side": "long", "positionAmt": 2.0}}
+	at, rec := newCopyTestTrader(t, cfg, 100, held)
+
+	add := copytrading.Signal{Symbol: "SOLUSDT", Action: copytrading.ActionAddLong, Price: 100, NotionalUSD: 1000, LeaderEquity: 10000, DeltaSize: 10, LeaderPosBefore: 10, LeaderPosAfter: 20}
+	closeAll := copytrading.Signal{Symbol: "SOLUSDT", Action: copytrading.ActionCloseLong, Price: 100, NotionalUSD: 2000, LeaderEquity: 10000, DeltaSize: -20, LeaderPosBefore: 20}
+	for _, sig := range []copytrading.Signal{add, closeAll} {
+		if err := at.processCopySignal(sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(rec.orders) != 0 {
+		t.Fatalf("expected add and close skipped with follow_add/follow_reduce off, got %+v", rec.orders)
+	}
+
+	// 跟随者在空头方向没有仓位：领航员的加空对跟随者是开仓，按 FollowOpen 放行
+	addShort := add
+	addShort.Action = copytrading.ActionAddShort
+	if err := at.processCopySignal(addShort); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.orders) != 1 || rec.orders[0].action != "open_short" {
+		t.Fatalf("expected the add treated as an open for a flat follower, got %+v", rec.orders)
+	}
+}
+
+func TestUpdateCopyTradingConfig_ResubscribesWhenFollowModeChanges(t *testing.T) {
+	var runs int32
+	started := make(chan struct{})
+	close(started)
+	prev := defaultCopySignalHub.newProvider
+	defaultCopySignalHub.newProvider = func(cfg copytrading.Config) (copytrading.Provider, error) {
+		return &scriptedCopyProvider{start: started, runs: &runs}, nil
+	}
+	defer func() { defaultCopySignalHub.newProvider = prev }()
+
+	at := &AutoTrader{
+		name:              "copy",
+		signalSourceType:  "okx_wallet",
+		signalSourceValue: "resubscribe-leader",
+		copyTradingConfig: DefaultCopyTradingConfig(),
+		stopMonitorCh:     make(chan struct{}),
+	}
+	tradeSource := at.copySourceConfig()
+	done := make(chan error, 1)
+	go func() { done <- at.runCopyTradingLoop() }()
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitFor("the initial subscription", func() bool { return defaultCopySignalHub.subscribers(tradeSource) == 1 })
+
+	// 只改仓位参数：继续使用同一信号源
+	sizing := DefaultCopyTradingConfig()
+	sizing.FollowRatio = 30
+	if err := at.UpdateCopyTradingConfig(sizing); err != nil {
+		t.Fatal(err)
+	}
+	if len(at.copySourceUpdates()) != 0 {
+		t.Fatal("a sizing-only update must not resubscribe")
+	}
+
+	net := sizing
+	net.FollowMode = FollowModeNet
+	net.RebalanceThresholdPct = 5
+	if err := at.UpdateCopyTradingConfig(net); err != nil {
+		t.Fatal(err)
+	}
+	netSource := at.copySourceConfig()
+	waitFor("the net-mode subscription", func() bool {
+		return defaultCopySignalHub.subscribers(netSource) == 1 && defaultCopySignalHub.subscribers(tradeSource) == 0
+	})
+	if atomic.LoadInt32(&runs) != 2 {
+		t.Fatalf("expected a second provider for the net-mode source, got %d runs", runs)
+	}
+
+	close(at.stopMonitorCh)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if defaultCopySignalHub.subscribers(netSource) != 0 {
+		t.Fatal("stopping the loop must release the current source")
+	}
+}
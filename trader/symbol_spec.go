@@ -0,0 +1,130 @@
+package trader
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SymbolSpec 交易所对单个币种的下单规则
+type SymbolSpec struct {
+	MinNotional float64 // 最小名义价值（USDT），0 表示未知
+	StepSize    float64 // 数量步进
+	TickSize    float64 // 价格步进
+}
+
+// SymbolSpecProvider 由能查询交易规则的交易所实现（可选接口）
+type SymbolSpecProvider interface {
+	GetSymbolSpec(symbol string) (SymbolSpec, error)
+}
+
+// defaultSymbolSpecTTL 交易规则缓存有效期，过期后下次查询时刷新
+const defaultSymbolSpecTTL = time.Hour
+
+// symbolSpecCache 缓存交易规则，按 TTL 刷新；刷新失败时沿用旧值
+type symbolSpecCache struct {
+	mu      sync.Mutex
+	lookup  func(symbol string) (SymbolSpec, error)
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]symbolSpecEntry
+}
+
+type symbolSpecEntry struct {
+	spec      SymbolSpec
+	fetchedAt time.Time
+}
+
+func newSymbolSpecCache(lookup func(symbol string) (SymbolSpec, error), ttl time.Duration, now func() time.Time) *symbolSpecCache {
+	if ttl <= 0 {
+		ttl = defaultSymbolSpecTTL
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &symbolSpecCache{
+		lookup:  lookup,
+		ttl:     ttl,
+		now:     now,
+		entries: make(map[string]symbolSpecEntry),
+	}
+}
+
+// get 返回币种交易规则；ok=false 表示从未成功获取
+func (c *symbolSpecCache) get(symbol string) (SymbolSpec, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, cached := c.entries[symbol]
+	if cached && c.now().Sub(entry.fetchedAt) < c.ttl {
+		return entry.spec, true
+	}
+	spec, err := c.lookup(symbol)
+	if err != nil {
+		return entry.spec, cached
+	}
+	c.entries[symbol] = symbolSpecEntry{spec: spec, fetchedAt: c.now()}
+	return spec, true
+}
+
+// refresh 将缓存标记为过期，下次查询时重新获取（获取失败时仍沿用旧值）
+func (c *symbolSpecCache) refresh(symbol string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[symbol]; ok {
+		entry.fetchedAt = time.Time{}
+		c.entries[symbol] = entry
+	}
+}
+
+// symbolSpecRejectionMarkers 交易所因数量步进/精度/最小名义价值拒单时的错误特征
+// （Binance: -1013 Filter failure、-1111 Precision、-4164 notional too small）
+var symbolSpecRejectionMarkers = []string{"-1013", "-1111", "-4164", "lot_size", "min_notional", "precision"}
+
+// isSymbolSpecRejection 判断下单错误是否因违反交易规则被拒（说明缓存的规则可能已变化）
+func isSymbolSpecRejection(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range symbolSpecRejectionMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyCopySymbolSpec 按交易规则修正开仓数量：先按步进向下取整，再检查
+// 有效最小名义价值 max(MinAmount, spec.MinNotional)。配置了 MinAmount 时视为
+// 接受抬高到最小值（clamped），否则低于交易所最小值的订单直接放弃（qty=0）
+func applyCopySymbolSpec(quantity, price float64, spec SymbolSpec, cfg CopyTradingConfig) (qty float64, clamped bool) {
+	if quantity <= 0 || price <= 0 {
+		return 0, false
+	}
+	qty = floorToStep(quantity, spec.StepSize)
+	minNotional := math.Max(cfg.MinAmount, spec.MinNotional)
+	if spec.MinNotional <= 0 || qty*price >= minNotional {
+		return qty, false
+	}
+	if cfg.MinAmount <= 0 {
+		return 0, false
+	}
+	return ceilToStep(minNotional/price, spec.StepSize), true
+}
+
+func floorToStep(v, step float64) float64 {
+	if step <= 0 {
+		return v
+	}
+	// 加一个极小量避免 0.3/0.1=2.9999999 之类的浮点误差
+	return math.Floor(v/step+1e-9) * step
+}
+
+func ceilToStep(v, step float64) float64 {
+	if step <= 0 {
+		return v
+	}
+	return math.Ceil(v/step-1e-9) * step
+}
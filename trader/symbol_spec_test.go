@@ -0,0 +1,122 @@
+package trader
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"nofx/copytrading"
+	"nofx/logger"
+)
+
+func TestApplyCopySymbolSpec_ExchangeMinimumAboveMinAmount(t *testing.T) {
+	spec := SymbolSpec{MinNotional: 100, StepSize: 0.01}
+
+	// 0.5 * 50 = 25 USDT，低于交易所最小值 100；配置了 MinAmount=5 → 抬高到 100
+	cfg := DefaultCopyTradingConfig()
+	cfg.MinAmount = 5
+	qty, clamped := applyCopySymbolSpec(0.5, 50, spec, cfg)
+	if !clamped || math.Abs(qty-2) > 1e-9 {
+		t.Fatalf("expected clamp to 2 coins (100 USDT), got qty=%v clamped=%v", qty, clamped)
+	}
+
+	// 未配置 MinAmount → 放弃订单，避免被交易所拒单
+	qty, _ = applyCopySymbolSpec(0.5, 50, spec, DefaultCopyTradingConfig())
+	if qty != 0 {
+		t.Fatalf("expected order dropped, got qty=%v", qty)
+	}
+
+	// 满足最小值时只按步进取整
+	qty, clamped = applyCopySymbolSpec(3.456, 50, spec, DefaultCopyTradingConfig())
+	if clamped || math.Abs(qty-3.45) > 1e-9 {
+		t.Fatalf("expected step rounding to 3.45, got qty=%v clamped=%v", qty, clamped)
+	}
+}
+
+func TestSymbolSpecCache_RefreshesAfterTTL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	fail := false
+	cache := newSymbolSpecCache(func(symbol string) (SymbolSpec, error) {
+		calls++
+		if fail {
+			return SymbolSpec{}, errors.New("exchange down")
+		}
+		return SymbolSpec{MinNotional: float64(calls * 10)}, nil
+	}, time.Minute, func() time.Time { return now })
+
+	if spec, ok := cache.get("BTCUSDT"); !ok || spec.MinNotional != 10 {
+		t.Fatalf("unexpected spec: %+v ok=%v", spec, ok)
+	}
+	cache.get("BTCUSDT")
+	if calls != 1 {
+		t.Fatalf("expected cached spec within TTL, got %d lookups", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	fail = true
+	if spec, ok := cache.get("BTCUSDT"); !ok || spec.MinNotional != 10 {
+		t.Fatalf("expected stale spec kept when refresh fails, got %+v ok=%v", spec, ok)
+	}
+
+	fail = false
+	cache.refresh("BTCUSDT")
+	if spec, _ := cache.get("BTCUSDT"); spec.MinNotional != 30 {
+		t.Fatalf("expected refreshed spec, got %+v", spec)
+	}
+}
+
+// specRejectingTrader 支持查询交易规则，并可模拟交易所因 LOT_SIZE 拒单
+type specRejectingTrader struct {
+	*recordingCopyTrader
+	lookups int
+	reject  bool
+}
+
+func (r *specRejectingTrader) GetSymbolSpec(symbol string) (SymbolSpec, error) {
+	r.lookups++
+	return SymbolSpec{StepSize: 0.01}, nil
+}
+
+func (r *specRejectingTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if r.reject {
+		return nil, errors.New("<APIError> code=-1013, msg=Filter failure: LOT_SIZE")
+	}
+	return r.recordingCopyTrader.OpenLong(symbol, quantity, leverage)
+}
+
+func TestProcessCopySignal_RefreshesSymbolSpecAfterRejection(t *testing.T) {
+	logger.Init(nil) // 下单失败时经由全局 logger 告警
+	at, rec := newCopyTestTrader(t, DefaultCopyTradingConfig(), 100, nil)
+	exchange := &specRejectingTrader{recordingCopyTrader: rec}
+	at.trader = exchange
+	open := copytrading.Signal{Symbol: "SOLUSDT", Action: copytrading.ActionOpenLong, Price: 100, NotionalUSD: 1000, LeaderEquity: 10000, DeltaSize: 10}
+
+	if err := at.processCopySignal(open); err != nil {
+		t.Fatal(err)
+	}
+	if err := at.processCopySignal(open); err != nil {
+		t.Fatal(err)
+	}
+	if exchange.lookups != 1 {
+		t.Fatalf("expected the cached spec reused within the TTL, got %d lookups", exchange.lookups)
+	}
+
+	// 交易所按交易规则拒单：缓存失效，下一笔订单重新查询规则
+	exchange.reject = true
+	if err := at.processCopySignal(open); err == nil {
+		t.Fatal("expected the rejected order to return an error")
+	}
+	exchange.reject = false
+	if err := at.processCopySignal(open); err != nil {
+		t.Fatal(err)
+	}
+	if exchange.lookups != 2 {
+		t.Fatalf("expected the spec re-fetched after a LOT_SIZE rejection, got %d lookups", exchange.lookups)
+	}
+
+	if isSymbolSpecRejection(errors.New("insufficient margin")) {
+		t.Fatal("unrelated order errors must not invalidate the spec cache")
+	}
+}
@@ -924,7 +924,6 @@ function DecisionCard({
                       Follower: eq {action.follower_equity?.toFixed(2) ?? '--'} | mgn{' '}
                       {action.follower_margin_usd?.toFixed(2) ?? '--'} | ratio{' '}
                       {action.copy_ratio ? `${action.copy_ratio.toFixed(0)}%` : '--'}{' '}
-                      {action.min_amount_applied ? '(min applied)' : ''}
                       {action.max_amount_applied ? '(max applied)' : ''}
                     </div>
                   </div>
@@ -60,7 +60,6 @@ export interface DecisionAction {
   follower_equity?: number
   follower_margin_usd?: number
   copy_ratio?: number
-  min_amount_applied?: boolean
   max_amount_applied?: boolean
 }
 